@@ -0,0 +1,429 @@
+package pxl
+
+import (
+    "fmt"
+    "image"
+    "image/color"
+    "math"
+    "strings"
+)
+
+// EncodeDiff converts an image to ANSI escape sequences which only redraw the cells
+// that changed since the prev frame, positioning the cursor with the shortest moves it can,
+// so a column changing across many rows is updated by stepping straight down it.
+// The image is drawn from the top-left corner of the terminal. If prev is nil
+// or its size differs from img, every cell is drawn.
+func (e *Encoder) EncodeDiff(prev, img image.Image) (encoded string, err error) {
+    next, err := e.Decode(img)
+    if err != nil {
+        return
+    }
+
+    var old Pixels
+    if prev != nil && prev.Bounds().Size() == img.Bounds().Size() {
+        old, err = e.Decode(prev)
+        if err != nil {
+            return
+        }
+    }
+
+    return e.diffANSI(old, next), nil
+}
+
+// UpdateFrom converts an image to ANSI escape sequences which only redraw the cells that changed since prev,
+// like EncodeDiff() does, but compared to the text last shown rather than the image it came from. The text
+// is parsed like ToImage() does, so it may come from any function which formats images for tview.
+// Along with the sequences it returns the text to pass as prev the next time, formatted like FromImage() does.
+// When prev can't be parsed, or is another size than img, every cell is drawn.
+func UpdateFrom(prev string, img image.Image) (delta, state string, err error) {
+    if state, err = FromImage(img); err != nil {
+        return
+    }
+
+    // Both sides are parsed from text, so their cells hold the same colour types
+    shown, err := ToImage(state)
+    if err != nil {
+        return
+    }
+
+    next, err := DecodeToPixels(shown)
+    if err != nil {
+        return
+    }
+
+    var old Pixels
+    if before, parseErr := ToImage(prev); parseErr == nil && before.Bounds().Size() == shown.Bounds().Size() {
+        old, _ = DecodeToPixels(before)
+    }
+
+    return NewEncoder(WithMode(ModeANSI)).diffANSI(old, next), state, nil
+}
+
+// EncodeInterlaced converts an image to ANSI escape sequences in two passes, the first drawing
+// the even rows of cells & the second the odd ones, so on a slow connection the whole image shows up early
+// & fills in, like an interlaced GIF. Each row is drawn after moving the cursor to its start, from the top-left
+// corner of the terminal. The colours are those of the encoder's mode when it's one of the ANSI ones.
+func (e *Encoder) EncodeInterlaced(img image.Image) (passes [2]string, err error) {
+    pixels, err := e.Decode(img)
+    if err != nil {
+        return
+    }
+
+    for pass := range passes {
+        var b strings.Builder
+
+        for row := pass; row < len(pixels); row += 2 {
+            b.WriteString(moveCursor(-1, -1, 0, row))
+            b.WriteString(e.ansiRow(row, pixels[row]))
+        }
+
+        passes[pass] = b.String()
+    }
+
+    return
+}
+
+// ansiRow emits a row with true colour SGR sequences, resetting the colours at the end of it.
+func (e *Encoder) ansiRow(row int, cells []Cell) string {
+    var b strings.Builder
+    var prevfg, prevbg color.Color
+
+    for col, cell := range cells {
+        if s, ok := e.substitute(col, row, cell); ok {
+            b.WriteString(s)
+            prevfg, prevbg = nil, nil
+            continue
+        }
+
+        b.WriteString(e.ansiGlyphed(e.ansiCell(cell, &prevfg, &prevbg), cell))
+    }
+
+    b.WriteString("\x1b[0m")
+    return b.String()
+}
+
+// ansiGlyphed swaps the half block an encoded cell ends with for the glyph of the encoder, or with WithAsciiBlockApprox(true)
+// for # when the top pixel of the cell is the brighter one & . when the bottom one is.
+func (e *Encoder) ansiGlyphed(encoded string, cell Cell) string {
+    if strings.HasSuffix(encoded, " ") {
+        return encoded
+    }
+
+    if !e.asciiApprox {
+        return e.glyphed(encoded)
+    }
+
+    glyph := "."
+    if luminance(Composite(cell.Fg, color.Black)) > luminance(Composite(cell.Bg, color.Black)) {
+        glyph = "#"
+    }
+
+    return strings.TrimSuffix(encoded, "▀") + glyph
+}
+
+// eraseLine returns the sequence which erases the rest of a line, written at the end of rows
+// when the encoder was created with WithEraseEOL(true).
+func (e *Encoder) eraseLine() string {
+    switch e.mode {
+        case ModeANSI, ModeANSI256, ModeANSI16, ModeGray256:
+            if e.eraseEOL {
+                return "\x1b[K"
+            }
+    }
+
+    return ""
+}
+
+// diffANSI draws the cells of next which differ from old, old may be nil to draw every cell.
+// The cursor moves over as many columns per cell as its glyph is wide.
+func (e *Encoder) diffANSI(old, next Pixels) string {
+    var b strings.Builder
+    var prevfg, prevbg color.Color
+    var width = e.glyphWidth()
+
+    // -1 means the cursor position is unknown
+    col, row := -1, -1
+
+    for i := range next {
+        r := scanIndex(i, len(next), e.scanY)
+        cells := next[r]
+
+        for j := range cells {
+            c := scanIndex(j, len(cells), e.scanX)
+            cell := cells[c]
+            if old != nil && SameCell(old[r][c].Fg, cell.Fg) && SameCell(old[r][c].Bg, cell.Bg) {
+                continue
+            }
+
+            b.WriteString(moveCursor(col, row, c * width, r))
+            b.WriteString(e.ansiGlyphed(EncodeANSI(cell.Fg, cell.Bg, &prevfg, &prevbg), cell))
+            col, row = (c + 1) * width, r
+
+            // Writing the last column leaves the cursor in a pending wrap state,
+            // where relative moves don't behave consistently across terminals
+            if c + 1 == len(cells) {
+                col, row = -1, -1
+            }
+        }
+    }
+
+    if b.Len() > 0 {
+        b.WriteString("\x1b[0m")
+    }
+
+    return b.String()
+}
+
+// moveCursor returns the shortest escape sequence taking the cursor from (col, row) to (tocol, torow),
+// all zero based. A negative col or row means the current position is unknown.
+func moveCursor(col, row, tocol, torow int) string {
+    absolute := fmt.Sprintf("\x1b[%d;%dH", torow + 1, tocol + 1)
+    if col < 0 || row < 0 {
+        return absolute
+    }
+
+    relative := relativeMove(torow - row, "B", "A") + relativeMove(tocol - col, "C", "D")
+    if len(relative) < len(absolute) {
+        return relative
+    }
+
+    return absolute
+}
+
+// relativeMove moves the cursor n cells using the forward or backward final byte.
+func relativeMove(n int, forward, backward string) string {
+    final := forward
+    if n < 0 {
+        n, final = -n, backward
+    }
+
+    switch n {
+        case 0:
+            return ""
+
+        case 1:
+            return "\x1b[" + final
+
+        default:
+            return fmt.Sprintf("\x1b[%d%s", n, final)
+    }
+}
+
+// EncodeANSI converts a fg & bg colour into a true colour 'pixel', only setting the
+// colours which differ from prevfg & prevbg, just like Encode() does for tview.
+// A fully transparent bg resets the background to the terminal's default, so its own shows through.
+func EncodeANSI(fg, bg color.Color, prevfg, prevbg *color.Color) (encoded string) {
+    return encodeSGR(fg, bg, prevfg, prevbg, trueColorSGR)
+}
+
+// ansiCell encodes a cell with the colours of the encoder's mode, ModeANSI256, ModeANSI16 & ModeGray256
+// map the colours onto their palettes first, so colours which look the same share their sequences.
+// With WithFlatSpaces(true), cells whose colours are written the same are a space in the background colour.
+func (e *Encoder) ansiCell(cell Cell, prevfg, prevbg *color.Color) string {
+    fg, bg, sgr := e.ansiColors(cell)
+
+    // A fully transparent top pixel is drawn black, which the default background of a space isn't
+    if _, _, _, a := fg.RGBA(); e.flatSpaces && a > 0 && sgr(fg, true) == sgr(bg, true) {
+        if SameCell(bg, *prevbg) {
+            return " "
+        }
+
+        *prevbg = bg
+        return "\x1b[" + sgr(bg, true) + "m "
+    }
+
+    return encodeSGR(fg, bg, prevfg, prevbg, sgr)
+}
+
+// ansiColors returns the colours of a cell as the encoder's mode writes them, along with how it writes them.
+func (e *Encoder) ansiColors(cell Cell) (fg, bg color.Color, sgr ansiSGR) {
+    switch e.mode {
+        case ModeANSI256:
+            return ansi256[ansi256Index(cell.Fg)], ansi256[ansi256Index(cell.Bg)], ansi256SGR
+
+        case ModeANSI16:
+            return ansi16[ansi16.Index(cell.Fg)], ansi16[ansi16.Index(cell.Bg)], ansi16SGR
+
+        case ModeGray256:
+            return ansi256[grayIndex(cell.Fg)], ansi256[grayIndex(cell.Bg)], ansi256SGR
+
+        default:
+            return cell.Fg, cell.Bg, trueColorSGR
+    }
+}
+
+// ansiSGR returns the SGR parameters setting a colour as the foreground, or the background if bg is true.
+type ansiSGR func(c color.Color, bg bool) string
+
+// encodeSGR is like EncodeANSI() but writes the colours with the given parameters.
+// Colours are compared with SameCell(), as Encode() does, so equal colours of different types don't repeat a sequence.
+func encodeSGR(fg, bg color.Color, prevfg, prevbg *color.Color, sgr ansiSGR) (encoded string) {
+    sameFg, sameBg := SameCell(fg, *prevfg), SameCell(bg, *prevbg)
+
+    switch {
+        case sameFg && sameBg:
+            encoded = "▀"
+
+        case sameFg:
+            encoded = "\x1b[" + sgr(bg, true) + "m▀"
+
+        case sameBg:
+            encoded = "\x1b[" + sgr(fg, false) + "m▀"
+
+        default:
+            encoded = "\x1b[" + sgr(fg, false) + ";" + sgr(bg, true) + "m▀"
+    }
+
+    *prevfg = fg
+    *prevbg = bg
+    return
+}
+
+func trueColorSGR(c color.Color, bg bool) string {
+    if bg {
+        if _, _, _, a := c.RGBA(); a == 0 {
+            return "49"
+        }
+
+        return "48;2;" + ansiRGB(c)
+    }
+
+    return "38;2;" + ansiRGB(c)
+}
+
+func ansi256SGR(c color.Color, bg bool) string {
+    if bg {
+        return fmt.Sprintf("48;5;%d", ansi256Index(c))
+    }
+
+    return fmt.Sprintf("38;5;%d", ansi256Index(c))
+}
+
+func ansi16SGR(c color.Color, bg bool) string {
+    i := ansi16.Index(c)
+
+    base := 30
+    if bg {
+        base = 40
+    }
+
+    if i >= 8 {
+        // The bright colours
+        base, i = base + 60, i - 8
+    }
+
+    return fmt.Sprintf("%d", base + i)
+}
+
+func ansiRGB(c color.Color) string {
+    c8 := rgba8(c)
+    return fmt.Sprintf("%d;%d;%d", c8.R, c8.G, c8.B)
+}
+
+// ansi16 holds the 16 standard terminal colours, as xterm draws them by default.
+var ansi16 = color.Palette{
+    color.RGBA{0x00, 0x00, 0x00, 0xff}, color.RGBA{0xcd, 0x00, 0x00, 0xff},
+    color.RGBA{0x00, 0xcd, 0x00, 0xff}, color.RGBA{0xcd, 0xcd, 0x00, 0xff},
+    color.RGBA{0x00, 0x00, 0xee, 0xff}, color.RGBA{0xcd, 0x00, 0xcd, 0xff},
+    color.RGBA{0x00, 0xcd, 0xcd, 0xff}, color.RGBA{0xe5, 0xe5, 0xe5, 0xff},
+    color.RGBA{0x7f, 0x7f, 0x7f, 0xff}, color.RGBA{0xff, 0x00, 0x00, 0xff},
+    color.RGBA{0x00, 0xff, 0x00, 0xff}, color.RGBA{0xff, 0xff, 0x00, 0xff},
+    color.RGBA{0x5c, 0x5c, 0xff, 0xff}, color.RGBA{0xff, 0x00, 0xff, 0xff},
+    color.RGBA{0x00, 0xff, 0xff, 0xff}, color.RGBA{0xff, 0xff, 0xff, 0xff},
+}
+
+// ansi256 holds the colours of the xterm 256 colour palette, the first 16 are the standard colours
+// which terminal themes tend to change, so only the 6x6x6 colour cube & the grey ramp after them are ever picked.
+var ansi256 = func() (palette color.Palette) {
+    palette = append(palette, ansi16...)
+
+    for i := 0; i < 216; i++ {
+        palette = append(palette, color.RGBA{cubeLevels[i / 36], cubeLevels[i / 6 % 6], cubeLevels[i % 6], 0xff})
+    }
+
+    for i := 0; i < 24; i++ {
+        v := uint8(8 + 10 * i)
+        palette = append(palette, color.RGBA{v, v, v, 0xff})
+    }
+
+    return
+}()
+
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// grayIndex returns the palette index of the grey ramp entry nearest to the luminance of c.
+func grayIndex(c color.Color) int {
+    step := int(math.Round((luminance(c) * 0xff - 8) / 10))
+    if step < 0 {
+        step = 0
+    }
+
+    if step > 23 {
+        step = 23
+    }
+
+    return 232 + step
+}
+
+// ansi256Index returns the palette index of the colour cube or grey ramp entry nearest to c.
+func ansi256Index(c color.Color) int {
+    c8 := rgba8(c)
+
+    level := func(v uint8) int {
+        best := 0
+        for i, l := range cubeLevels {
+            if absDiff(v, l) < absDiff(v, cubeLevels[best]) {
+                best = i
+            }
+        }
+
+        return best
+    }
+
+    r, g, b := level(c8.R), level(c8.G), level(c8.B)
+    cube := 16 + r * 36 + g * 6 + b
+
+    // The grey ramp goes from 8 to 238 in steps of 10
+    mean := (int(c8.R) + int(c8.G) + int(c8.B)) / 3
+    grey := (mean - 3) / 10
+    if grey < 0 {
+        grey = 0
+    }
+
+    if grey > 23 {
+        grey = 23
+    }
+
+    if sqDistance(c8, ansi256[232 + grey]) < sqDistance(c8, ansi256[cube]) {
+        return 232 + grey
+    }
+
+    return cube
+}
+
+func absDiff(a, b uint8) int {
+    if a > b {
+        return int(a - b)
+    }
+
+    return int(b - a)
+}
+
+// sqDistance is the squared distance between the colours, ignoring their opacity.
+func sqDistance(a color.RGBA, b color.Color) int {
+    c := rgba8(b)
+    dr, dg, db := absDiff(a.R, c.R), absDiff(a.G, c.G), absDiff(a.B, c.B)
+    return dr * dr + dg * dg + db * db
+}
+
+// WrapAltScreen prepares content for a full screen viewer, switching to the terminal's alternate screen,
+// hiding the cursor & drawing from the top-left corner. Write RestoreScreen() once the viewer is done.
+func WrapAltScreen(content string) string {
+    return "\x1b[?1049h\x1b[?25l\x1b[2J\x1b[H" + content
+}
+
+// RestoreScreen undoes WrapAltScreen(), showing the cursor & switching back to the normal screen
+// with whatever was on it before.
+func RestoreScreen() string {
+    return "\x1b[?25h\x1b[?1049l"
+}
@@ -0,0 +1,355 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "regexp"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+func TestEncodeDiffColumn(t *testing.T) {
+    prev := solid(4, 8, color.White)
+    next := solid(4, 8, color.White)
+    for y := 0; y < 8; y++ {
+        next.Set(2, y, color.NRGBA{0xff, 0, 0, 0xff})
+    }
+
+    encoded, err := NewEncoder(WithMode(ModeANSI)).EncodeDiff(prev, next)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Straight to the third column, then down it a row at a time without rewriting the colours
+    red := "\x1b[38;2;255;0;0;48;2;255;0;0m▀"
+    want := "\x1b[1;3H" + red + "\x1b[2;3H▀\x1b[3;3H▀\x1b[4;3H▀\x1b[0m"
+    if encoded != want {
+        t.Errorf("EncodeDiff() = %q, want %q", encoded, want)
+    }
+}
+
+func TestEncodeDiffWideGlyph(t *testing.T) {
+    prev := solid(4, 2, color.White)
+    next := solid(4, 2, color.White)
+    next.Set(1, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    next.Set(3, 0, color.NRGBA{0xff, 0, 0, 0xff})
+
+    encoded, err := NewEncoder(WithMode(ModeANSI), WithCellGlyph('🟥')).EncodeDiff(prev, next)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every cell takes two columns, so the second cell starts in the third column & the fourth one two columns on
+    want := "\x1b[1;3H\x1b[38;2;255;0;0;48;2;255;255;255m🟥\x1b[2C🟥\x1b[0m"
+    if encoded != want {
+        t.Errorf("EncodeDiff() with a wide glyph = %q, want %q", encoded, want)
+    }
+}
+
+func TestEncodeDiffUnchanged(t *testing.T) {
+    img := TestPattern(6, 4)
+
+    encoded, err := NewEncoder(WithMode(ModeANSI)).EncodeDiff(img, Prepare(img))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if encoded != "" {
+        t.Errorf("EncodeDiff() of the same image as another type = %q, want nothing", encoded)
+    }
+
+    full, err := NewEncoder(WithMode(ModeANSI)).EncodeDiff(nil, img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if n := strings.Count(full, "▀"); n != 12 || !strings.HasPrefix(full, "\x1b[1;1H") {
+        t.Errorf("EncodeDiff() without a previous frame drew %d cells from %q", n, full)
+    }
+}
+
+func TestMoveCursor(t *testing.T) {
+    tests := []struct {
+        col, row, tocol, torow int
+        want                   string
+    }{
+        {-1, -1, 2, 3, "\x1b[4;3H"},
+        {2, 3, 2, 4, "\x1b[B"},
+        {5, 3, 2, 3, "\x1b[3D"},
+        {0, 0, 40, 30, "\x1b[31;41H"},
+    }
+
+    for _, test := range tests {
+        if got := moveCursor(test.col, test.row, test.tocol, test.torow); got != test.want {
+            t.Errorf("moveCursor(%d, %d, %d, %d) = %q, want %q", test.col, test.row, test.tocol, test.torow, got, test.want)
+        }
+    }
+}
+
+func TestWrapAltScreen(t *testing.T) {
+    content, err := NewEncoder(WithMode(ModeANSI)).Encode(TestPattern(2, 2))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    shown := WrapAltScreen(content) + RestoreScreen()
+    enter, leave := strings.Index(shown, "\x1b[?1049h"), strings.Index(shown, "\x1b[?1049l")
+    hide, show := strings.Index(shown, "\x1b[?25l"), strings.Index(shown, "\x1b[?25h")
+    at := strings.Index(shown, content)
+
+    if enter != 0 || hide < 0 || hide > at || at < 0 || show < at + len(content) || leave < show {
+        t.Errorf("content isn't between switching to the alternate screen & hiding the cursor, & undoing both: %q", shown)
+    }
+}
+
+func TestModePagerSafe(t *testing.T) {
+    encoded, err := NewEncoder(WithMode(ModePagerSafe), WithHyperlink("https://example.com"), WithEraseEOL(true)).Encode(TestPattern(3, 8))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Once the SGR sequences are taken out, no escape is left
+    if plain := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(encoded, ""); strings.ContainsAny(plain, "\x1b\x07") {
+        t.Errorf("output holds escape sequences other than SGR: %q", encoded)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if len(lines) != 4 {
+        t.Fatalf("output has %d rows, want 4", len(lines))
+    }
+
+    for i, line := range lines {
+        if !strings.HasSuffix(line, "\x1b[0m") {
+            t.Errorf("row %d doesn't end by resetting the colours: %q", i, line)
+        }
+    }
+}
+
+func TestModeGray256(t *testing.T) {
+    // Every step of the grey ramp, which runs from 8 to 238 in steps of 10, over black & white
+    ramp := solid(26, 2, color.White)
+    for x := 0; x < 24; x++ {
+        ramp.Set(x, 0, color.Gray{uint8(8 + x * 10)})
+    }
+    ramp.Set(24, 0, color.Black)
+    ramp.Set(25, 0, color.NRGBA{0xff, 0, 0, 0xff})
+
+    encoded, err := NewEncoder(WithMode(ModeGray256)).Encode(ramp)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var fgs []string
+    for _, m := range regexp.MustCompile(`38;5;(\d+)`).FindAllStringSubmatch(encoded, -1) {
+        fgs = append(fgs, m[1])
+    }
+
+    // Black & white are the ends of the ramp, & colours are drawn grey too
+    want := "232 233 234 235 236 237 238 239 240 241 242 243 244 245 246 247 248 249 250 251 252 253 254 255 232"
+    if got := strings.Join(fgs, " "); len(fgs) != 26 || !strings.HasPrefix(got, want + " ") {
+        t.Fatalf("foregrounds = %s, want %s & a grey", got, want)
+    }
+
+    if red, _ := strconv.Atoi(fgs[25]); red < 232 || red > 255 {
+        t.Errorf("red is drawn as %d, want a grey from 232 to 255", red)
+    }
+
+    if !strings.Contains(encoded, "48;5;255m") || strings.Contains(encoded, "38;2") {
+        t.Errorf("ModeGray256 wrote %q, want white backgrounds from the ramp & no true colours", encoded)
+    }
+}
+
+func TestEncodeInterlaced(t *testing.T) {
+    img := TestPattern(3, 10)
+    e := NewEncoder(WithMode(ModeANSI))
+
+    passes, err := e.EncodeInterlaced(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    encoded, err := e.Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    rows := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    drawn := map[int]int{}
+
+    // Every row is drawn once, after moving to its start, the even ones in the first pass
+    move := regexp.MustCompile(`\x1b\[(\d+);1H`)
+    for pass, s := range passes {
+        parts := move.Split(s, -1)
+        for i, m := range move.FindAllStringSubmatch(s, -1) {
+            row, _ := strconv.Atoi(m[1])
+            row--
+            drawn[row]++
+
+            if row % 2 != pass || row >= len(rows) || parts[i + 1] != rows[row] {
+                t.Errorf("pass %d drew row %d as %q", pass, row, parts[i + 1])
+            }
+        }
+
+        if parts[0] != "" {
+            t.Errorf("pass %d starts with %q before moving the cursor", pass, parts[0])
+        }
+    }
+
+    for row := range rows {
+        if drawn[row] != 1 {
+            t.Errorf("row %d was drawn %d times, want once", row, drawn[row])
+        }
+    }
+}
+
+func TestWithEraseEOL(t *testing.T) {
+    img := TestPattern(3, 8)
+    for _, mode := range []Mode{ModeANSI, ModeANSI256} {
+        erased, err := NewEncoder(WithMode(mode), WithEraseEOL(true)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        plain, err := NewEncoder(WithMode(mode)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // Every row ends with the erase, which is all that's added
+        lines := strings.Split(strings.TrimSuffix(erased, "\n"), "\n")
+        for i, line := range lines {
+            if !strings.HasSuffix(line, "\x1b[K") {
+                t.Errorf("mode %d: row %d = %q, want it to end with an erase", mode, i, line)
+            }
+        }
+
+        if len(lines) != 4 || strings.ReplaceAll(erased, "\x1b[K", "") != plain {
+            t.Errorf("mode %d: WithEraseEOL(true) = %q, want %q with erases", mode, erased, plain)
+        }
+    }
+}
+
+func TestANSITransparentBackground(t *testing.T) {
+    // Red over a transparent pixel, then red again over blue
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 1, color.NRGBA{0, 0, 0xff, 0xff})
+
+    encoded, err := NewEncoder(WithMode(ModeANSI)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "\x1b[38;2;255;0;0;49m▀\x1b[48;2;0;0;255m▀\x1b[0m\n"; encoded != want {
+        t.Errorf("transparent bottom pixel = %q, want the default background %q", encoded, want)
+    }
+
+    // A background set by the options is drawn instead
+    themed, err := NewEncoder(WithMode(ModeANSI), WithThemeBackground(color.Black)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if strings.Contains(themed, "49m") || !strings.Contains(themed, "48;2;0;0;0m") {
+        t.Errorf("transparent pixel over a theme = %q, want it drawn black", themed)
+    }
+}
+
+func TestEncodeANSISameCell(t *testing.T) {
+    var prevfg, prevbg color.Color
+    EncodeANSI(color.NRGBA{0xff, 0, 0, 0xff}, color.Transparent, &prevfg, &prevbg)
+
+    // The same red as another type, over another fully transparent colour, needs no new sequence
+    if encoded := EncodeANSI(color.RGBA{0xff, 0, 0, 0xff}, color.NRGBA{0x12, 0x34, 0x56, 0}, &prevfg, &prevbg); encoded != "▀" {
+        t.Errorf("EncodeANSI() of the same colours as other types = %q, want %q", encoded, "▀")
+    }
+
+    if encoded := EncodeANSI(color.RGBA{0xff, 0, 0, 0xff}, color.White, &prevfg, &prevbg); encoded != "\x1b[48;2;255;255;255m▀" {
+        t.Errorf("EncodeANSI() of a new background = %q, want only the background set", encoded)
+    }
+}
+
+func TestWithAsciiBlockApprox(t *testing.T) {
+    // White over black, then black over white, then two greys the same
+    img := solid(3, 2, color.Gray{0x80})
+    img.Set(0, 0, color.White)
+    img.Set(0, 1, color.Black)
+    img.Set(1, 0, color.Black)
+    img.Set(1, 1, color.White)
+
+    encoded, err := NewEncoder(WithMode(ModeANSI), WithAsciiBlockApprox(true)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "\x1b[38;2;255;255;255;48;2;0;0;0m#\x1b[38;2;0;0;0;48;2;255;255;255m.\x1b[38;2;128;128;128;48;2;128;128;128m.\x1b[0m\n"
+    if encoded != want {
+        t.Errorf("WithAsciiBlockApprox(true) = %q, want %q", encoded, want)
+    }
+}
+
+func TestUpdateFrom(t *testing.T) {
+    img := TestPattern(4, 6)
+
+    // The text shown may come from another path, only the colours it shows count
+    shown, err := NewEncoder(WithLineReset(true)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    delta, state, err := UpdateFrom(shown, img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); delta != "" || state != want {
+        t.Errorf("UpdateFrom() of an unchanged image = %q, state %q, want nothing to draw & %q", delta, state, want)
+    }
+
+    // A single changed cell is all that's drawn
+    changed := image.NewNRGBA(img.Bounds())
+    draw.Draw(changed, changed.Rect, img, image.Point{}, draw.Src)
+    changed.Set(2, 3, color.NRGBA{0xff, 0, 0, 0xff})
+
+    if delta, _, _ = UpdateFrom(state, changed); strings.Count(delta, "▀") != 1 || !strings.HasPrefix(delta, "\x1b[2;3H") {
+        t.Errorf("UpdateFrom() of a changed cell = %q, want only the cell at 3, 2", delta)
+    }
+
+    // Text which can't be parsed redraws every cell
+    if delta, _, _ = UpdateFrom("not an image", img); strings.Count(delta, "▀") != 12 {
+        t.Errorf("UpdateFrom() of unparsable text = %q, want all 12 cells drawn", delta)
+    }
+}
+
+func TestWithFlatSpaces(t *testing.T) {
+    img := solid(3, 4, color.NRGBA{0x12, 0x34, 0x56, 0xff})
+
+    flat, err := NewEncoder(WithMode(ModeANSI), WithFlatSpaces(true)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // One background escape per row, & spaces rather than half blocks
+    row := "\x1b[48;2;18;52;86m   \x1b[0m\n"
+    if want := row + row; flat != want {
+        t.Errorf("flat ANSI output of a solid image = %q, want %q", flat, want)
+    }
+
+    if blocks, _ := NewEncoder(WithMode(ModeANSI)).Encode(img); len(flat) >= len(blocks) {
+        t.Errorf("flat ANSI output is %d bytes, half blocks %d, want it shorter", len(flat), len(blocks))
+    }
+
+    // A cell of two colours is still a half block, & the flat cell after it sets its background again
+    img.Set(1, 1, color.White)
+    mixed, err := NewEncoder(WithMode(ModeANSI), WithFlatSpaces(true)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "\x1b[48;2;18;52;86m \x1b[38;2;18;52;86;48;2;255;255;255m▀\x1b[48;2;18;52;86m \x1b[0m\n" + row; mixed != want {
+        t.Errorf("flat ANSI output of a cell of two colours = %q, want %q", mixed, want)
+    }
+}
@@ -0,0 +1,71 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/color/palette"
+    "image/draw"
+    "testing"
+)
+
+// The benchmarks all convert the same 512 by 512 pattern, stored as the type each path takes.
+var (
+    benchNRGBA    = TestPattern(512, 512).(*image.NRGBA)
+    benchRGBA     = image.NewRGBA(benchNRGBA.Rect)
+    benchPaletted = image.NewPaletted(benchNRGBA.Rect, palette.WebSafe)
+    benchYCbCr    = image.NewYCbCr(benchNRGBA.Rect, image.YCbCrSubsampleRatio420)
+)
+
+func init() {
+    draw.Draw(benchRGBA, benchRGBA.Rect, benchNRGBA, image.Point{}, draw.Src)
+    draw.Draw(benchPaletted, benchPaletted.Rect, benchNRGBA, image.Point{}, draw.Src)
+
+    for y := 0; y < 512; y++ {
+        for x := 0; x < 512; x++ {
+            c := benchNRGBA.NRGBAAt(x, y)
+            yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+            benchYCbCr.Y[benchYCbCr.YOffset(x, y)] = yy
+            benchYCbCr.Cb[benchYCbCr.COffset(x, y)] = cb
+            benchYCbCr.Cr[benchYCbCr.COffset(x, y)] = cr
+        }
+    }
+}
+
+// benchmark runs a conversion over & over, reporting its allocations.
+func benchmark(b *testing.B, convert func() (string, error)) {
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        if _, err := convert(); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkFromNRGBA(b *testing.B) {
+    benchmark(b, func() (string, error) { return FromNRGBA(benchNRGBA) })
+}
+
+func BenchmarkFromPaletted(b *testing.B) {
+    benchmark(b, func() (string, error) { return FromPaletted(benchPaletted) })
+}
+
+func BenchmarkFromYCbCr(b *testing.B) {
+    benchmark(b, func() (string, error) { return FromYCbCr(benchYCbCr) })
+}
+
+func BenchmarkFromRGBA(b *testing.B) {
+    benchmark(b, func() (string, error) { return FromRGBA(benchRGBA) })
+}
+
+func BenchmarkFromGeneric(b *testing.B) {
+    benchmark(b, func() (string, error) { return FromImageGeneric(benchNRGBA) })
+}
+
+func BenchmarkEncode(b *testing.B) {
+    pixels, err := DecodeToPixels(benchNRGBA)
+    if err != nil {
+        b.Fatal(err)
+    }
+
+    benchmark(b, func() (string, error) { return pixels.Encode(), nil })
+}
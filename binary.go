@@ -0,0 +1,174 @@
+package pxl
+
+import (
+    "encoding/binary"
+    "image/color"
+
+    "github.com/pkg/errors"
+)
+
+// binaryVersion is the first byte of the binary format of Pixels, for telling later versions apart.
+const binaryVersion = 1
+
+var errBinary = errors.New("pixelview: Can't unmarshal invalid or truncated pixels")
+
+// MarshalBinary writes the cells in a compact binary format, for caching many renders
+// far smaller than their text, which can be encoded in any mode once they're read back with UnmarshalBinary().
+// Every distinct colour is stored once with its 16 bit channels, followed by the runs of identical cells
+// of every row as indices into those colours. The cells read back hold color.RGBA64 values with the same channels,
+// so Encode() writes them exactly like the ones written.
+func (p Pixels) MarshalBinary() ([]byte, error) {
+    var palette [][4]uint32
+    index := map[[4]uint32]uint64{}
+    var rows []byte
+
+    // Index 0 stands for a nil colour
+    colorIndex := func(c color.Color) uint64 {
+        if c == nil {
+            return 0
+        }
+
+        r, g, b, a := c.RGBA()
+        key := [4]uint32{r, g, b, a}
+
+        i, ok := index[key]
+        if !ok {
+            palette = append(palette, key)
+            i = uint64(len(palette))
+            index[key] = i
+        }
+
+        return i
+    }
+
+    var buf [binary.MaxVarintLen64]byte
+    putUvarint := func(out []byte, v uint64) []byte {
+        return append(out, buf[:binary.PutUvarint(buf[:], v)]...)
+    }
+
+    rows = putUvarint(rows, uint64(len(p)))
+    for _, cells := range p {
+        var runs []byte
+        var count uint64
+
+        for x := 0; x < len(cells); {
+            fg, bg := colorIndex(cells[x].Fg), colorIndex(cells[x].Bg)
+
+            end := x + 1
+            for end < len(cells) && colorIndex(cells[end].Fg) == fg && colorIndex(cells[end].Bg) == bg {
+                end++
+            }
+
+            runs = putUvarint(putUvarint(putUvarint(runs, uint64(end - x)), fg), bg)
+            count++
+            x = end
+        }
+
+        rows = append(putUvarint(rows, count), runs...)
+    }
+
+    data := putUvarint([]byte{binaryVersion}, uint64(len(palette)))
+    for _, c := range palette {
+        for _, v := range c {
+            // Custom colour types may return channels past 0xffff, which rgba8() clamps the same way
+            if v > 0xffff {
+                v = 0xffff
+            }
+
+            data = append(data, byte(v >> 8), byte(v))
+        }
+    }
+
+    return append(data, rows...), nil
+}
+
+// UnmarshalBinary reads cells written by MarshalBinary(), replacing the ones held.
+// It refuses data holding more than 4Mi cells in all, rather than allocating whatever it claims to hold.
+func (p *Pixels) UnmarshalBinary(data []byte) error {
+    if len(data) == 0 || data[0] != binaryVersion {
+        return errors.New("pixelview: Can't unmarshal pixels of an unknown version")
+    }
+
+    pos := 1
+    readUvarint := func() (uint64, bool) {
+        v, n := binary.Uvarint(data[pos:])
+        if n <= 0 {
+            return 0, false
+        }
+
+        pos += n
+        return v, true
+    }
+
+    size, ok := readUvarint()
+    if !ok || size > uint64(len(data) - pos) / 8 {
+        return errBinary
+    }
+
+    palette := make([]color.Color, size + 1)
+    for i := range palette[1:] {
+        var c [4]uint16
+        for j := range c {
+            c[j] = binary.BigEndian.Uint16(data[pos:])
+            pos += 2
+        }
+
+        palette[i + 1] = color.RGBA64{c[0], c[1], c[2], c[3]}
+    }
+
+    count, ok := readUvarint()
+    if !ok || count > uint64(len(data) - pos) {
+        return errBinary
+    }
+
+    // The runs of a row are read before it's allocated, so its cells can be checked against maxCells up front
+    type run struct{ length, fg, bg uint64 }
+
+    pixels := make(Pixels, count)
+    var total uint64
+    for y := range pixels {
+        runs, ok := readUvarint()
+        if !ok || runs > uint64(len(data) - pos) {
+            return errBinary
+        }
+
+        var row []run
+        var width uint64
+
+        for ; runs > 0; runs-- {
+            length, ok1 := readUvarint()
+            fg, ok2 := readUvarint()
+            bg, ok3 := readUvarint()
+
+            if !ok1 || !ok2 || !ok3 || fg > size || bg > size || length > maxCells {
+                return errBinary
+            }
+
+            width += length
+            if total + width > maxCells {
+                return errBinary
+            }
+
+            row = append(row, run{length, fg, bg})
+        }
+
+        total += width
+        pixels[y] = make([]Cell, 0, width)
+        for _, r := range row {
+            for i := uint64(0); i < r.length; i++ {
+                pixels[y] = append(pixels[y], Cell{palette[r.fg], palette[r.bg]})
+            }
+        }
+    }
+
+    if pos != len(data) {
+        return errBinary
+    }
+
+    *p = pixels
+    return nil
+}
+
+// maxCells is the most cells UnmarshalBinary() reads, across all rows, which guards against huge allocations
+// from a few bytes of runs. It's far more than fits on any screen, 2048 columns by as many rows.
+const maxCells = 1 << 22
@@ -0,0 +1,98 @@
+package pxl
+
+import (
+    "encoding/binary"
+    "image/color"
+    "testing"
+)
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+    pixels, err := DecodeToPixels(TestPattern(9, 6))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Rows of different widths, nil colours & an empty row survive too
+    pixels = append(pixels, []Cell{{nil, color.White}, {color.Transparent, nil}}, []Cell{})
+
+    data, err := pixels.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var read Pixels
+    if err = read.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+
+    if len(read) != len(pixels) {
+        t.Fatalf("read %d rows, want %d", len(read), len(pixels))
+    }
+
+    for y := range pixels {
+        if len(read[y]) != len(pixels[y]) {
+            t.Fatalf("row %d holds %d cells, want %d", y, len(read[y]), len(pixels[y]))
+        }
+
+        for x, cell := range pixels[y] {
+            got := read[y][x]
+            if (cell.Fg == nil) != (got.Fg == nil) || (cell.Bg == nil) != (got.Bg == nil) ||
+                !SameCell(cell.Fg, got.Fg) || !SameCell(cell.Bg, got.Bg) {
+                t.Errorf("cell %d,%d = %v, want %v", x, y, got, cell)
+            }
+        }
+    }
+
+    // Encode() has no way to write nil colours, so only the rows of the image are compared
+    if got, want := read[:3].Encode(), pixels[:3].Encode(); got != want {
+        t.Errorf("read cells encode to %q, want %q", got, want)
+    }
+}
+
+func TestUnmarshalBinaryRejectsHugeRows(t *testing.T) {
+    put := func(data []byte, v uint64) []byte {
+        var buf [binary.MaxVarintLen64]byte
+        return append(data, buf[:binary.PutUvarint(buf[:], v)]...)
+    }
+
+    // No colours, a row of two runs of 1<<24 nil cells each
+    data := put(put([]byte{binaryVersion}, 0), 1)
+    data = put(data, 2)
+    for i := 0; i < 2; i++ {
+        data = put(put(put(data, 1 << 24), 0), 0)
+    }
+
+    var pixels Pixels
+    if err := pixels.UnmarshalBinary(data); err != errBinary {
+        t.Errorf("UnmarshalBinary() of %d bytes claiming 1<<25 cells returned %v, want %v", len(data), err, errBinary)
+    }
+
+    // Rows each under the limit mustn't add up past it either
+    data = put(put([]byte{binaryVersion}, 0), 3)
+    for i := 0; i < 3; i++ {
+        data = put(put(put(put(data, 1), maxCells / 2), 0), 0)
+    }
+
+    if err := pixels.UnmarshalBinary(data); err != errBinary {
+        t.Errorf("UnmarshalBinary() of rows adding up past the limit returned %v, want %v", err, errBinary)
+    }
+}
+
+func TestUnmarshalBinaryRejectsTruncated(t *testing.T) {
+    pixels, err := DecodeToPixels(TestPattern(4, 4))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    data, err := pixels.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for n := 0; n < len(data); n++ {
+        var read Pixels
+        if err := read.UnmarshalBinary(data[:n]); err == nil {
+            t.Errorf("UnmarshalBinary() of the first %d of %d bytes succeeded", n, len(data))
+        }
+    }
+}
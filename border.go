@@ -0,0 +1,97 @@
+package pxl
+
+import (
+    "image/color"
+    "strings"
+)
+
+// BorderStyle selects the box drawing characters a border is drawn with.
+type BorderStyle int
+
+const (
+    // BorderSingle draws the border with thin lines & square corners.
+    BorderSingle BorderStyle = iota
+
+    // BorderRounded draws the border with thin lines & rounded corners.
+    BorderRounded
+
+    // BorderDouble draws the border with double lines.
+    BorderDouble
+
+    // BorderHeavy draws the border with thick lines.
+    BorderHeavy
+)
+
+// borderGlyphs holds the top-left, top-right, bottom-left & bottom-right corners
+// followed by the horizontal & vertical lines of every style.
+var borderGlyphs = map[BorderStyle][]rune{
+    BorderSingle:  []rune("┌┐└┘─│"),
+    BorderRounded: []rune("╭╮╰╯─│"),
+    BorderDouble:  []rune("╔╗╚╝═║"),
+    BorderHeavy:   []rune("┏┓┗┛━┃"),
+}
+
+type border struct {
+    glyphs []rune
+    color  color.Color
+}
+
+// borderLine returns the top or bottom edge of the border for an image width cells wide,
+// or the box it's centered in if that's wider, followed by the separator. It's empty if there's no border.
+func (e *Encoder) borderLine(top bool, width int) string {
+    if e.border == nil {
+        return ""
+    }
+
+    if width < e.box.X {
+        width = e.box.X
+    }
+
+    g := e.border.glyphs
+    left, right := g[2], g[3]
+    if top {
+        left, right = g[0], g[1]
+    }
+
+    return e.borderText(string(left) + strings.Repeat(string(g[4]), width) + string(right)) + e.eraseLine() + e.separator
+}
+
+// bordered pads an encoded row width cells wide to the width of the box it's centered in, if there's one,
+// & wraps it in the vertical lines of the border, if there's one.
+func (e *Encoder) bordered(row string, width int) string {
+    before, after := padding(width, e.box.X)
+    row = e.fillText(before) + row + e.fillText(after)
+
+    if e.border == nil {
+        return row
+    }
+
+    side := e.borderText(string(e.border.glyphs[5]))
+    return side + row + side
+}
+
+// borderText writes text in the border colour over the default background.
+func (e *Encoder) borderText(text string) string {
+    switch e.mode {
+        default:
+            return "[" + ColorHex(e.border.color) + ":-]" + text
+
+        case ModeHTML:
+            return `<span style="color:` + ColorString(e.border.color, e.notation) + `">` + text + "</span>"
+
+        case ModeANSI, ModePagerSafe:
+            return "\x1b[" + trueColorSGR(e.border.color, false) + "m" + text + "\x1b[0m"
+
+        case ModeANSI256:
+            return "\x1b[" + ansi256SGR(e.border.color, false) + "m" + text + "\x1b[0m"
+
+        case ModeANSI16:
+            return "\x1b[" + ansi16SGR(e.border.color, false) + "m" + text + "\x1b[0m"
+
+        case ModeGray256:
+            return "\x1b[" + ansi256SGR(ansi256[grayIndex(e.border.color)], false) + "m" + text + "\x1b[0m"
+
+        case ModeASCII:
+            return text
+    }
+}
@@ -0,0 +1,40 @@
+package pxl
+
+import (
+    "image/color"
+    "strings"
+    "testing"
+)
+
+func TestWithBorder(t *testing.T) {
+    red := color.RGBA{0xff, 0, 0, 0xff}
+
+    encoded, err := NewEncoder(WithBorder(BorderSingle, red)).Encode(solid(2, 4, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#ff0000:-]┌──┐\n" +
+        "[#ff0000:-]│[#ffffff:#ffffff]▀▀[#ff0000:-]│\n" +
+        "[#ff0000:-]│[#ffffff:#ffffff]▀▀[#ff0000:-]│\n" +
+        "[#ff0000:-]└──┘\n"
+    if encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+
+    // Each style has corners of its own, unknown styles fall back to BorderSingle
+    for style, corners := range map[BorderStyle]string{BorderRounded: "╭╮╰╯", BorderDouble: "╔╗╚╝", BorderHeavy: "┏┓┗┛", 42: "┌┐└┘"} {
+        encoded, err = NewEncoder(WithBorder(style, red)).Encode(TestPattern(3, 4))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+        top := []rune(strings.TrimPrefix(lines[0], "[#ff0000:-]"))
+        bottom := []rune(strings.TrimPrefix(lines[len(lines) - 1], "[#ff0000:-]"))
+        got := string([]rune{top[0], top[len(top) - 1], bottom[0], bottom[len(bottom) - 1]})
+        if len(lines) != 4 || got != corners {
+            t.Errorf("style %d drew %d lines with the corners %q, want 4 lines with %q", style, len(lines), got, corners)
+        }
+    }
+}
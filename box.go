@@ -0,0 +1,131 @@
+package pxl
+
+import (
+    "html"
+    "image"
+    "image/color"
+    "strings"
+)
+
+// surround is what the space around an image centered in a box is drawn with.
+type surround struct {
+    glyph  rune
+    fg, bg color.Color
+}
+
+// WithBox centers the image in a box of cols by rows cells, drawing the space around it with the fill
+// set by WithFill(), which is blank in the default colours unless set. Rows count lines of output,
+// so in ModeTwoRowSpace a row of cells takes two of them. Images larger than the box aren't cut,
+// they're only padded in the directions the box is larger in. A border goes around the whole box.
+func WithBox(cols, rows int) Option {
+    return func(e *Encoder) {
+        e.box = image.Point{cols, rows}
+    }
+}
+
+// WithFill sets the glyph & colours the space around an image centered by WithBox() is drawn with,
+// a nil colour is the default one of the terminal. In ModeASCII only the glyph is drawn.
+func WithFill(glyph rune, fg, bg color.Color) Option {
+    return func(e *Encoder) {
+        e.surround = surround{glyph, fg, bg}
+    }
+}
+
+// padding returns how many cells of fill go before & after a line size cells long to center it in length cells.
+func padding(size, length int) (before, after int) {
+    if length <= size {
+        return 0, 0
+    }
+
+    before = (length - size) / 2
+    return before, length - size - before
+}
+
+// fillRows returns count lines of fill as wide as the box, or an image width cells wide if that's wider,
+// each bordered & followed by the separator.
+func (e *Encoder) fillRows(count, width int) string {
+    if count <= 0 {
+        return ""
+    }
+
+    if width < e.box.X {
+        width = e.box.X
+    }
+
+    line := e.fillText(width)
+    if e.border != nil {
+        side := e.borderText(string(e.border.glyphs[5]))
+        line = side + line + side
+    }
+
+    return strings.Repeat(line + e.eraseLine() + e.separator, count)
+}
+
+// fillText returns n fill glyphs in the fill colours, written for the encoder's mode.
+func (e *Encoder) fillText(n int) string {
+    if n <= 0 {
+        return ""
+    }
+
+    glyphs := strings.Repeat(string(e.surround.glyph), n)
+    fg, bg := e.surround.fg, e.surround.bg
+
+    switch e.mode {
+        default:
+            return "[" + fillTag(fg) + ":" + fillTag(bg) + "]" + glyphs
+
+        case ModeHTML:
+            var style []string
+            if fg != nil {
+                style = append(style, "color:" + ColorString(fg, e.notation))
+            }
+
+            if bg != nil {
+                style = append(style, "background-color:" + ColorString(bg, e.notation))
+            }
+
+            return `<span style="` + strings.Join(style, ";") + `">` + html.EscapeString(glyphs) + "</span>"
+
+        case ModeANSI, ModePagerSafe, ModeANSI256, ModeANSI16, ModeGray256:
+            params := []string{"0"}
+            if fg != nil {
+                params = append(params, e.sgr()(fg, false))
+            }
+
+            if bg != nil {
+                params = append(params, e.sgr()(bg, true))
+            }
+
+            return "\x1b[" + strings.Join(params, ";") + "m" + glyphs + "\x1b[0m"
+
+        case ModeASCII:
+            return glyphs
+    }
+}
+
+// fillTag writes a fill colour for a tview tag, nil is the default colour.
+func fillTag(c color.Color) string {
+    if c == nil {
+        return "-"
+    }
+
+    return tagColor(c)
+}
+
+// sgr returns how the encoder's ANSI mode writes colours as SGR parameters.
+func (e *Encoder) sgr() ansiSGR {
+    switch e.mode {
+        case ModeANSI256:
+            return ansi256SGR
+
+        case ModeANSI16:
+            return ansi16SGR
+
+        case ModeGray256:
+            return func(c color.Color, bg bool) string {
+                return ansi256SGR(ansi256[grayIndex(c)], bg)
+            }
+    }
+
+    return trueColorSGR
+}
@@ -0,0 +1,28 @@
+package pxl
+
+import (
+    "image/color"
+    "testing"
+)
+
+func TestWithFill(t *testing.T) {
+    grey, navy := color.RGBA{0x80, 0x80, 0x80, 0xff}, color.RGBA{0, 0, 0x80, 0xff}
+    img := solid(2, 2, color.White)
+
+    encoded, err := NewEncoder(WithBox(4, 3), WithFill('·', grey, navy)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#808080:#000080]····\n" +
+        "[#808080:#000080]·[#ffffff:#ffffff]▀▀[#808080:#000080]·\n" +
+        "[#808080:#000080]····\n"
+    if encoded != want {
+        t.Errorf("WithFill() = %q, want %q", encoded, want)
+    }
+
+    // Without a fill the box is blank in the default colours
+    if blank, _ := NewEncoder(WithBox(4, 3)).Encode(img); blank != "[-:-]    \n[-:-] [#ffffff:#ffffff]▀▀[-:-] \n[-:-]    \n" {
+        t.Errorf("WithBox() without a fill = %q", blank)
+    }
+}
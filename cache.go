@@ -0,0 +1,95 @@
+package pxl
+
+import (
+    "container/list"
+    "os"
+    "sync"
+    "time"
+)
+
+// defaultCache is the cache used by CachedFromFile().
+var defaultCache = NewCache(128)
+
+// CachedFromFile converts an image file to text like FromFile() does, reusing the text from
+// a previous call until the file is modified. The most recently used 128 files are kept,
+// NewCache() makes a cache of another size.
+func CachedFromFile(filename string) (encoded string, err error) {
+    return defaultCache.FromFile(filename)
+}
+
+// Cache keeps the text of recently converted image files, for programs which show the same files
+// again & again, like a file browser. When it's full the least recently used file is dropped.
+// A Cache is safe for concurrent use.
+type Cache struct {
+    mu       sync.Mutex
+    capacity int
+    order    *list.List
+    entries  map[cacheKey]*list.Element
+
+    // decode converts files which aren't cached yet
+    decode func(filename string) (string, error)
+}
+
+// cacheKey identifies a version of a file.
+type cacheKey struct {
+    filename string
+    modTime  time.Time
+    size     int64
+}
+
+type cacheEntry struct {
+    key     cacheKey
+    encoded string
+}
+
+// NewCache creates a cache which keeps at most capacity files, at least one.
+func NewCache(capacity int) *Cache {
+    if capacity < 1 {
+        capacity = 1
+    }
+
+    return &Cache{
+        capacity: capacity,
+        order:    list.New(),
+        entries:  make(map[cacheKey]*list.Element),
+        decode:   FromFile,
+    }
+}
+
+// FromFile converts an image file to text like FromFile() does, unless the cache holds
+// the text of the file as it is now, going by its modification time & size.
+func (c *Cache) FromFile(filename string) (encoded string, err error) {
+    info, err := os.Stat(filename)
+    if err != nil {
+        return
+    }
+
+    key := cacheKey{filename, info.ModTime(), info.Size()}
+
+    c.mu.Lock()
+    if elem, ok := c.entries[key]; ok {
+        c.order.MoveToFront(elem)
+        c.mu.Unlock()
+        return elem.Value.(*cacheEntry).encoded, nil
+    }
+    c.mu.Unlock()
+
+    if encoded, err = c.decode(filename); err != nil {
+        return
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if _, ok := c.entries[key]; !ok {
+        c.entries[key] = c.order.PushFront(&cacheEntry{key, encoded})
+    }
+
+    for c.order.Len() > c.capacity {
+        oldest := c.order.Back()
+        delete(c.entries, oldest.Value.(*cacheEntry).key)
+        c.order.Remove(oldest)
+    }
+
+    return
+}
@@ -0,0 +1,69 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/png"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// writePNG saves img as a PNG file named filename.
+func writePNG(t *testing.T, filename string, img image.Image) {
+    f, err := os.Create(filename)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+
+    if err = png.Encode(f, img); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestCacheFromFile(t *testing.T) {
+    dir := t.TempDir()
+    first, second := filepath.Join(dir, "first.png"), filepath.Join(dir, "second.png")
+    writePNG(t, first, solid(2, 2, color.White))
+    writePNG(t, second, TestPattern(4, 4))
+
+    cache := NewCache(1)
+    decoded := map[string]int{}
+    cache.decode = func(filename string) (string, error) {
+        decoded[filename]++
+        return FromFile(filename)
+    }
+
+    for i := 0; i < 2; i++ {
+        encoded, err := cache.FromFile(first)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want, _ := FromFile(first); encoded != want {
+            t.Errorf("FromFile() = %q, want %q", encoded, want)
+        }
+    }
+
+    if decoded[first] != 1 {
+        t.Errorf("the file was decoded %d times, want once", decoded[first])
+    }
+
+    // A file of another size is a new version of it
+    writePNG(t, first, solid(2, 4, color.White))
+    if encoded, _ := cache.FromFile(first); decoded[first] != 2 || encoded != "[#ffffff:#ffffff]▀▀\n[#ffffff:#ffffff]▀▀\n" {
+        t.Errorf("the modified file was decoded %d times in all, to %q", decoded[first], encoded)
+    }
+
+    // With room for a single file, the other one pushes it out
+    cache.FromFile(second)
+    cache.FromFile(first)
+    if decoded[first] != 3 || decoded[second] != 1 {
+        t.Errorf("files were decoded %v times, want the first one 3 times & the second once", decoded)
+    }
+
+    if _, err := cache.FromFile(filepath.Join(dir, "missing.png")); err == nil {
+        t.Error("FromFile() of a missing file succeeded")
+    }
+}
@@ -0,0 +1,423 @@
+package pxl
+
+import (
+    "fmt"
+    "image"
+    "image/color"
+    "math"
+    "strconv"
+
+    "github.com/pkg/errors"
+)
+
+// ColorNotation is a way of writing a colour as text.
+type ColorNotation int
+
+const (
+    // NotationHex writes colours as #rrggbb.
+    NotationHex ColorNotation = iota
+
+    // NotationRGB writes colours in the CSS rgb(r,g,b) functional notation.
+    NotationRGB
+)
+
+// ColorString writes a colour using the given notation.
+func ColorString(c color.Color, notation ColorNotation) string {
+    if notation == NotationRGB {
+        c8 := rgba8(c)
+        return fmt.Sprintf("rgb(%d,%d,%d)", c8.R, c8.G, c8.B)
+    }
+
+    return ColorHex(c)
+}
+
+// rgba8 converts a colour to 8 bits per channel, clamping channels outside of the 0 to 0xffff range
+// color.Color documents, which custom colour types may return anyway.
+func rgba8(c color.Color) color.RGBA {
+    r, g, b, a := c.RGBA()
+    return color.RGBA{channel8(r), channel8(g), channel8(b), channel8(a)}
+}
+
+func channel8(v uint32) uint8 {
+    if v > 0xffff {
+        return 0xff
+    }
+
+    return uint8(v >> 8)
+}
+
+// roundColor rounds every channel to the nearest 8 bit value, the result is
+// stored so that truncating it to 8 bits, like ColorHex() does, gives the rounded value back.
+func roundColor(c color.Color) color.Color {
+    r, g, b, a := c.RGBA()
+    round := func(v uint32) uint16 {
+        return uint16(channel8(v + 0x80)) * 0x101
+    }
+
+    return color.RGBA64{round(r), round(g), round(b), round(a)}
+}
+
+// checkerboard holds the two greys transparency is previewed over.
+var checkerboard = [2]color.Color{
+    color.Gray{0xcc},
+    color.Gray{0x99},
+}
+
+// Composite blends a colour over a background, which is treated as opaque.
+// Opaque colours are returned unchanged.
+func Composite(c, bg color.Color) color.Color {
+    r, g, b, a := c.RGBA()
+    if a == 0xffff {
+        return c
+    }
+
+    br, bgg, bb, _ := bg.RGBA()
+    return color.RGBA64{
+        uint16(r + br * (0xffff - a) / 0xffff),
+        uint16(g + bgg * (0xffff - a) / 0xffff),
+        uint16(b + bb * (0xffff - a) / 0xffff),
+        0xffff,
+    }
+}
+
+// compositeLinear is like Composite() but blends in linear light, converting from & back to sRGB,
+// like image editors which are gamma correct do. Semi-transparent edges come out lighter than they do with Composite().
+func compositeLinear(c, bg color.Color) color.Color {
+    n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+    if n.A == 0xffff {
+        return c
+    }
+
+    a := float64(n.A) / 0xffff
+    br, bgg, bb, _ := bg.RGBA()
+
+    blend := func(v uint16, back uint32) uint16 {
+        linear := srgbToLinear(float64(v) / 0xffff) * a + srgbToLinear(float64(back) / 0xffff) * (1 - a)
+        return uint16(math.Round(linearToSRGB(linear) * 0xffff))
+    }
+
+    return color.RGBA64{blend(n.R, br), blend(n.G, bgg), blend(n.B, bb), 0xffff}
+}
+
+// srgbToLinear converts a channel from 0 to 1 from the sRGB transfer curve to linear light.
+func srgbToLinear(v float64) float64 {
+    if v <= 0.04045 {
+        return v / 12.92
+    }
+
+    return math.Pow((v + 0.055) / 1.055, 2.4)
+}
+
+// linearToSRGB is the inverse of srgbToLinear().
+func linearToSRGB(v float64) float64 {
+    if v <= 0.0031308 {
+        return v * 12.92
+    }
+
+    return 1.055 * math.Pow(v, 1 / 2.4) - 0.055
+}
+
+// ParseHex is the inverse of ColorHex(), it parses a colour written as #rrggbb,
+// or in the #rgb shorthand, into an opaque color.RGBA.
+func ParseHex(s string) (c color.Color, err error) {
+    if (len(s) != 4 && len(s) != 7) || s[0] != '#' {
+        err = errors.Errorf("pixelview: Can't parse %q as a hex colour", s)
+        return
+    }
+
+    v, err := strconv.ParseUint(s[1:], 16, 32)
+    if err != nil {
+        err = errors.Wrapf(err, "pixelview: Can't parse %q as a hex colour", s)
+        return
+    }
+
+    if len(s) == 4 {
+        // Each digit of the shorthand is repeated, so #fa0 is #ffaa00
+        r, g, b := uint8(v >> 8 & 0xf), uint8(v >> 4 & 0xf), uint8(v & 0xf)
+        c = color.RGBA{r * 0x11, g * 0x11, b * 0x11, 0xff}
+        return
+    }
+
+    c = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+    return
+}
+
+// luminance returns the brightness of a colour from 0 to 1, weighting the channels
+// like color.GrayModel does. The colour is un-premultiplied first, so alpha doesn't darken it.
+func luminance(c color.Color) float64 {
+    n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+    return (0.299 * float64(n.R) + 0.587 * float64(n.G) + 0.114 * float64(n.B)) / 0xffff
+}
+
+// withAlphaOf replaces a colour which another has been mapped to, keeping the opacity of the original.
+func withAlphaOf(mapped, original color.Color) color.Color {
+    _, _, _, a := original.RGBA()
+    if a == 0xffff {
+        return mapped
+    }
+
+    n := color.NRGBA64Model.Convert(mapped).(color.NRGBA64)
+    n.A = uint16(uint32(n.A) * a / 0xffff)
+    return n
+}
+
+// sampleGradient returns the colour at t, from 0 to 1, along evenly spaced colour stops,
+// interpolating linearly between the two nearest ones.
+func sampleGradient(stops []color.Color, t float64) color.Color {
+    if t <= 0 || len(stops) == 1 {
+        return stops[0]
+    }
+
+    if t >= 1 {
+        return stops[len(stops) - 1]
+    }
+
+    pos := t * float64(len(stops) - 1)
+    i := int(pos)
+    return lerpColor(stops[i], stops[i + 1], pos - float64(i))
+}
+
+// lerpColor interpolates linearly between two colours, t = 0 being a & t = 1 being b.
+func lerpColor(a, b color.Color, t float64) color.Color {
+    ar, ag, ab, aa := a.RGBA()
+    br, bg, bb, ba := b.RGBA()
+    lerp := func(x, y uint32) uint16 {
+        return uint16(float64(x) + (float64(y) - float64(x)) * t + 0.5)
+    }
+
+    return color.RGBA64{lerp(ar, br), lerp(ag, bg), lerp(ab, bb), lerp(aa, ba)}
+}
+
+// darken scales the colour channels by factor, keeping the opacity.
+func darken(c color.Color, factor float64) color.Color {
+    r, g, b, a := c.RGBA()
+    scale := func(v uint32) uint16 {
+        return uint16(float64(v) * factor + 0.5)
+    }
+
+    return color.RGBA64{scale(r), scale(g), scale(b), uint16(a)}
+}
+
+// swizzle rearranges the colour channels, so channel i of the result is channel channels[i] of c.
+func swizzle(c color.Color, channels [3]int) color.Color {
+    r, g, b, a := c.RGBA()
+    rgb := [3]uint32{r, g, b}
+    return color.RGBA64{uint16(rgb[channels[0]]), uint16(rgb[channels[1]]), uint16(rgb[channels[2]]), uint16(a)}
+}
+
+// darkenAlpha scales the opacity of a colour by factor, making it more transparent.
+func darkenAlpha(c color.Color, factor float64) color.Color {
+    r, g, b, a := c.RGBA()
+    scale := func(v uint32) uint16 {
+        return uint16(float64(v) * factor + 0.5)
+    }
+
+    return color.RGBA64{scale(r), scale(g), scale(b), scale(a)}
+}
+
+// snapColor snaps the 8 bit colour channels to the nearest multiple of step.
+func snapColor(c color.Color, step uint8) color.Color {
+    c8 := rgba8(c)
+    snap := func(v uint8) uint8 {
+        snapped := (int(v) + int(step) / 2) / int(step) * int(step)
+        if snapped > int(c8.A) {
+            // Premultiplied channels can't exceed the alpha
+            snapped -= int(step)
+        }
+
+        return uint8(snapped)
+    }
+
+    return color.RGBA{snap(c8.R), snap(c8.G), snap(c8.B), c8.A}
+}
+
+// posterize reduces every colour channel to levels evenly spaced values, from 0 to 0xff,
+// rounding to the nearest one. The channels are taken without the alpha premultiplied, so opaque colours
+// come out exactly on the levels.
+func posterize(c color.Color, levels int) color.Color {
+    n := color.NRGBAModel.Convert(c).(color.NRGBA)
+    steps := float64(levels - 1)
+    level := func(v uint8) uint8 {
+        return uint8(math.Round(math.Round(float64(v) * steps / 0xff) * 0xff / steps))
+    }
+
+    return color.NRGBA{level(n.R), level(n.G), level(n.B), n.A}
+}
+
+// straightImage reads an image whose RGBA() method returns straight rather than premultiplied alpha,
+// returning colours that follow the image/color conventions.
+type straightImage struct {
+    image.Image
+}
+
+func (img straightImage) ColorModel() color.Model {
+    return color.NRGBA64Model
+}
+
+func (img straightImage) At(x, y int) color.Color {
+    r, g, b, a := img.Image.At(x, y).RGBA()
+    return color.NRGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}
+}
+
+// stretchContrast returns an image with its luminance stretched from that of the darkest to the lightest pixel
+// to the full range, ignoring the clip fraction of pixels at either end. Transparent pixels aren't counted.
+func stretchContrast(img image.Image, clip float64) image.Image {
+    var hist [256]int
+    var total int
+    bounds := img.Bounds()
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            c := img.At(x, y)
+            if _, _, _, a := c.RGBA(); a > 0 {
+                hist[int(math.Round(luminance(c) * 0xff))]++
+                total++
+            }
+        }
+    }
+
+    if total == 0 {
+        return img
+    }
+
+    // The darkest & lightest levels with more than the clipped pixels beyond them
+    skip := int(clip * float64(total))
+    low, high := 0, 0xff
+    for seen := hist[low]; seen <= skip && low < 0xff; seen += hist[low] {
+        low++
+    }
+
+    for seen := hist[high]; seen <= skip && high > 0; seen += hist[high] {
+        high--
+    }
+
+    if high <= low {
+        return img
+    }
+
+    return contrastImage{img, float64(low) / 0xff, float64(high) / 0xff}
+}
+
+// contrastImage stretches the channels of every pixel linearly from low to high onto the full range.
+type contrastImage struct {
+    image.Image
+    low, high float64
+}
+
+func (img contrastImage) At(x, y int) color.Color {
+    n := color.NRGBA64Model.Convert(img.Image.At(x, y)).(color.NRGBA64)
+    stretch := func(v uint16) uint16 {
+        t := (float64(v) / 0xffff - img.low) / (img.high - img.low)
+        return uint16(math.Round(math.Max(0, math.Min(t, 1)) * 0xffff))
+    }
+
+    return color.NRGBA64{stretch(n.R), stretch(n.G), stretch(n.B), n.A}
+}
+
+// temperatureRGB approximates the colour of light of a colour temperature, with channels from 0 to 1,
+// with the fit of Tanner Helland to the blackbody data of Mitchell Charity.
+func temperatureRGB(kelvin float64) [3]float64 {
+    t := math.Max(1000, math.Min(kelvin, 40000)) / 100
+
+    r, g, b := 255.0, 0.0, 255.0
+    if t > 66 {
+        r = 329.698727446 * math.Pow(t - 60, -0.1332047592)
+        g = 288.1221695283 * math.Pow(t - 60, -0.0755148492)
+    } else {
+        g = 99.4708025861 * math.Log(t) - 161.1195681661
+    }
+
+    switch {
+        case t <= 19:
+            b = 0
+
+        case t < 66:
+            b = 138.5177312231 * math.Log(t - 10) - 305.0447927307
+    }
+
+    clamp := func(v float64) float64 {
+        return math.Max(0, math.Min(v, 255)) / 255
+    }
+
+    return [3]float64{clamp(r), clamp(g), clamp(b)}
+}
+
+// temperatureScale returns what the channels are multiplied by to shift the white balance from 6500 K to kelvin.
+func temperatureScale(kelvin float64) (scale [3]float64) {
+    target, neutral := temperatureRGB(kelvin), temperatureRGB(6500)
+    for i := range scale {
+        scale[i] = target[i] / neutral[i]
+    }
+
+    return
+}
+
+// scaleChannels multiplies the red, green & blue channels of a colour, clamping them to its alpha.
+func scaleChannels(c color.Color, scale [3]float64) color.Color {
+    r, g, b, a := c.RGBA()
+    channel := func(v uint32, s float64) uint16 {
+        return uint16(math.Min(math.Round(float64(v) * s), float64(a)))
+    }
+
+    return color.RGBA64{channel(r, scale[0]), channel(g, scale[1]), channel(b, scale[2]), uint16(a)}
+}
+
+// Thresholds desubpixelImage tells subpixel fringes by: how much darker one side of the pixel is than the other,
+// & how much more saturated than either side the pixel is, both from 0 to 1.
+const (
+    fringeEdge   = 0.25
+    fringeChroma = 0.15
+)
+
+// desubpixelImage desaturates the coloured fringes LCD subpixel antialiasing leaves along the edges of text,
+// which show as stray colours once a screenshot is scaled down to cells. A pixel is taken for a fringe
+// when it sits on a horizontal edge, between neighbours much lighter & darker than each other,
+// & is far more saturated than both of them. It's replaced with the grey of its luminance.
+type desubpixelImage struct {
+    image.Image
+}
+
+func (img desubpixelImage) At(x, y int) color.Color {
+    c := img.Image.At(x, y)
+    bounds := img.Bounds()
+    if x <= bounds.Min.X || x >= bounds.Max.X - 1 {
+        return c
+    }
+
+    left, right := img.Image.At(x - 1, y), img.Image.At(x + 1, y)
+    if math.Abs(luminance(left) - luminance(right)) < fringeEdge ||
+        chroma(c) - math.Max(chroma(left), chroma(right)) < fringeChroma {
+        return c
+    }
+
+    _, _, _, a := c.RGBA()
+    grey := uint16(luminance(c) * float64(a) + 0.5)
+    return color.RGBA64{grey, grey, grey, uint16(a)}
+}
+
+// chroma returns how far apart the lightest & darkest channels of a colour are, from 0 for greys to 1.
+func chroma(c color.Color) float64 {
+    n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+    return float64(max16(n.R, n.G, n.B) - min16(n.R, n.G, n.B)) / 0xffff
+}
+
+func max16(values ...uint16) (max uint16) {
+    for _, v := range values {
+        if v > max {
+            max = v
+        }
+    }
+
+    return
+}
+
+func min16(values ...uint16) (min uint16) {
+    min = 0xffff
+    for _, v := range values {
+        if v < min {
+            min = v
+        }
+    }
+
+    return
+}
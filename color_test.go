@@ -0,0 +1,194 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+    "testing"
+)
+
+func TestParseHex(t *testing.T) {
+    valid := map[string]color.RGBA{
+        "#ffffff": {0xff, 0xff, 0xff, 0xff},
+        "#fff":    {0xff, 0xff, 0xff, 0xff},
+        "#fa0":    {0xff, 0xaa, 0x00, 0xff},
+        "#12AbEf": {0x12, 0xab, 0xef, 0xff},
+        "#000000": {0, 0, 0, 0xff},
+    }
+
+    for s, want := range valid {
+        c, err := ParseHex(s)
+        if err != nil {
+            t.Errorf("ParseHex(%q) failed: %v", s, err)
+            continue
+        }
+
+        if c != want {
+            t.Errorf("ParseHex(%q) = %v, want %v", s, c, want)
+        }
+
+        if len(s) == 7 && ColorHex(c) != strings.ToLower(s) {
+            t.Errorf("ColorHex(ParseHex(%q)) = %q", s, ColorHex(c))
+        }
+    }
+
+    for _, s := range []string{"", "#", "ffffff", "#ffff", "#fffffff", "#gggggg", "#-12345", " #fff"} {
+        if c, err := ParseHex(s); err == nil {
+            t.Errorf("ParseHex(%q) = %v, want an error", s, c)
+        }
+    }
+}
+
+// overRange is a colour whose RGBA() goes past the documented 0xffff.
+type overRange struct{}
+
+func (overRange) RGBA() (r, g, b, a uint32) {
+    return 0x1ffff, 0x10000, 0x7f00, 0xffffff
+}
+
+// overRangeImage is an image filled with overRange colours.
+type overRangeImage struct{}
+
+func (overRangeImage) ColorModel() color.Model { return color.RGBA64Model }
+func (overRangeImage) Bounds() image.Rectangle { return image.Rect(0, 0, 2, 2) }
+func (overRangeImage) At(x, y int) color.Color { return overRange{} }
+
+func TestColorHexClamps(t *testing.T) {
+    if got, want := ColorHex(overRange{}), "#ffff7f"; got != want {
+        t.Errorf("ColorHex() of an over-range colour = %q, want %q", got, want)
+    }
+
+    encoded, err := FromImageGeneric(overRangeImage{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#ffff7f:#ffff7f]▀▀\n"; encoded != want {
+        t.Errorf("FromImageGeneric() = %q, want %q", encoded, want)
+    }
+}
+
+func TestWithDesubpixel(t *testing.T) {
+    red, blue, green := color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}, color.NRGBA{0, 0xff, 0, 0xff}
+
+    // Red & blue fringes on the edges of a white stroke, next to a flat green area
+    row := []color.Color{color.Black, red, color.White, color.White, blue, color.Black, green, green, green}
+    img := image.NewNRGBA(image.Rect(0, 0, len(row), 2))
+    for x, c := range row {
+        img.Set(x, 0, c)
+        img.Set(x, 1, c)
+    }
+
+    pixels, err := NewEncoder(WithDesubpixel(true)).Decode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for x, c := range row {
+        want := ColorHex(c)
+        if x == 1 || x == 4 {
+            want = ColorHex(color.Gray{uint8(luminance(c) * 0xff + 0.5)})
+        }
+
+        if got := ColorHex(pixels[0][x].Fg); got != want {
+            t.Errorf("pixel %d is %s, want %s", x, got, want)
+        }
+    }
+
+    // Without the option the fringes are left alone
+    plain, err := NewEncoder(WithDesubpixel(false)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); plain != want {
+        t.Errorf("WithDesubpixel(false) = %q, want %q", plain, want)
+    }
+}
+
+func TestWithAutoContrast(t *testing.T) {
+    // A washed-out gradient from 100 to 150, & a copy with a speck of black in a corner
+    gradient := image.NewGray(image.Rect(0, 0, 51, 2))
+    for i := range gradient.Pix {
+        gradient.Pix[i] = uint8(100 + i % 51)
+    }
+
+    speck := image.NewGray(gradient.Rect)
+    copy(speck.Pix, gradient.Pix)
+    speck.Pix[0] = 0
+
+    // The range of grey levels the output spans
+    span := func(img image.Image, options ...Option) (low, high uint8) {
+        encoded, err := NewEncoder(options...).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        decoded, err := ToImage(encoded)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        low, high = 0xff, 0
+        for x := 1; x < 51; x++ {
+            level := color.GrayModel.Convert(decoded.At(x, 0)).(color.Gray).Y
+            if level < low {
+                low = level
+            }
+
+            if level > high {
+                high = level
+            }
+        }
+
+        return
+    }
+
+    if low, high := span(gradient); low != 101 || high != 150 {
+        t.Fatalf("without stretching the gradient spans %d to %d, want 101 to 150", low, high)
+    }
+
+    if low, high := span(gradient, WithAutoContrast(true)); low > 5 || high != 0xff {
+        t.Errorf("stretched gradient spans %d to %d, want 0 to 255", low, high)
+    }
+
+    // The speck holds back the stretch, unless it's clipped
+    if low, _ := span(speck, WithAutoContrast(true)); low < 90 {
+        t.Errorf("with a speck of black the stretched gradient starts at %d, want it hardly darker", low)
+    }
+
+    if low, high := span(speck, WithAutoContrast(true), WithContrastClip(0.02)); low > 5 || high != 0xff {
+        t.Errorf("clipping the speck the stretched gradient spans %d to %d, want 0 to 255", low, high)
+    }
+}
+
+func TestWithColorTemperature(t *testing.T) {
+    gray := solid(1, 2, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+
+    // The colour the grey is written as at a temperature
+    shifted := func(kelvin float64) color.NRGBA {
+        encoded, err := NewEncoder(WithColorTemperature(kelvin)).Encode(gray)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        decoded, err := ToImage(encoded)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return color.NRGBAModel.Convert(decoded.At(0, 0)).(color.NRGBA)
+    }
+
+    if warm := shifted(3000); warm.R <= warm.B + 0x20 {
+        t.Errorf("at 3000 K grey is %s, want red boosted over blue", ColorHex(warm))
+    }
+
+    if cool := shifted(12000); cool.B <= cool.R + 0x10 {
+        t.Errorf("at 12000 K grey is %s, want blue boosted over red", ColorHex(cool))
+    }
+
+    if neutral := shifted(6500); ColorHex(neutral) != "#808080" {
+        t.Errorf("at 6500 K grey is %s, want it unchanged", ColorHex(neutral))
+    }
+}
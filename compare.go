@@ -0,0 +1,56 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+
+    "github.com/pkg/errors"
+)
+
+// DiffHeatmap converts the difference between two images of the same size to text, like FromImage() does,
+// for eyeballing where a screenshot changed. Identical pixels are black, the more two pixels differ
+// the redder they get, up to pure red for the largest possible difference in a channel.
+func DiffHeatmap(a, b image.Image) (encoded string, err error) {
+    if a.Bounds().Size() != b.Bounds().Size() {
+        err = errors.New("pixelview: Can't diff images of different sizes")
+        return
+    }
+
+    size := a.Bounds().Size()
+    heat := image.NewRGBA(image.Rectangle{Max: size})
+
+    for y := 0; y < size.Y; y++ {
+        for x := 0; x < size.X; x++ {
+            ca := a.At(a.Bounds().Min.X + x, a.Bounds().Min.Y + y)
+            cb := b.At(b.Bounds().Min.X + x, b.Bounds().Min.Y + y)
+
+            t := float64(difference(ca, cb)) / 0xffff
+            heat.Set(x, y, lerpColor(color.Black, color.RGBA{0xff, 0, 0, 0xff}, t))
+        }
+    }
+
+    return FromImage(heat)
+}
+
+// difference returns the largest difference between the channels of two colours.
+func difference(a, b color.Color) (max uint32) {
+    ar, ag, ab, aa := a.RGBA()
+    br, bg, bb, ba := b.RGBA()
+
+    for _, d := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+        diff := d[0] - d[1]
+        if d[1] > d[0] {
+            diff = d[1] - d[0]
+        }
+
+        if diff > max {
+            max = diff
+        }
+    }
+
+    if max > 0xffff {
+        max = 0xffff
+    }
+
+    return
+}
@@ -0,0 +1,38 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "testing"
+)
+
+func TestDiffHeatmap(t *testing.T) {
+    a := TestPattern(4, 4).(*image.NRGBA)
+    b := image.NewNRGBA(image.Rect(10, 10, 14, 14))
+    draw.Draw(b, b.Rect, a, image.Point{}, draw.Src)
+
+    // The top-right cell changes as much as it can, the one left of it by half of that
+    b.Set(13, 10, color.White)
+    b.Set(13, 11, color.White)
+    b.SetNRGBA(12, 10, color.NRGBA{0, 0, 0, 0x7f})
+    b.SetNRGBA(12, 11, color.NRGBA{0, 0, 0, 0x7f})
+    a.SetNRGBA(2, 0, color.NRGBA{0, 0, 0, 0xff})
+    a.SetNRGBA(2, 1, color.NRGBA{0, 0, 0, 0xff})
+    a.Set(3, 0, color.Black)
+    a.Set(3, 1, color.Black)
+
+    encoded, err := DiffHeatmap(a, b)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#000000:#000000]▀▀[#800000:#800000]▀[#ff0000:#ff0000]▀\n[#000000:#000000]▀▀▀▀\n"
+    if encoded != want {
+        t.Errorf("DiffHeatmap() = %q, want %q", encoded, want)
+    }
+
+    if _, err = DiffHeatmap(a, TestPattern(4, 6)); err == nil {
+        t.Error("DiffHeatmap() of images of different sizes succeeded")
+    }
+}
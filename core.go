@@ -6,8 +6,8 @@ import (
     "fmt"
     "image"
     "image/color"
-
-    "github.com/pkg/errors"
+    "image/draw"
+    "strings"
 )
 
 // FromFile func is a convenience function that converts a file to a formatted string.
@@ -40,101 +40,228 @@ func FromReader(reader io.Reader) (encoded string, err error) {
 // pixels in the returned string.
 // Because each character represents two pixels, it is not possible to convert an
 func FromImage(img image.Image) (encoded string, err error) {
-    if (img.Bounds().Max.Y - img.Bounds().Min.Y) % 2 != 0 {
-        err = errors.New("pixelview: Can't process image with uneven height")
+    // Solid images, like placeholders, take a single tag a line
+    if c, ok := uniformColor(img); ok {
+        if err = checkHeight(img); err != nil {
+            return
+        }
+
+        return fromUniform(c, img.Bounds().Dx(), img.Bounds().Dy() / 2), nil
+    }
+
+    if v, ok := img.(*image.Paletted); ok && len(v.Palette) <= 2 {
+        return FromPaletted(v)
+    }
+
+    pixels, err := DecodeToPixels(img)
+    if err != nil {
         return
     }
 
-    switch v := img.(type) {
-		default:
-			return FromImageGeneric(img)
+    return pixels.Encode(), nil
+}
 
-		case *image.Paletted:
-			return FromPaletted(v)
+// uniformColor returns the colour of an image whose pixels are all written the same way, like a placeholder,
+// or false if they aren't or the image is empty. Paletted images with a single colour, or none, aren't scanned.
+func uniformColor(img image.Image) (color.Color, bool) {
+    bounds := img.Bounds()
+    if bounds.Empty() {
+        return nil, false
+    }
 
-		case *image.NRGBA:
-			return FromNRGBA(v)
+    if v, ok := img.(*image.Paletted); ok && len(v.Palette) <= 1 {
+        return clearTransparent(v.Palette)[0], true
     }
+
+    first := img.At(bounds.Min.X, bounds.Min.Y)
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            if !SameCell(first, img.At(x, y)) {
+                return nil, false
+            }
+        }
+    }
+
+    return first, true
+}
+
+// fromUniform emits cols by rows cells of a single colour with a single tag at the start of every line,
+// so like any other output each line can be split off & drawn on its own.
+func fromUniform(c color.Color, cols, rows int) string {
+    tag := tagColor(c)
+    line := "[" + tag + ":" + tag + "]" + strings.Repeat("▀", cols) + "\n"
+    return strings.Repeat(line, rows)
 }
 
 // FromImageGeneric is the fallback function for processing images.
 // It will be used for more exotic image formats than png or gif.
 func FromImageGeneric(img image.Image) (encoded string, err error) {
+    var pixels Pixels
     for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y += 2 {
-        var prevfg, prevbg color.Color
-        for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
-            fg := img.At(x, y)
-            bg := img.At(x, y + 1)
-            encoded += Encode(fg, bg, &prevfg, &prevbg)
-        }
-
-        encoded += "\n"
+        pixels = append(pixels, genericRow(img, y))
     }
 
-    return
+    return pixels.Encode(), nil
 }
 
 // FromPaletted saves a few μs when working with paletted images.
-// These are what PNG8 images are decoded as.
+// These are what PNG8 images are decoded as. Like FromImage(), it can't process an image with an uneven height.
 func FromPaletted(img *image.Paletted) (encoded string, err error) {
-    for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
-        var prevfg, prevbg color.Color
+    if err = checkHeight(img); err != nil {
+        return
+    }
 
-        for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
-            i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X)
-            fg := img.Palette[img.Pix[i]]
-            bg := img.Palette[img.Pix[i + img.Stride]]
-            encoded += Encode(fg, bg, &prevfg, &prevbg)
-        }
+    if len(img.Palette) <= 2 {
+        return fromBilevel(img), nil
+    }
 
-        encoded += "\n"
+    var pixels Pixels
+    palette := clearTransparent(img.Palette)
+    for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
+        pixels = append(pixels, palettedRow(img, palette, y))
     }
 
-    return
+    return pixels.Encode(), nil
 }
 
 // FromNRGBA saves a handful of μs when working with NRGBA images.
-// These are what PNG24 images are decoded as.
+// These are what PNG24 images are decoded as. Like FromImage(), it can't process an image with an uneven height.
 func FromNRGBA(img *image.NRGBA) (encoded string, err error) {
+    if err = checkHeight(img); err != nil {
+        return
+    }
+
+    var pixels Pixels
+    for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
+        pixels = append(pixels, nrgbaRow(img, y))
+    }
+
+    return pixels.Encode(), nil
+}
+
+// FromYCbCr saves a handful of μs when working with YCbCr images.
+// These are what JPEG images are decoded as, with any of the chroma subsampling ratios they use.
+// Like FromImage(), it can't process an image with an uneven height.
+func FromYCbCr(img *image.YCbCr) (encoded string, err error) {
+    if err = checkHeight(img); err != nil {
+        return
+    }
+
+    if c, ok := uniformColor(img); ok {
+        return fromUniform(c, img.Rect.Dx(), img.Rect.Dy() / 2), nil
+    }
+
+    var pixels Pixels
     for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
-        var prevfg, prevbg color.Color
+        pixels = append(pixels, ycbcrRow(img, y))
+    }
+
+    return pixels.Encode(), nil
+}
+
+// Prepare converts an image to NRGBA once, keeping its bounds, so encoding it many times,
+// like in several modes, takes the FromNRGBA() fast path every time. NRGBA images are returned as they are.
+// Opaque pixels convert exactly, semi-transparent ones may be off by one level of a channel.
+func Prepare(img image.Image) *image.NRGBA {
+    if v, ok := img.(*image.NRGBA); ok {
+        return v
+    }
+
+    bounds := img.Bounds()
+    prepared := image.NewNRGBA(bounds)
+    draw.Draw(prepared, bounds, img, bounds.Min, draw.Src)
+    return prepared
+}
+
+// FromRGBA saves a handful of μs when working with RGBA images.
+// These are what screenshot libraries usually hand back, often as sub-images with a non-zero Rect.Min.
+// Like image.RGBA itself, it takes the pixels to be alpha-premultiplied,
+// which makes no difference for the opaque pixels of a screenshot. Like FromImage(), it can't process an image
+// with an uneven height.
+func FromRGBA(img *image.RGBA) (encoded string, err error) {
+    if err = checkHeight(img); err != nil {
+        return
+    }
+
+    var pixels Pixels
+    for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
+        pixels = append(pixels, rgbaRow(img, y))
+    }
+
+    return pixels.Encode(), nil
+}
+
+// fromBilevel handles paletted images with at most two colours, like 1-bit PNGs.
+// There are so few possible colour pairs that it compares palette indices
+// & formats each tag once instead of once per colour change.
+func fromBilevel(img *image.Paletted) string {
+    var b strings.Builder
+    var hex [2]string
+    var index = [2]int{0, 1}
+    var palette = clearTransparent(img.Palette)
+
+    for i, c := range palette {
+        hex[i] = tagColor(c)
+    }
+
+    if len(palette) == 2 && SameCell(palette[0], palette[1]) {
+        index[1] = 0
+    }
+
+    for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y += 2 {
+        prevfg, prevbg := -1, -1
 
         for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
-            i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X) * 4
-            fg := color.NRGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
-            i += img.Stride
-            bg := color.NRGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
-            encoded += Encode(fg, bg, &prevfg, &prevbg)
+            i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X)
+            fg, bg := index[img.Pix[i]], index[img.Pix[i + img.Stride]]
+
+            switch {
+                case fg == prevfg && bg == prevbg:
+                    b.WriteString("▀")
+
+                case fg == prevfg:
+                    b.WriteString("[:" + hex[bg] + "]▀")
+
+                case bg == prevbg:
+                    b.WriteString("[" + hex[fg] + ":]▀")
+
+                default:
+                    b.WriteString("[" + hex[fg] + ":" + hex[bg] + "]▀")
+            }
+
+            prevfg, prevbg = fg, bg
         }
 
-        encoded += "\n"
+        b.WriteString("\n")
     }
 
-    return
+    return b.String()
 }
 
 // Encode converts a fg & bg colour into a formatted pair of 'pixels',
-// using the prevfg & prevbg colours to perform something akin to run-length encoding
+// using the prevfg & prevbg colours to perform something akin to run-length encoding.
+// Fully transparent colours are written as -, so the terminal's own background shows through
 func Encode(fg, bg color.Color, prevfg, prevbg *color.Color) (encoded string) {
-    if fg == *prevfg && bg == *prevbg {
+    sameFg, sameBg := SameCell(fg, *prevfg), SameCell(bg, *prevbg)
+    if sameFg && sameBg {
         encoded = "▀"
         return
     }
 
-    if fg == *prevfg {
+    if sameFg {
         encoded = fmt.Sprintf(
             "[:%s]▀",
-            ColorHex(bg),
+            tagColor(bg),
         )
 
         *prevbg = bg
         return
     }
 
-    if bg == *prevbg {
+    if sameBg {
         encoded = fmt.Sprintf(
             "[%s:]▀",
-            ColorHex(fg),
+            tagColor(fg),
         )
 
         *prevfg = fg
@@ -143,8 +270,8 @@ func Encode(fg, bg color.Color, prevfg, prevbg *color.Color) (encoded string) {
 
     encoded = fmt.Sprintf(
         "[%s:%s]▀",
-        ColorHex(fg),
-        ColorHex(bg),
+        tagColor(fg),
+        tagColor(bg),
     )
 
     *prevfg = fg
@@ -152,7 +279,40 @@ func Encode(fg, bg color.Color, prevfg, prevbg *color.Color) (encoded string) {
     return
 }
 
+// SameCell reports whether Encode() writes two colours the same way, in which case it doesn't repeat a tag for them.
+// That's when they're both fully transparent, or neither is & they're equal once reduced to 8 bits per channel,
+// since tags don't carry any alpha. Colours of different types can be the same.
+func SameCell(a, b color.Color) bool {
+    if a == b {
+        return true
+    }
+
+    if a == nil || b == nil {
+        return false
+    }
+
+    _, _, _, alphaA := a.RGBA()
+    _, _, _, alphaB := b.RGBA()
+    if alphaA == 0 || alphaB == 0 {
+        return alphaA == alphaB
+    }
+
+    a8, b8 := rgba8(a), rgba8(b)
+    return a8.R == b8.R && a8.G == b8.G && a8.B == b8.B
+}
+
+// ColorHex writes a colour as #rrggbb, the notation tview understands.
 func ColorHex(c color.Color) string {
-    r, g, b, _ := c.RGBA()
-    return fmt.Sprintf("#%.2x%.2x%.2x", r >> 8, g >> 8, b >> 8)
+    c8 := rgba8(c)
+    return fmt.Sprintf("#%.2x%.2x%.2x", c8.R, c8.G, c8.B)
+}
+
+// tagColor writes a colour for a tview tag, fully transparent colours become -,
+// which tview takes for the default colour of the terminal.
+func tagColor(c color.Color) string {
+    if _, _, _, a := c.RGBA(); a == 0 {
+        return "-"
+    }
+
+    return ColorHex(c)
 }
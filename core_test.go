@@ -0,0 +1,358 @@
+package pxl
+
+import (
+    "bytes"
+    "image"
+    "image/color"
+    "image/color/palette"
+    "image/draw"
+    "image/png"
+    "strings"
+    "testing"
+)
+
+// solid returns a w by h image filled with c.
+func solid(w, h int, c color.Color) *image.NRGBA {
+    img := image.NewNRGBA(image.Rect(0, 0, w, h))
+    draw.Draw(img, img.Rect, image.NewUniform(c), image.Point{}, draw.Src)
+    return img
+}
+
+func TestFromImageSolidTagsEveryLine(t *testing.T) {
+    for _, c := range []color.Color{color.NRGBA{10, 20, 30, 255}, color.Transparent, color.NRGBA{10, 20, 30, 128}} {
+        img := solid(3, 6, c)
+
+        encoded, err := FromImage(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        pixels, err := DecodeToPixels(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want := pixels.Encode(); encoded != want {
+            t.Errorf("FromImage(%v) = %q, Pixels.Encode() = %q", c, encoded, want)
+        }
+
+        for i, line := range strings.Split(strings.TrimSuffix(encoded, "\n"), "\n") {
+            if !strings.HasPrefix(line, "[") {
+                t.Errorf("line %d of %q doesn't start with a tag", i, encoded)
+            }
+        }
+    }
+}
+
+func TestStackVerticalSolidBlock(t *testing.T) {
+    block, err := FromImage(solid(2, 4, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    other, err := FromImage(solid(4, 2, color.Black))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#ffffff:#ffffff]▀▀[-:-]  \n[#ffffff:#ffffff]▀▀[-:-]  \n[#000000:#000000]▀▀▀▀\n"
+    if got := StackVertical(block, other); got != want {
+        t.Errorf("StackVertical() = %q, want %q", got, want)
+    }
+}
+
+// ycbcrImage fills a YCbCr image of a subsampling ratio with colours which change with every pixel.
+func ycbcrImage(rect image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+    img := image.NewYCbCr(rect, ratio)
+    for y := rect.Min.Y; y < rect.Max.Y; y++ {
+        for x := rect.Min.X; x < rect.Max.X; x++ {
+            img.Y[img.YOffset(x, y)] = uint8(x * 37 + y * 11)
+            c := img.COffset(x, y)
+            img.Cb[c], img.Cr[c] = uint8(x * 13 + 40), uint8(y * 29 + 90)
+        }
+    }
+
+    return img
+}
+
+func TestFromYCbCrSubsampleRatios(t *testing.T) {
+    ratios := []image.YCbCrSubsampleRatio{
+        image.YCbCrSubsampleRatio444, image.YCbCrSubsampleRatio422, image.YCbCrSubsampleRatio420,
+        image.YCbCrSubsampleRatio440, image.YCbCrSubsampleRatio411, image.YCbCrSubsampleRatio410,
+    }
+
+    for _, ratio := range ratios {
+        // An odd offset, which the chroma planes don't line up with
+        img := ycbcrImage(image.Rect(3, -5, 14, 5), ratio)
+
+        encoded, err := FromYCbCr(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want, _ := FromImageGeneric(img); encoded != want {
+            t.Errorf("%v: FromYCbCr() = %q, want %q", ratio, encoded, want)
+        }
+
+        if want, _ := FromImage(img); encoded != want {
+            t.Errorf("%v: FromYCbCr() differs from FromImage()", ratio)
+        }
+    }
+}
+
+func TestFromYCbCrSolidAndUneven(t *testing.T) {
+    img := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)
+    for i := range img.Y {
+        img.Y[i] = 0x80
+    }
+
+    encoded, err := FromYCbCr(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); encoded != want {
+        t.Errorf("FromYCbCr() of a solid image = %q, FromImage() = %q", encoded, want)
+    }
+
+    if _, err = FromYCbCr(image.NewYCbCr(image.Rect(0, 0, 4, 5), image.YCbCrSubsampleRatio420)); err != ErrOddHeight {
+        t.Errorf("FromYCbCr() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestFromImageBilevelPNG(t *testing.T) {
+    img := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+    for y := 0; y < 4; y++ {
+        for x := 0; x < 4; x++ {
+            img.SetColorIndex(x, y, uint8((x + y) % 2))
+        }
+    }
+
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, img); err != nil {
+        t.Fatal(err)
+    }
+
+    decoded, err := png.Decode(&buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if p, ok := decoded.(*image.Paletted); !ok || len(p.Palette) != 2 {
+        t.Fatalf("1-bit PNG decoded as %T, want a 2 colour paletted image", decoded)
+    }
+
+    encoded, err := FromImage(decoded)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every row of cells alternates between black over white & white over black
+    row := "[#000000:#ffffff]▀[#ffffff:#000000]▀[#000000:#ffffff]▀[#ffffff:#000000]▀\n"
+    if want := row + row; encoded != want {
+        t.Errorf("FromImage() = %q, want %q", encoded, want)
+    }
+
+    if generic, _ := FromImageGeneric(decoded); encoded != generic {
+        t.Errorf("bilevel path wrote %q, generic path %q", encoded, generic)
+    }
+}
+
+func TestFastPathsUnevenHeight(t *testing.T) {
+    bilevel := image.NewPaletted(image.Rect(0, 0, 2, 3), color.Palette{color.Black, color.White})
+    bilevel.SetColorIndex(1, 2, 1)
+
+    rgba := image.NewRGBA(image.Rect(0, 0, 2, 3))
+    images := []image.Image{bilevel, image.NewPaletted(bilevel.Rect, palette.WebSafe), solid(2, 3, color.White), rgba}
+
+    for _, img := range images {
+        if _, err := FromImage(img); err != ErrOddHeight {
+            t.Errorf("FromImage() of an uneven %T returned %v, want ErrOddHeight", img, err)
+        }
+    }
+
+    if _, err := FromPaletted(bilevel); err != ErrOddHeight {
+        t.Errorf("FromPaletted() of an uneven bilevel image returned %v, want ErrOddHeight", err)
+    }
+
+    if _, err := FromNRGBA(solid(2, 3, color.White)); err != ErrOddHeight {
+        t.Errorf("FromNRGBA() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+
+    if _, err := FromRGBA(rgba); err != ErrOddHeight {
+        t.Errorf("FromRGBA() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestFromImageEmptyPalette(t *testing.T) {
+    img := image.NewPaletted(image.Rect(0, 0, 2, 2), nil)
+    want := "[-:-]▀▀\n"
+
+    encoded, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if encoded != want {
+        t.Errorf("FromImage() of an empty palette = %q, want %q", encoded, want)
+    }
+
+    if encoded, _ = FromPaletted(img); encoded != want {
+        t.Errorf("FromPaletted() of an empty palette = %q, want %q", encoded, want)
+    }
+
+    if encoded, _ = NewEncoder().Encode(img); encoded != want {
+        t.Errorf("Encode() of an empty palette = %q, want %q", encoded, want)
+    }
+}
+
+func TestFromRGBAScreenshot(t *testing.T) {
+    screen := image.NewRGBA(image.Rect(0, 0, 6, 6))
+    draw.Draw(screen, screen.Rect, TestPattern(6, 6), image.Point{}, draw.Src)
+
+    encoded, err := FromRGBA(screen)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImageGeneric(screen); encoded != want {
+        t.Errorf("FromRGBA() = %q, want %q", encoded, want)
+    }
+
+    // A window cropped out of the screen, at an offset into its pixels
+    window := screen.SubImage(image.Rect(3, 1, 5, 5)).(*image.RGBA)
+    encoded, err = FromRGBA(window)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var want strings.Builder
+    for y := 1; y < 5; y += 2 {
+        for x := 3; x < 5; x++ {
+            want.WriteString("[" + ColorHex(screen.At(x, y)) + ":" + ColorHex(screen.At(x, y + 1)) + "]▀")
+        }
+        want.WriteString("\n")
+    }
+
+    if encoded != want.String() {
+        t.Errorf("FromRGBA() of a window = %q, want %q", encoded, want.String())
+    }
+}
+
+func TestFromImageTransparentHalves(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    img.Set(2, 0, color.NRGBA{0, 0xff, 0, 0xff})
+    img.Set(2, 1, color.NRGBA{0x12, 0x34, 0x56, 0})
+
+    encoded, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Transparent pixels, whatever their colour channels hold, take the default colour
+    if want := "[#ff0000:-]▀[-:#0000ff]▀[#00ff00:-]▀\n"; encoded != want {
+        t.Errorf("FromImage() = %q, want %q", encoded, want)
+    }
+
+    // Unless a background is set
+    if themed, _ := NewEncoder(WithThemeBackground(color.Black)).Encode(img); themed != "[#ff0000:#000000]▀[#000000:#0000ff]▀[#00ff00:#000000]▀\n" {
+        t.Errorf("over a theme = %q, want every half drawn", themed)
+    }
+}
+
+// generic hides the type of an image, so it takes the generic path.
+type generic struct {
+    image.Image
+}
+
+func TestNegativeOrigin(t *testing.T) {
+    // With a transparent corner, so the preview's checkerboard shows through
+    pattern := TestPattern(7, 10).(*image.NRGBA)
+    draw.Draw(pattern, image.Rect(0, 0, 3, 4), image.Transparent, image.Point{}, draw.Src)
+    negative := image.Rect(-3, -5, 4, 5)
+
+    // Each image is drawn at both origins, & a part of it is cut out at both
+    copies := func(newImage func(r image.Rectangle) draw.Image) (moved, origin draw.Image) {
+        moved, origin = newImage(negative), newImage(pattern.Bounds())
+        draw.Draw(moved, negative, pattern, image.Point{}, draw.Src)
+        draw.Draw(origin, origin.Bounds(), pattern, image.Point{}, draw.Src)
+        return
+    }
+
+    types := map[string]func(r image.Rectangle) draw.Image{
+        "NRGBA":    func(r image.Rectangle) draw.Image { return image.NewNRGBA(r) },
+        "RGBA":     func(r image.Rectangle) draw.Image { return image.NewRGBA(r) },
+        "Paletted": func(r image.Rectangle) draw.Image { return image.NewPaletted(r, palette.Plan9) },
+        "bilevel":  func(r image.Rectangle) draw.Image { return image.NewPaletted(r, color.Palette{color.Black, color.White}) },
+    }
+
+    encoders := []*Encoder{NewEncoder(), NewEncoder(WithTransparencyPreview(true), WithPixelStride(2))}
+
+    sub := func(img draw.Image, r image.Rectangle) image.Image {
+        return img.(interface{ SubImage(image.Rectangle) image.Image }).SubImage(r)
+    }
+
+    for name, newImage := range types {
+        moved, origin := copies(newImage)
+
+        pairs := [][2]image.Image{
+            {moved, origin},
+            {generic{moved}, origin},
+            {sub(moved, image.Rect(-2, -4, 2, 2)), sub(origin, image.Rect(1, 1, 5, 7))},
+        }
+
+        for i, pair := range pairs {
+            for j, e := range encoders {
+                got, err := e.Encode(pair[0])
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                if want, _ := e.Encode(pair[1]); got != want {
+                    t.Errorf("%s %d, encoder %d: at %v = %q, at the origin %q", name, i, j, pair[0].Bounds(), got, want)
+                }
+            }
+        }
+    }
+
+    ycbcr := ycbcrImage(negative, image.YCbCrSubsampleRatio420)
+    encoded, err := FromImage(ycbcr)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImageGeneric(ycbcr); encoded != want {
+        t.Errorf("YCbCr at %v = %q, generic path %q", negative, encoded, want)
+    }
+}
+
+func TestPrepare(t *testing.T) {
+    pattern := TestPattern(6, 6)
+    rgba := image.NewRGBA(image.Rect(2, -2, 8, 4))
+    draw.Draw(rgba, rgba.Rect, pattern, image.Point{}, draw.Src)
+
+    images := []image.Image{rgba, image.NewPaletted(rgba.Rect, palette.WebSafe), ycbcrImage(rgba.Rect, image.YCbCrSubsampleRatio420)}
+    draw.Draw(images[1].(*image.Paletted), rgba.Rect, pattern, image.Point{}, draw.Src)
+
+    for _, img := range images {
+        prepared := Prepare(img)
+        if prepared.Rect != img.Bounds() {
+            t.Errorf("Prepare() of a %T at %v is at %v", img, img.Bounds(), prepared.Rect)
+        }
+
+        encoded, err := FromImage(prepared)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want, _ := FromImage(img); encoded != want {
+            t.Errorf("FromImage(Prepare()) of a %T = %q, want %q", img, encoded, want)
+        }
+    }
+
+    if nrgba := pattern.(*image.NRGBA); Prepare(nrgba) != nrgba {
+        t.Error("Prepare() of an NRGBA image returned a copy")
+    }
+}
@@ -0,0 +1,53 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+)
+
+// Eighth blocks covering from none to all of a cell, from the bottom up or from the left
+var (
+    verticalCoverage   = []rune(" ▁▂▃▄▅▆▇█")
+    horizontalCoverage = []rune(" ▏▎▍▌▋▊▉█")
+)
+
+// FromImageCoverage converts an image to a string formatted for tview, where the alpha of every pixel
+// is shown by how much of its cell is covered, so antialiased edges come out smoother than when composited.
+// Cells are filled from the bottom up, or from the left if horizontal is true, in the colour of the pixel
+// over the default background. Like FromImageSpace(), each row of pixels takes a whole row of output.
+func FromImageCoverage(img image.Image, horizontal bool) (encoded string, err error) {
+    glyphs := verticalCoverage
+    if horizontal {
+        glyphs = horizontalCoverage
+    }
+
+    var b strings.Builder
+
+    for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+        prev := ""
+
+        for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+            c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+
+            glyph := coverage(c.A, glyphs)
+            if glyph != ' ' {
+                if hex := ColorHex(color.NRGBA{c.R, c.G, c.B, 0xff}); hex != prev {
+                    b.WriteString("[" + hex + ":-]")
+                    prev = hex
+                }
+            }
+
+            b.WriteRune(glyph)
+        }
+
+        b.WriteString("\n")
+    }
+
+    return b.String(), nil
+}
+
+// coverage picks the glyph covering the nearest eighth of a cell to the opacity.
+func coverage(alpha uint8, glyphs []rune) rune {
+    return glyphs[(int(alpha) * 8 + 0x7f) / 0xff]
+}
@@ -0,0 +1,32 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+func TestFromImageCoverage(t *testing.T) {
+    // Each alpha to the nearest eighth of a cell, 0x0f is just under half of one
+    alphas := []uint8{0, 0x0f, 0x10, 0x20, 0x60, 0x80, 0xe0, 0xff}
+    img := image.NewNRGBA(image.Rect(0, 0, len(alphas), 1))
+    for x, a := range alphas {
+        img.SetNRGBA(x, 0, color.NRGBA{0xff, 0, 0, a})
+    }
+
+    tests := map[bool]string{
+        false: "  [#ff0000:-]▁▁▃▄▇█\n",
+        true:  "  [#ff0000:-]▏▏▍▌▉█\n",
+    }
+
+    for horizontal, want := range tests {
+        encoded, err := FromImageCoverage(img, horizontal)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if encoded != want {
+            t.Errorf("horizontal %v: FromImageCoverage() = %q, want %q", horizontal, encoded, want)
+        }
+    }
+}
@@ -0,0 +1,19 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/base64"
+    "image"
+    "image/png"
+)
+
+// ToDataURI encodes an image as a PNG data URI, ready to be embedded in markdown or HTML
+// alongside its rendering in the terminal.
+func ToDataURI(img image.Image) (uri string, err error) {
+    var buf bytes.Buffer
+    if err = png.Encode(&buf, img); err != nil {
+        return
+    }
+
+    return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,36 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/base64"
+    "image/color"
+    "image/png"
+    "strings"
+    "testing"
+)
+
+func TestToDataURI(t *testing.T) {
+    uri, err := ToDataURI(solid(7, 3, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    const prefix = "data:image/png;base64,"
+    if !strings.HasPrefix(uri, prefix) {
+        t.Fatalf("ToDataURI() = %q, want it to start with %q", uri, prefix)
+    }
+
+    data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    config, err := png.DecodeConfig(bytes.NewReader(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if config.Width != 7 || config.Height != 3 {
+        t.Errorf("data URI holds a %d by %d PNG, want 7 by 3", config.Width, config.Height)
+    }
+}
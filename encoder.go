@@ -0,0 +1,1464 @@
+package pxl
+
+import (
+    "bytes"
+    "html"
+    "image"
+    "image/color"
+    "io"
+    "math"
+    "os"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+
+    "github.com/mattn/go-runewidth"
+    "github.com/pkg/errors"
+)
+
+// Mode selects the format an Encoder emits.
+type Mode int
+
+const (
+    // ModeTview emits text formatted with tview colour tags, like FromImage() does.
+    ModeTview Mode = iota
+
+    // ModeHTML emits a <pre> block of styled <span> elements.
+    ModeHTML
+
+    // ModeANSI emits true colour ANSI escape sequences, for writing straight to a terminal.
+    // Transparent bottom pixels are left in the default background of the terminal.
+    ModeANSI
+
+    // ModeTwoRowSpace emits text formatted for tview like FromImageSpace() does, drawing every pixel
+    // as a space with its colour set as the background. A row of cells takes two rows of output,
+    // the PixelFunc option isn't applied to it.
+    ModeTwoRowSpace
+
+    // ModePagerSafe emits colours with nothing but SGR sequences, reset at the end of every row,
+    // so the output can be piped through less -R. Hyperlinks & byte order marks are left out.
+    ModePagerSafe
+
+    // ModeANSI256 is like ModeANSI for terminals limited to the xterm 256 colour palette.
+    ModeANSI256
+
+    // ModeANSI16 is like ModeANSI for terminals limited to the 16 standard colours.
+    ModeANSI16
+
+    // ModeASCII emits plain text without any colour, every cell is a character
+    // which is denser the lighter its pixels are.
+    ModeASCII
+
+    // ModeMatte emits text formatted for tview where only the opaque pixels are drawn,
+    // leaving the transparent ones to the default background so the image can be laid over
+    // anything, like a sprite. Pixels count as opaque from the alpha set with WithMatteThreshold().
+    ModeMatte
+
+    // ModeGray256 is like ModeANSI256 but draws the image in greys, picked from the 24 step grey ramp of the palette
+    // by the luminance of every pixel, for a cleaner monochrome look than true colour greys & shorter sequences.
+    ModeGray256
+)
+
+// Encoder converts images to text according to the options it was created with.
+// An Encoder is safe to reuse for any number of images.
+type Encoder struct {
+    mode      Mode
+    notation  ColorNotation
+    theme     color.Color
+    separator string
+    heatmap   []color.Color
+    pixelFunc PixelFunc
+    rowFunc   func(row int, content string)
+    rowWrap   func(row int, content string) string
+    maxColors int
+    lab       bool
+    dither    Dither
+    preview   bool
+    pattern   image.Image
+    rounding  Rounding
+    gradient  *gradient
+    hyperlink string
+    duotone   *duotone
+    colScale  int
+    glyph     string
+    bom       bool
+    border    *border
+    straight  bool
+    maxCols   int
+    maxRows   int
+    maxOutput image.Point
+    box       image.Point
+    surround  surround
+    scanlines float64
+    channels  *[3]int
+    snap      uint8
+    mirror    *reflection
+    stride    int
+    sampling  Sampling
+    scanX     Direction
+    scanY     Direction
+    fill      EdgeFill
+
+    matteThreshold uint8
+    alphaCutoff    uint8
+    warnProfile    bool
+    eraseEOL       bool
+    bottomFg       bool
+    sanitize       bool
+    toneMap        ToneMap
+    lineReset      bool
+    desubpixel     bool
+    gamma          bool
+    posterize      int
+    asciiApprox    bool
+    lumaDither     bool
+    vignette       float64
+    maxTags        int
+    temporalDither bool
+    ditherPhase    image.Point
+    regions        func(col, row int) string
+    autoContrast   bool
+    contrastClip   float64
+    cvd            CVD
+    tagFormatter   TagFormatter
+    temperature    *[3]float64
+    flatSpaces     bool
+}
+
+// reflection is a mirror image of the bottom of the image, fading away below it.
+type reflection struct {
+    rows int
+    fade float64
+}
+
+// duotone maps dark pixels to one colour & light ones to another.
+type duotone struct {
+    dark, light color.Color
+    threshold   uint8
+}
+
+// gradient is a background which fades linearly across the image.
+type gradient struct {
+    from, to   color.Color
+    horizontal bool
+}
+
+// Sampling is the way nearest neighbour scaling picks the pixel which stands for each area of the source.
+type Sampling int
+
+const (
+    // SampleCenter picks the pixel at the center of the area, it's the default
+    // & keeps thin lines which don't run along its edge.
+    SampleCenter Sampling = iota
+
+    // SampleTruncate picks the pixel at the top-left corner of the area, like truncating scaled coordinates does.
+    SampleTruncate
+)
+
+// Direction is the order rows or columns of cells are written out in.
+type Direction int
+
+const (
+    // Forward writes columns from left to right & rows from top to bottom, it's the default.
+    Forward Direction = iota
+
+    // Backward writes columns from right to left & rows from bottom to top.
+    Backward
+)
+
+// Rounding is the way 16 bit colour channels are reduced to the 8 bits that are written out.
+type Rounding int
+
+const (
+    // RoundTruncate drops the low 8 bits, it's the default & matches ColorHex().
+    RoundTruncate Rounding = iota
+
+    // RoundNearest rounds to the nearest 8 bit value, which avoids darkening 16 bit sources slightly.
+    RoundNearest
+)
+
+// WindowsCompat configures the output for Windows terminals & ConEmu, whose fonts or code pages
+// may not render the half block glyph even though they handle true colour escapes fine.
+type WindowsCompat struct {
+    // Glyph is drawn in every cell instead of '▀', Fg colours its top & Bg the rest of the cell.
+    // The zero value, or an invalid rune, keeps '▀'.
+    Glyph rune
+
+    // BOM writes a UTF-8 byte order mark before the output, which some Windows tools
+    // need to tell UTF-8 from the ANSI code page. It's left out unless set.
+    BOM bool
+}
+
+// EdgeFill is how an image is made to fit the cells of a mode, when its size isn't a multiple of them,
+// like an uneven height with half blocks.
+type EdgeFill int
+
+const (
+    // EdgeError refuses to process images which don't fit, it's the default.
+    EdgeError EdgeFill = iota
+
+    // EdgePad adds transparent pixels to the right & bottom edges.
+    EdgePad
+
+    // EdgeCrop cuts the pixels that don't fit off the right & bottom edges.
+    EdgeCrop
+
+    // EdgeDuplicate repeats the last column & row of pixels past the right & bottom edges.
+    EdgeDuplicate
+
+    // EdgeReflect mirrors the pixels along the right & bottom edges past them, so the row added
+    // to an uneven height is a copy of the second to last one, which blends in better with photos than a flat fill.
+    // Images a single pixel wide or tall repeat it like EdgeDuplicate does.
+    EdgeReflect
+)
+
+// Option configures an Encoder.
+type Option func(*Encoder)
+
+// PixelFunc can substitute its own string for the cell at column x & row y of the output,
+// which holds the fg & bg pixels. Returning false leaves the cell to be encoded as usual.
+type PixelFunc func(x, y int, fg, bg color.Color) (string, bool)
+
+// NewEncoder creates an Encoder configured with opts,
+// without any options it produces the same output as FromImage().
+func NewEncoder(opts ...Option) *Encoder {
+    e := &Encoder{
+        separator:      "\n",
+        matteThreshold: 0x80,
+        surround:       surround{glyph: ' '},
+    }
+
+    for _, opt := range opts {
+        opt(e)
+    }
+
+    return e
+}
+
+// WithMode selects the output format, the default is ModeTview.
+func WithMode(mode Mode) Option {
+    return func(e *Encoder) {
+        e.mode = mode
+    }
+}
+
+// WithColorNotation selects how colours are written in modes which use CSS colours, like ModeHTML.
+// tview tags are always written in hex, as it's the only notation tview understands.
+func WithColorNotation(notation ColorNotation) Option {
+    return func(e *Encoder) {
+        e.notation = notation
+    }
+}
+
+// WithLineSeparator sets the string written after every row, the default is "\n".
+// It can be "\r\n" for sinks that expect it, or empty to handle the rows some other way.
+func WithLineSeparator(separator string) Option {
+    return func(e *Encoder) {
+        e.separator = separator
+    }
+}
+
+// WithRowCallback passes every encoded row to fn in order, along with its index, instead of writing it
+// to the output followed by the separator, so the caller can lay the rows out itself.
+// The header, footer & border lines are still part of the output.
+func WithRowCallback(fn func(row int, content string)) Option {
+    return func(e *Encoder) {
+        e.rowFunc = fn
+    }
+}
+
+// WithRowWrapper replaces every encoded row with what fn returns for it, before the separator is added,
+// to put sequences or tags of its own around some of the rows, like a tview region to make them clickable.
+func WithRowWrapper(fn func(row int, content string) string) Option {
+    return func(e *Encoder) {
+        e.rowWrap = fn
+    }
+}
+
+// WithThemeBackground composites every pixel over the background colour of a tview theme,
+// so semi-transparent images come out fully opaque and blend into the surrounding UI.
+// The alpha of the theme colour itself is ignored.
+func WithThemeBackground(theme color.Color) Option {
+    return func(e *Encoder) {
+        e.theme = theme
+    }
+}
+
+// WithHeatmap replaces the colour of every pixel with the colour sampled from the gradient by its luminance,
+// so black pixels take the first colour, white pixels the last & the rest somewhere in between.
+// The opacity of each pixel is kept.
+func WithHeatmap(gradient []color.Color) Option {
+    return func(e *Encoder) {
+        e.heatmap = gradient
+    }
+}
+
+// WithDuotone forces the image into two colours for a retro look, pixels with a luminance
+// below threshold become dark & the rest become light. The opacity of each pixel is kept.
+func WithDuotone(dark, light color.Color, threshold uint8) Option {
+    return func(e *Encoder) {
+        e.duotone = &duotone{dark, light, threshold}
+    }
+}
+
+// WithPixelFunc lets fn intercept every cell before it's encoded, to draw overlays like grid lines.
+// A substituted string is written as is, after it the colours of the next cell are set in full,
+// since the string may have changed them.
+func WithPixelFunc(fn PixelFunc) Option {
+    return func(e *Encoder) {
+        e.pixelFunc = fn
+    }
+}
+
+// TagFormatter writes the tag which sets the colours of the cells after it, fgChanged & bgChanged tell which of them
+// differ from the cell before, both do at the start of every row.
+type TagFormatter func(fg, bg color.Color, fgChanged, bgChanged bool) string
+
+// WithTagFormatter writes the tags of ModeTview with fn rather than as tview colour tags, for widgets
+// with a markup of their own. It's only called where the colours change, the glyphs are written after it as usual.
+func WithTagFormatter(fn TagFormatter) Option {
+    return func(e *Encoder) {
+        e.tagFormatter = fn
+    }
+}
+
+// WithRegions wraps the cells of ModeTview & ModeMatte output in tview region tags with the ID fn returns
+// for their column & row, so clicks on the image can be mapped back to cells through the highlights of a tview.TextView.
+// Cells next to each other with the same ID share a tag, an empty ID leaves a cell outside of any region.
+// tview only takes IDs of letters, digits, spaces & the characters _,;:-. so any other character is left out of them.
+func WithRegions(fn func(col, row int) string) Option {
+    return func(e *Encoder) {
+        e.regions = fn
+    }
+}
+
+// WithMaxColors quantizes the image down to at most n colours before encoding it,
+// which keeps the output small, since fewer colours means fewer tags.
+func WithMaxColors(n int) Option {
+    return func(e *Encoder) {
+        e.maxColors = n
+    }
+}
+
+// WithPerceptualQuantization makes WithMaxColors group colours by how different they look rather than
+// by their channel values, so dark areas don't get more palette entries than they need
+// while light ones get too few. Dark photos in particular come out with fewer tags.
+func WithPerceptualQuantization(perceptual bool) Option {
+    return func(e *Encoder) {
+        e.lab = perceptual
+    }
+}
+
+// WithDither dithers the colours WithMaxColors reduces the image to, the default is DitherNone.
+func WithDither(dither Dither) Option {
+    return func(e *Encoder) {
+        e.dither = dither
+    }
+}
+
+// WithLumaDither makes DitherFloydSteinberg diffuse only the error in brightness, measured like the Y of YCbCr,
+// keeping the hue & saturation of every pixel as they are, so photos don't get speckled with coloured noise.
+// The ordered dithers already shift every channel alike, which only changes brightness.
+func WithLumaDither(luma bool) Option {
+    return func(e *Encoder) {
+        e.lumaDither = luma
+    }
+}
+
+// WithTemporalDither shifts the matrix of the ordered dithers from one frame of an animation to the next,
+// see Encoder.EncodeGIF(), so the pattern averages out over time rather than sitting still as visible noise.
+// Frames which are the same as the ones before them are dithered again too.
+func WithTemporalDither(temporal bool) Option {
+    return func(e *Encoder) {
+        e.temporalDither = temporal
+    }
+}
+
+// WithTransparencyPreview shows transparent areas over a grey checkerboard, like image editors do,
+// rather than letting them turn black. Each cell is one square of the checkerboard.
+func WithTransparencyPreview(preview bool) Option {
+    return func(e *Encoder) {
+        e.preview = preview
+    }
+}
+
+// WithTransparencyPattern shows transparent areas over a pattern image, tiled across the whole image
+// pixel for pixel, which generalises the checkerboard of WithTransparencyPreview(). The pattern is treated
+// as opaque, an empty one is ignored.
+func WithTransparencyPattern(pattern image.Image) Option {
+    return func(e *Encoder) {
+        if pattern != nil && pattern.Bounds().Empty() {
+            pattern = nil
+        }
+
+        e.pattern = pattern
+    }
+}
+
+// WithGradientBackground composites transparent areas over a gradient from one colour to another,
+// running from the left edge to the right one if horizontal is true, otherwise from top to bottom.
+func WithGradientBackground(from, to color.Color, horizontal bool) Option {
+    return func(e *Encoder) {
+        e.gradient = &gradient{from, to, horizontal}
+    }
+}
+
+// WithHyperlink makes the whole image a link to url in terminals which support OSC 8 hyperlinks,
+// by wrapping the output in the OSC 8 escape sequences. In ModeHTML the output is wrapped in an <a> element instead.
+// Control characters & invalid UTF-8 are left out of the url, as they could end the escape sequence early
+// & write whatever follows them to the terminal.
+func WithHyperlink(url string) Option {
+    return func(e *Encoder) {
+        e.hyperlink = strings.Map(func(r rune) rune {
+            if unicode.IsControl(r) {
+                return -1
+            }
+
+            return r
+        }, strings.ToValidUTF8(url, ""))
+    }
+}
+
+// WithColumnScale repeats every cell n times horizontally, to make up for terminal cells
+// being taller than they're wide without resizing the image. Repeated cells share a single tag,
+// so the output grows very little.
+func WithColumnScale(n int) Option {
+    return func(e *Encoder) {
+        e.colScale = n
+    }
+}
+
+// WithDoubleWide draws every cell twice side by side, so each pair of pixels takes a roughly square block
+// of the terminal. It's the same as WithColumnScale(2), or WithColumnScale(1) when double is false.
+func WithDoubleWide(double bool) Option {
+    if double {
+        return WithColumnScale(2)
+    }
+
+    return WithColumnScale(1)
+}
+
+// WithCellGlyph draws every cell with glyph instead of ▀, keeping its colours, for clients which show colours
+// but not the half block, like some chat apps, or which style a character of their choosing. The glyph still takes
+// the top pixel as its colour & the bottom one as its background. It applies to ModeTview, ModeHTML & the ANSI modes,
+// an invalid rune or 0 is ▀. A wide glyph, like most emoji, takes two columns of the terminal per cell,
+// which EncodeDiff() accounts for when it moves the cursor.
+func WithCellGlyph(glyph rune) Option {
+    return func(e *Encoder) {
+        e.glyph = ""
+        if glyph != 0 && glyph != utf8.RuneError && utf8.ValidRune(glyph) {
+            e.glyph = string(glyph)
+        }
+    }
+}
+
+// WithAsciiBlockApprox draws the cells of the ANSI modes with ASCII rather than ▀, for terminals & fonts without it,
+// while still colouring them: # where the top pixel of a cell is brighter than the bottom one, & . where it isn't.
+// Each glyph is in the colour of the top pixel over the bottom one, so some of the colour of both halves shows.
+func WithAsciiBlockApprox(approx bool) Option {
+    return func(e *Encoder) {
+        e.asciiApprox = approx
+    }
+}
+
+// WithFlatSpaces writes the cells of the ANSI modes whose top & bottom pixels are the same colour as a space
+// with only that colour set as the background, which is shorter than the half block with both colours set
+// & looks the same, so flat areas take less output.
+func WithFlatSpaces(flat bool) Option {
+    return func(e *Encoder) {
+        e.flatSpaces = flat
+    }
+}
+
+// WithWindowsCompat emits true colour ANSI escape sequences, like ModeANSI, drawn with the glyph
+// & byte order mark choices of compat. The output is always valid UTF-8.
+func WithWindowsCompat(compat WindowsCompat) Option {
+    return func(e *Encoder) {
+        e.mode = ModeANSI
+        e.glyph = ""
+        if compat.Glyph != 0 && compat.Glyph != utf8.RuneError && utf8.ValidRune(compat.Glyph) {
+            e.glyph = string(compat.Glyph)
+        }
+
+        e.bom = compat.BOM
+    }
+}
+
+// WithBorder surrounds the image with a frame of box drawing characters in the given colour,
+// adding a cell on every side of it. Unknown styles are drawn as BorderSingle.
+// EncodeDiff() & FrameEncoder don't draw the border.
+func WithBorder(style BorderStyle, c color.Color) Option {
+    return func(e *Encoder) {
+        glyphs, ok := borderGlyphs[style]
+        if !ok {
+            glyphs = borderGlyphs[BorderSingle]
+        }
+
+        e.border = &border{glyphs, c}
+    }
+}
+
+// WithInputAlpha tells the encoder whether the colours of the image are premultiplied by their alpha,
+// as the image/color package expects of every RGBA() method, which is the default.
+// It's only needed for custom image types which return straight alpha instead.
+func WithInputAlpha(premultiplied bool) Option {
+    return func(e *Encoder) {
+        e.straight = !premultiplied
+    }
+}
+
+// WithMaxCols cuts every row off after n cells, showing the image at its own resolution
+// as far as it fits rather than resizing it. Cut off rows end by resetting the colours,
+// so they don't carry over into whatever follows. Zero or less means no limit.
+func WithMaxCols(n int) Option {
+    return func(e *Encoder) {
+        e.maxCols = n
+    }
+}
+
+// WithMaxRows scales the image down vertically when it would take more than n rows of cells,
+// averaging its rows just enough to fit while leaving its width as it is. Zero or less means no limit.
+func WithMaxRows(n int) Option {
+    return func(e *Encoder) {
+        e.maxRows = n
+    }
+}
+
+// ErrTooLarge is returned for images whose output would take more cells than WithMaxOutputCells() allows.
+var ErrTooLarge = errors.New("pixelview: Output exceeds the size limit in cells")
+
+// WithMaxOutputCells makes encoding fail with ErrTooLarge, before any of it is done, when the output would be
+// more than cols cells wide or rows tall, borders included, so the caller can offer to scale the image down
+// rather than filling the terminal. The limits apply after options like WithMaxRows() have resized the image.
+// Zero or less means no limit in that direction.
+func WithMaxOutputCells(cols, rows int) Option {
+    return func(e *Encoder) {
+        e.maxOutput = image.Point{cols, rows}
+    }
+}
+
+// WithScanlines darkens every other row of cells for the look of a CRT screen,
+// multiplying the colours of odd rows by 1 - intensity. The intensity is clamped between 0 & 1.
+func WithScanlines(intensity float64) Option {
+    return func(e *Encoder) {
+        e.scanlines = math.Max(0, math.Min(intensity, 1))
+    }
+}
+
+// WithMaxTagsPerRow limits how many times the colours change along every row of cells to n, as tview parses
+// every tag again each time it draws. Rows with more changes have neighbouring runs of cells merged, the shorter
+// run taking the colours of the longer one, picking those which are most alike & fewest cells, until they're within the limit.
+func WithMaxTagsPerRow(n int) Option {
+    return func(e *Encoder) {
+        e.maxTags = n
+    }
+}
+
+// WithVignette darkens the image towards its corners for a photographic look, multiplying the colours of every pixel
+// by 1 - strength times the square of how far it is from the center, relative to the corners.
+// The strength is clamped between 0 & 1, at 1 the corner pixels come close to black.
+func WithVignette(strength float64) Option {
+    return func(e *Encoder) {
+        e.vignette = math.Max(0, math.Min(strength, 1))
+    }
+}
+
+// WithColorTemperature shifts the white balance of the image to that of light of the given colour temperature,
+// warmer below the neutral 6500 K & cooler above it, by scaling the red, green & blue channels of every pixel
+// by the colour of such light relative to that of 6500 K. A temperature of 0, the default, leaves colours as they are.
+func WithColorTemperature(kelvin float64) Option {
+    return func(e *Encoder) {
+        e.temperature = nil
+        if kelvin > 0 {
+            scale := temperatureScale(kelvin)
+            e.temperature = &scale
+        }
+    }
+}
+
+// WithChannelOrder reads the colour channels of every pixel in the given order, like "bgr" for
+// BGRA data wrapped as RGBA, so the first letter names the channel written as red & so on.
+// Orders which aren't made of each of r, g & b once are ignored.
+func WithChannelOrder(order string) Option {
+    return func(e *Encoder) {
+        var channels [3]int
+        seen := map[rune]bool{}
+
+        if len(order) != 3 {
+            return
+        }
+
+        for i, ch := range strings.ToLower(order) {
+            index := strings.IndexRune("rgb", ch)
+            if index < 0 || seen[ch] {
+                return
+            }
+
+            channels[i], seen[ch] = index, true
+        }
+
+        e.channels = &channels
+    }
+}
+
+// WithColorSnap snaps every colour channel to the nearest multiple of step, so pixels which differ
+// by a little, like the output of different decoders, come out the same. It's meant for keeping
+// snapshot tests stable, a step of 0 or 1 leaves the colours as they are.
+func WithColorSnap(step uint8) Option {
+    return func(e *Encoder) {
+        e.snap = step
+    }
+}
+
+// WithPosterize reduces every colour channel to levels evenly spaced values for the flat bands of a poster,
+// like 0 & 255 with 2 levels. Unlike WithMaxColors() it's applied to each channel on its own, the same way
+// for every image. Less than 2 levels leaves the colours as they are.
+func WithPosterize(levels int) Option {
+    return func(e *Encoder) {
+        e.posterize = levels
+    }
+}
+
+// WithReflection adds height rows of cells below the image, holding the bottom of it flipped upside down
+// for a glossy look. The reflection gets more transparent the further it is from the image, until
+// the fade, from 0 to 1, of its opacity is gone, so it blends into the background of WithThemeBackground().
+// It's never taller than the image itself.
+func WithReflection(height int, fade float64) Option {
+    return func(e *Encoder) {
+        e.mirror = nil
+        if height > 0 {
+            e.mirror = &reflection{height * 2, math.Max(0, math.Min(fade, 1))}
+        }
+    }
+}
+
+// WithPixelStride only encodes every nth column of pixels & every nth row of cells, skipping the rest,
+// for a quick preview of a large image which is cheaper than resizing it.
+func WithPixelStride(n int) Option {
+    return func(e *Encoder) {
+        e.stride = n
+    }
+}
+
+// WithSampleRounding selects which pixel of every n WithPixelStride() keeps, the default is SampleCenter.
+func WithSampleRounding(sampling Sampling) Option {
+    return func(e *Encoder) {
+        e.sampling = sampling
+    }
+}
+
+// WithScanOrder sets the order the columns & rows of cells are written out in. Where cells aren't positioned,
+// like in tview output, writing them backward mirrors the image, which is as if it had been flipped.
+// EncodeDiff() positions every cell, so it draws the same image, only in that order.
+func WithScanOrder(horizontal, vertical Direction) Option {
+    return func(e *Encoder) {
+        e.scanX, e.scanY = horizontal, vertical
+    }
+}
+
+// WithEdgeFill selects how images whose size doesn't fit the cells of the mode are handled,
+// the default is EdgeError.
+func WithEdgeFill(fill EdgeFill) Option {
+    return func(e *Encoder) {
+        e.fill = fill
+    }
+}
+
+// WithMatteThreshold sets the alpha from which pixels count as opaque in ModeMatte, the default is 128.
+func WithMatteThreshold(threshold uint8) Option {
+    return func(e *Encoder) {
+        e.matteThreshold = threshold
+    }
+}
+
+// WithAlphaCutoff makes pixels less opaque than threshold fully transparent, cleaning up the faint fringes
+// antialiasing leaves around sprites. They're then written like any other transparent pixel, as the default colour
+// or over whatever background the other options set. More opaque pixels are left as they are.
+func WithAlphaCutoff(threshold uint8) Option {
+    return func(e *Encoder) {
+        e.alphaCutoff = threshold
+    }
+}
+
+// WithIgnoreColorProfile sets whether ICC colour profiles embedded in JPEGs & PNGs are ignored, which they always are,
+// the decoded RGB values are written as they are so the output is the same on every platform. The default is true.
+// With false, EncodeReader() & EncodeFile() return ErrColorProfile along with the output of images that have one,
+// as a warning that the colours may not match what a browser shows.
+func WithIgnoreColorProfile(ignore bool) Option {
+    return func(e *Encoder) {
+        e.warnProfile = !ignore
+    }
+}
+
+// WithPixelRoles sets which pixel of a cell its foreground colour is sampled from, the default is true for the top one.
+// With false the bottom pixel becomes the foreground & the top one the background, while the glyph stays ▀,
+// so every pair of rows is drawn upside down. Colour options still see each pixel where it is in the image.
+func WithPixelRoles(topIsForeground bool) Option {
+    return func(e *Encoder) {
+        e.bottomFg = !topIsForeground
+    }
+}
+
+// WithEraseEOL ends every row of ANSI output with an erase to the end of the line, so redrawing a frame over a wider one
+// clears what's left of it to the right. It's left out in ModePagerSafe, which only emits SGR sequences.
+func WithEraseEOL(erase bool) Option {
+    return func(e *Encoder) {
+        e.eraseEOL = erase
+    }
+}
+
+// WithLineReset ends every row of tview output with a reset to the default colours, so encoded images can be joined
+// on the same lines without the colours of one bleeding into the text after it. ANSI rows always end with a reset.
+func WithLineReset(reset bool) Option {
+    return func(e *Encoder) {
+        e.lineReset = reset
+    }
+}
+
+// WithDesubpixel desaturates the coloured fringes LCD subpixel antialiasing leaves along the edges of text,
+// for screenshots of text, whose fringes show as stray colours at the size of cells. Only strongly coloured pixels
+// on sharp horizontal edges between much less coloured ones are changed, the rest of the image is left as it is.
+func WithDesubpixel(desubpixel bool) Option {
+    return func(e *Encoder) {
+        e.desubpixel = desubpixel
+    }
+}
+
+// WithAutoContrast stretches the range of brightness of the image to run from black to white, for washed-out photos
+// which look muddy in the few colours of a terminal. Every channel of a pixel is stretched alike, from the luminance
+// of the darkest pixel to that of the lightest, so hues stay the same. See WithContrastClip() to ignore outliers.
+func WithAutoContrast(stretch bool) Option {
+    return func(e *Encoder) {
+        e.autoContrast = stretch
+    }
+}
+
+// WithContrastClip makes WithAutoContrast() stretch from the luminance which fraction of the pixels are darker than
+// to the one as many are lighter than, rather than the darkest & lightest pixels, so a few specks of black or white
+// don't keep the rest of the image from being stretched. Clipped pixels turn black or white.
+// The fraction is clamped between 0, the default, & 0.5.
+func WithContrastClip(fraction float64) Option {
+    return func(e *Encoder) {
+        e.contrastClip = math.Max(0, math.Min(fraction, 0.5))
+    }
+}
+
+// WithGamma sets whether transparent pixels are composited over the backgrounds of WithThemeBackground(),
+// WithGradientBackground(), WithTransparencyPreview() & WithTransparencyPattern() in linear light, like image editors do,
+// rather than blending the sRGB values as they are, the default. It's most noticeable on antialiased edges:
+// white at half opacity over black comes out #bcbcbc rather than #808080.
+func WithGamma(correct bool) Option {
+    return func(e *Encoder) {
+        e.gamma = correct
+    }
+}
+
+// WithSanitizedOutput guarantees the output is valid UTF-8 without any control characters but the escape & bell
+// of escape sequences, tabs & line breaks, for sinks which reject anything else. Whatever else gets into the output,
+// like invalid bytes from a line separator, a row wrapper or a custom colour type, is dropped.
+// Colours are always written as valid text, so this is a last line of defence rather than a fix.
+func WithSanitizedOutput(sanitize bool) Option {
+    return func(e *Encoder) {
+        e.sanitize = sanitize
+    }
+}
+
+// WithRounding selects how colour channels are reduced to 8 bits, the default is RoundTruncate.
+func WithRounding(rounding Rounding) Option {
+    return func(e *Encoder) {
+        e.rounding = rounding
+    }
+}
+
+// Encode converts an image to text, see FromImage() for more details.
+func (e *Encoder) Encode(img image.Image) (encoded string, err error) {
+    pixels, err := e.Decode(img)
+    if err != nil {
+        return
+    }
+
+    return e.EncodePixels(pixels), nil
+}
+
+// EncodeFile converts an image file to text, see FromFile() for more details.
+func (e *Encoder) EncodeFile(filename string) (encoded string, err error) {
+    f, err := os.Open(filename)
+
+    if err != nil {
+        return
+    }
+
+    defer f.Close()
+    return e.EncodeReader(f)
+}
+
+// EncodeReader converts an image read from an io.Reader to text, see FromReader() for more details.
+func (e *Encoder) EncodeReader(reader io.Reader) (encoded string, err error) {
+    if !e.warnProfile {
+        img, _, err := image.Decode(reader)
+        if err != nil {
+            return "", err
+        }
+
+        return e.Encode(img)
+    }
+
+    data, err := io.ReadAll(reader)
+    if err != nil {
+        return
+    }
+
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return
+    }
+
+    if encoded, err = e.Encode(img); err == nil && hasColorProfile(data) {
+        err = ErrColorProfile
+    }
+
+    return
+}
+
+// Decode pairs the pixels of an image into cells, like DecodeToPixels() does,
+// with the colour options of the encoder applied.
+func (e *Encoder) Decode(img image.Image) (pixels Pixels, err error) {
+    if img, err = e.prepare(img); err != nil {
+        return
+    }
+
+    decode := e.rowDecoder(img)
+    for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y += 2 {
+        pixels = append(pixels, decode(y))
+    }
+
+    return
+}
+
+// EncodeTo converts an image like Encode() does, but writes each row to w
+// as soon as it's encoded instead of building the whole string first.
+// It returns the number of bytes written.
+func (e *Encoder) EncodeTo(w io.Writer, img image.Image) (n int, err error) {
+    return e.encodeTo(w, img, -1)
+}
+
+// encodeTo writes whole rows to w until the next one would take the output past max bytes,
+// in which case it returns ErrOutputLimit. A negative max means there's no limit.
+func (e *Encoder) encodeTo(w io.Writer, img image.Image, max int) (n int, err error) {
+    next, err := e.chunks(img)
+    if err != nil {
+        return
+    }
+
+    for chunk, ok := next(); ok; chunk, ok = next() {
+        if max >= 0 && n + len(chunk) > max {
+            return n, ErrOutputLimit
+        }
+
+        m, err := io.WriteString(w, chunk)
+        n += m
+        if err != nil {
+            return n, err
+        }
+    }
+
+    return
+}
+
+// chunks returns a function which encodes an image a piece at a time, first the header,
+// then every row with its separator & last the footer. It returns false once there's nothing left.
+func (e *Encoder) chunks(img image.Image) (next func() (string, bool), err error) {
+    if img, err = e.prepare(img); err != nil {
+        return
+    }
+
+    width, lines := e.imageCells(img.Bounds().Size())
+    top, bottom := padding(lines, e.box.Y)
+
+    decode := e.rowDecoder(img)
+    y := img.Bounds().Min.Y - 2
+
+    next = func() (chunk string, ok bool) {
+        switch {
+            case y < img.Bounds().Min.Y:
+                chunk = e.header() + e.borderLine(true, width) + e.fillRows(top, width)
+
+            case y < img.Bounds().Max.Y:
+                row := scanIndex((y - img.Bounds().Min.Y) / 2, img.Bounds().Dy() / 2, e.scanY)
+                chunk = e.emitRow(row, decode(img.Bounds().Min.Y + row * 2))
+
+            case y == img.Bounds().Max.Y:
+                chunk = e.fillRows(bottom, width) + e.borderLine(false, width) + e.footer()
+
+            default:
+                return "", false
+        }
+
+        y += 2
+        return e.sanitized(chunk), true
+    }
+
+    return
+}
+
+// prepare applies the options which transform the image as a whole & fits it to the cells.
+func (e *Encoder) prepare(img image.Image) (image.Image, error) {
+    if e.straight {
+        img = straightImage{img}
+    }
+
+    if e.toneMap != ToneLinear {
+        img = toneMapImage(img, e.toneMap)
+    }
+
+    if e.desubpixel {
+        img = desubpixelImage{img}
+    }
+
+    if e.autoContrast {
+        img = stretchContrast(img, e.contrastClip)
+    }
+
+    if e.stride > 1 {
+        offset := e.stride / 2
+        if e.sampling == SampleTruncate {
+            offset = 0
+        }
+
+        img = strided{img, e.stride, offset}
+    }
+
+    if e.mirror != nil {
+        img = reflect(img, e.mirror.rows, e.mirror.fade)
+    }
+
+    if e.maxRows > 0 && img.Bounds().Dy() > e.maxRows * 2 {
+        img = resize(img, img.Bounds().Dx(), e.maxRows * 2)
+    }
+
+    if e.maxColors > 0 {
+        space := rgbSpace
+        if e.lab {
+            space = labSpace
+        }
+
+        dither := e.dither
+        if e.lumaDither && dither == DitherFloydSteinberg {
+            dither = ditherLuma
+        }
+
+        img = quantize(img, e.maxColors, space, dither, e.ditherPhase)
+    }
+
+    img, err := fitCells(img, 1, 2, e.fill)
+    if err != nil {
+        return nil, err
+    }
+
+    cols, rows := e.outputCells(img.Bounds().Size())
+    if (e.maxOutput.X > 0 && cols > e.maxOutput.X) || (e.maxOutput.Y > 0 && rows > e.maxOutput.Y) {
+        return nil, ErrTooLarge
+    }
+
+    return img, nil
+}
+
+// outputCells returns how many cells wide & tall the output for an image of the given size is.
+func (e *Encoder) outputCells(size image.Point) (cols, rows int) {
+    cols, rows = e.imageCells(size)
+    if cols < e.box.X {
+        cols = e.box.X
+    }
+
+    if rows < e.box.Y {
+        rows = e.box.Y
+    }
+
+    if e.border != nil {
+        cols, rows = cols + 2, rows + 2
+    }
+
+    return
+}
+
+// rowDecoder is like rowDecoder() but applies the colour options to every cell it decodes.
+// It also repeats the cells for the WithColumnScale option.
+func (e *Encoder) rowDecoder(img image.Image) func(y int) []Cell {
+    decode := rowDecoder(img)
+    if !e.filters() && e.colScale < 2 && !e.bottomFg && e.maxTags < 1 {
+        return decode
+    }
+
+    bounds := img.Bounds()
+    return func(y int) []Cell {
+        cells := decode(y)
+
+        if e.filters() {
+            for x := range cells {
+                cells[x].Fg = e.filter(x, y - bounds.Min.Y, bounds.Size(), cells[x].Fg)
+                cells[x].Bg = e.filter(x, y + 1 - bounds.Min.Y, bounds.Size(), cells[x].Bg)
+            }
+        }
+
+        if e.bottomFg {
+            for x := range cells {
+                cells[x].Fg, cells[x].Bg = cells[x].Bg, cells[x].Fg
+            }
+        }
+
+        if e.maxTags > 0 {
+            limitChanges(cells, e.maxTags)
+        }
+
+        if e.colScale > 1 {
+            scaled := make([]Cell, 0, len(cells) * e.colScale)
+            for _, cell := range cells {
+                for i := 0; i < e.colScale; i++ {
+                    scaled = append(scaled, cell)
+                }
+            }
+
+            cells = scaled
+        }
+
+        return cells
+    }
+}
+
+// limitChanges merges neighbouring runs of identical cells until there are at most n runs,
+// always the two whose merge changes the colours the least.
+func limitChanges(cells []Cell, n int) {
+    var starts []int
+    for x := range cells {
+        if x == 0 || !SameCell(cells[x].Fg, cells[x - 1].Fg) || !SameCell(cells[x].Bg, cells[x - 1].Bg) {
+            starts = append(starts, x)
+        }
+    }
+
+    end := func(run int) int {
+        if run + 1 < len(starts) {
+            return starts[run + 1]
+        }
+
+        return len(cells)
+    }
+
+    for len(starts) > n {
+        // Merging costs how different the colours are, for every cell which changes colour
+        merge, best := 0, -1
+        for i := 0; i + 1 < len(starts); i++ {
+            a, b := cells[starts[i]], cells[starts[i + 1]]
+            shorter := end(i) - starts[i]
+            if length := end(i + 1) - starts[i + 1]; length < shorter {
+                shorter = length
+            }
+
+            if d := (sqDistance(rgba8(a.Fg), b.Fg) + sqDistance(rgba8(a.Bg), b.Bg)) * shorter; best < 0 || d < best {
+                merge, best = i, d
+            }
+        }
+
+        // The shorter of the two runs takes the colours of the longer one
+        from, to := starts[merge], end(merge + 1)
+        keep := cells[from]
+        if end(merge) - from < to - starts[merge + 1] {
+            keep = cells[starts[merge + 1]]
+        }
+
+        for x := from; x < to; x++ {
+            cells[x] = keep
+        }
+
+        starts = append(starts[:merge + 1], starts[merge + 2:]...)
+    }
+}
+
+// composite blends a colour over a background with Composite(), or in linear light with WithGamma(true).
+func (e *Encoder) composite(c, bg color.Color) color.Color {
+    if e.gamma {
+        return compositeLinear(c, bg)
+    }
+
+    return Composite(c, bg)
+}
+
+// filters reports whether any colour option is set.
+func (e *Encoder) filters() bool {
+    return e.alphaCutoff > 0 ||
+        e.theme != nil ||
+        len(e.heatmap) > 0 ||
+        e.preview ||
+        e.pattern != nil ||
+        e.rounding == RoundNearest ||
+        e.gradient != nil ||
+        e.duotone != nil ||
+        e.scanlines > 0 ||
+        e.vignette > 0 ||
+        e.channels != nil ||
+        e.cvd != CVDNone ||
+        e.temperature != nil ||
+        e.snap > 1 ||
+        e.posterize > 1
+}
+
+// filter applies the colour options to the pixel at (x, y), counted from the top-left corner
+// of an image of the given size.
+func (e *Encoder) filter(x, y int, size image.Point, c color.Color) color.Color {
+    if _, _, _, a := c.RGBA(); a >> 8 < uint32(e.alphaCutoff) {
+        c = color.Transparent
+    }
+
+    if e.channels != nil {
+        c = swizzle(c, *e.channels)
+    }
+
+    if e.cvd != CVDNone {
+        c = simulateCVD(c, e.cvd)
+    }
+
+    if e.temperature != nil {
+        c = scaleChannels(c, *e.temperature)
+    }
+
+    if len(e.heatmap) > 0 {
+        c = withAlphaOf(sampleGradient(e.heatmap, luminance(c)), c)
+    }
+
+    if e.duotone != nil {
+        tone := e.duotone.light
+        if luminance(c) * 0xff < float64(e.duotone.threshold) {
+            tone = e.duotone.dark
+        }
+
+        c = withAlphaOf(tone, c)
+    }
+
+    if e.gradient != nil {
+        pos, length := y, size.Y
+        if e.gradient.horizontal {
+            pos, length = x, size.X
+        }
+
+        var t float64
+        if length > 1 {
+            t = float64(pos) / float64(length - 1)
+        }
+
+        c = e.composite(c, lerpColor(e.gradient.from, e.gradient.to, t))
+    }
+
+    if e.preview {
+        c = e.composite(c, checkerboard[(x + y / 2) % 2])
+    }
+
+    if e.pattern != nil {
+        bounds := e.pattern.Bounds()
+        c = e.composite(c, e.pattern.At(bounds.Min.X + x % bounds.Dx(), bounds.Min.Y + y % bounds.Dy()))
+    }
+
+    if e.theme != nil {
+        c = e.composite(c, e.theme)
+    }
+
+    if e.scanlines > 0 && (y / 2) % 2 == 1 {
+        c = darken(c, 1 - e.scanlines)
+    }
+
+    if e.vignette > 0 {
+        c = darken(c, 1 - e.vignette * vignetteDistance(x, y, size))
+    }
+
+    if e.rounding == RoundNearest {
+        c = roundColor(c)
+    }
+
+    if e.snap > 1 {
+        c = snapColor(c, e.snap)
+    }
+
+    if e.posterize > 1 {
+        c = posterize(c, e.posterize)
+    }
+
+    return c
+}
+
+// vignetteDistance returns the square of how far the center of the pixel at (x, y) is from the center
+// of an image of the given size, relative to the corners.
+func vignetteDistance(x, y int, size image.Point) float64 {
+    cx, cy := float64(size.X) / 2, float64(size.Y) / 2
+    dx, dy := float64(x) + 0.5 - cx, float64(y) + 0.5 - cy
+    return (dx * dx + dy * dy) / (cx * cx + cy * cy)
+}
+
+// EncodePixels converts already decoded cells to text.
+func (e *Encoder) EncodePixels(pixels Pixels) string {
+    var b strings.Builder
+
+    var width int
+    if len(pixels) > 0 {
+        width = e.clippedWidth(len(pixels[0]))
+    }
+
+    lines := len(pixels)
+    if e.mode == ModeTwoRowSpace {
+        lines *= 2
+    }
+
+    top, bottom := padding(lines, e.box.Y)
+
+    b.WriteString(e.header())
+    b.WriteString(e.borderLine(true, width))
+    b.WriteString(e.fillRows(top, width))
+
+    for i := range pixels {
+        row := scanIndex(i, len(pixels), e.scanY)
+        b.WriteString(e.emitRow(row, pixels[row]))
+    }
+
+    b.WriteString(e.fillRows(bottom, width))
+    b.WriteString(e.borderLine(false, width))
+    b.WriteString(e.footer())
+    return e.sanitized(b.String())
+}
+
+// header is written before the first row.
+func (e *Encoder) header() (header string) {
+    if e.mode == ModePagerSafe {
+        return
+    }
+
+    if e.bom {
+        header = "\ufeff"
+    }
+
+    if e.mode == ModeHTML {
+        header += htmlOpen
+        if e.hyperlink != "" {
+            header += `<a href="` + html.EscapeString(e.hyperlink) + `">`
+        }
+
+        return
+    }
+
+    if e.hyperlink != "" {
+        header += "\x1b]8;;" + e.hyperlink + "\x07"
+    }
+
+    return
+}
+
+// footer is written after the last row.
+func (e *Encoder) footer() (footer string) {
+    if e.mode == ModePagerSafe {
+        return
+    }
+
+    if e.mode == ModeHTML {
+        if e.hyperlink != "" {
+            footer = "</a>"
+        }
+
+        return footer + htmlClose
+    }
+
+    if e.hyperlink != "" {
+        footer = "\x1b]8;;\x07"
+    }
+
+    return
+}
+
+// emitRow converts a row of cells & returns it with the separator,
+// or hands it to the row callback & returns nothing when there is one.
+func (e *Encoder) emitRow(row int, cells []Cell) string {
+    encoded := e.encodeRow(row, cells)
+    if e.rowFunc != nil {
+        e.rowFunc(row, encoded)
+        return ""
+    }
+
+    return encoded + e.separator
+}
+
+// encodeRow converts a single row of cells, without the separator, & passes it through the row wrapper.
+func (e *Encoder) encodeRow(row int, cells []Cell) string {
+    if e.scanX == Backward {
+        reversed := make([]Cell, len(cells))
+        for x, cell := range cells {
+            reversed[len(cells) - 1 - x] = cell
+        }
+
+        cells = reversed
+    }
+
+    encoded := e.modeRow(row, cells)
+    if e.rowWrap != nil {
+        encoded = e.rowWrap(row, encoded)
+    }
+
+    return e.sanitized(encoded)
+}
+
+// sanitized drops invalid UTF-8 & stray control characters from output when the encoder was created
+// with WithSanitizedOutput(true).
+func (e *Encoder) sanitized(output string) string {
+    if !e.sanitize {
+        return output
+    }
+
+    return strings.Map(func(r rune) rune {
+        switch {
+            case r == '\x1b' || r == '\a' || r == '\t' || r == '\n' || r == '\r':
+                return r
+
+            case unicode.IsControl(r):
+                return -1
+        }
+
+        return r
+    }, strings.ToValidUTF8(output, ""))
+}
+
+// modeRow converts a single row of cells in the format of the encoder's mode.
+func (e *Encoder) modeRow(row int, cells []Cell) string {
+    clipped := e.clippedWidth(len(cells)) < len(cells)
+    if clipped {
+        cells = cells[:e.maxCols]
+    }
+
+    // Rows of tview text keep their last colours unless they're reset,
+    // which clipped rows need for the text after them
+    var reset string
+    if clipped || e.lineReset {
+        reset = "[-:-]"
+    }
+
+    switch e.mode {
+        default:
+            return e.bordered(e.tviewRow(row, cells) + reset, len(cells))
+
+        case ModeHTML:
+            return e.bordered(e.htmlRow(row, cells), len(cells))
+
+        case ModeANSI, ModePagerSafe, ModeANSI256, ModeANSI16, ModeGray256:
+            return e.bordered(e.ansiRow(row, cells), len(cells)) + e.eraseLine()
+
+        case ModeASCII:
+            return e.bordered(e.asciiRow(row, cells), len(cells))
+
+        case ModeMatte:
+            return e.bordered(e.matteRow(row, cells) + reset, len(cells))
+
+        case ModeTwoRowSpace:
+            return e.spaceRow(cells, reset)
+    }
+}
+
+// imageCells returns how many cells wide & how many lines tall the image itself takes in the output,
+// without the box or border around it.
+func (e *Encoder) imageCells(size image.Point) (cols, lines int) {
+    cols, lines = size.X, size.Y / 2
+    if e.colScale > 1 {
+        cols *= e.colScale
+    }
+
+    cols = e.clippedWidth(cols)
+
+    if e.mode == ModeTwoRowSpace {
+        lines *= 2
+    }
+
+    return
+}
+
+// scanIndex returns which of n rows or columns is the ith to be written in the direction dir.
+func scanIndex(i, n int, dir Direction) int {
+    if dir == Backward {
+        return n - 1 - i
+    }
+
+    return i
+}
+
+// clippedWidth returns how many of width cells are written per row, with the WithMaxCols option.
+func (e *Encoder) clippedWidth(width int) int {
+    if e.maxCols > 0 && width > e.maxCols {
+        return e.maxCols
+    }
+
+    return width
+}
+
+// regionTag returns the region tag which starts the cell at col & row, if its region differs from
+// the current one, which it updates. After the last cell of a row, col is past its end & the region is closed.
+func (e *Encoder) regionTag(col, row, width int, current *string) string {
+    if e.regions == nil {
+        return ""
+    }
+
+    var id string
+    if col < width {
+        id = regionID(e.regions(col, row))
+    }
+
+    if id == *current {
+        return ""
+    }
+
+    *current = id
+    return `["` + id + `"]`
+}
+
+// regionID leaves out the characters of a region ID which tview doesn't take, like quotes & brackets,
+// which would end the tag early & write the rest as text.
+func regionID(id string) string {
+    return strings.Map(func(r rune) rune {
+        switch {
+            case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("_,;: -.", r):
+                return r
+        }
+
+        return -1
+    }, id)
+}
+
+// substitute returns the string the PixelFunc option substitutes for a cell, if any.
+func (e *Encoder) substitute(col, row int, cell Cell) (string, bool) {
+    if e.pixelFunc == nil {
+        return "", false
+    }
+
+    return e.pixelFunc(col, row, cell.Fg, cell.Bg)
+}
+
+// cellGlyph returns the glyph drawn in every cell.
+func (e *Encoder) cellGlyph() string {
+    if e.glyph == "" {
+        return "▀"
+    }
+
+    return e.glyph
+}
+
+// glyphWidth returns the number of terminal columns the glyph of each ANSI cell takes, at least 1.
+func (e *Encoder) glyphWidth() int {
+    if e.asciiApprox {
+        return 1
+    }
+
+    if width := runewidth.StringWidth(e.cellGlyph()); width > 1 {
+        return width
+    }
+
+    return 1
+}
+
+// glyphed swaps the half block an encoded cell ends with for the glyph of the encoder.
+func (e *Encoder) glyphed(encoded string) string {
+    if e.glyph == "" {
+        return encoded
+    }
+
+    return strings.TrimSuffix(encoded, "▀") + e.glyph
+}
@@ -0,0 +1,1087 @@
+package pxl
+
+import (
+    "bytes"
+    "errors"
+    "image"
+    "image/color"
+    "image/draw"
+    "image/png"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "testing"
+    "unicode"
+    "unicode/utf8"
+)
+
+func TestWithHyperlink(t *testing.T) {
+    img := TestPattern(2, 2)
+
+    encoded, err := NewEncoder(WithMode(ModeANSI), WithHyperlink("https://example.com/a?b=c")).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !strings.HasPrefix(encoded, "\x1b]8;;https://example.com/a?b=c\x07") || !strings.HasSuffix(encoded, "\x1b]8;;\x07") {
+        t.Errorf("output isn't wrapped in an OSC 8 link: %q", encoded)
+    }
+
+    html, err := NewEncoder(WithMode(ModeHTML), WithHyperlink(`https://example.com/"<>`)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !strings.Contains(html, `<a href="https://example.com/&#34;&lt;&gt;">`) {
+        t.Errorf("HTML output doesn't hold an escaped link: %q", html)
+    }
+}
+
+func TestWithHyperlinkStripsControls(t *testing.T) {
+    url := "https://example.com/\x07\x1b[2J\x1b]8;;evil\x9c\u009b\xff"
+
+    encoded, err := NewEncoder(WithMode(ModeANSI), WithHyperlink(url)).Encode(TestPattern(2, 2))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    header := "\x1b]8;;https://example.com/[2J]8;;evil\x07"
+    if !strings.HasPrefix(encoded, header) {
+        t.Errorf("output starts with %q, want %q", encoded[:len(header)], header)
+    }
+
+    if strings.Count(encoded, "\x07") != 2 || strings.Contains(encoded, "\x1b[2J") {
+        t.Errorf("control characters of the link made it into the output: %q", encoded)
+    }
+}
+
+func TestWithRegions(t *testing.T) {
+    regions := func(col, row int) string {
+        if col < 2 {
+            return "left"
+        }
+
+        return ""
+    }
+
+    encoded, err := NewEncoder(WithRegions(regions)).Encode(solid(3, 2, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := `["left"][#ffffff:#ffffff]▀▀[""]▀` + "\n"; encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+}
+
+func TestWithRegionsStripsIDs(t *testing.T) {
+    regions := func(col, row int) string {
+        return `a"]b[red]` + "\x1b" + `c-1.2`
+    }
+
+    encoded, err := NewEncoder(WithRegions(regions)).Encode(solid(1, 2, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := `["abredc-1.2"][#ffffff:#ffffff]▀[""]` + "\n"; encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+}
+
+func TestWithThemeBackground(t *testing.T) {
+    img := solid(2, 2, color.NRGBA{0xff, 0xff, 0xff, 0x80})
+    img.Set(1, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 1, color.Transparent)
+
+    encoded, err := NewEncoder(WithThemeBackground(color.RGBA{0x20, 0x20, 0x20, 0x80})).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Half of white over the theme, opaque red as is, & the theme itself where the image is transparent
+    if want := "[#909090:#909090]▀[#ff0000:#202020]▀\n"; encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+}
+
+func TestWithLineSeparator(t *testing.T) {
+    img := TestPattern(3, 8)
+
+    for _, separator := range []string{"\r\n", "", "|"} {
+        for _, mode := range []Mode{ModeTview, ModeANSI} {
+            encoded, err := NewEncoder(WithMode(mode), WithLineSeparator(separator)).Encode(img)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if strings.Contains(strings.ReplaceAll(encoded, "\r\n", ""), "\n") {
+                t.Errorf("mode %d with separator %q still wrote a bare newline: %q", mode, separator, encoded)
+            }
+
+            if separator != "" && (strings.Count(encoded, separator) != 4 || !strings.HasSuffix(encoded, separator)) {
+                t.Errorf("mode %d: separator %q doesn't end each of 4 rows: %q", mode, separator, encoded)
+            }
+        }
+    }
+
+    lf, _ := NewEncoder().Encode(img)
+    crlf, _ := NewEncoder(WithLineSeparator("\r\n")).Encode(img)
+    if strings.ReplaceAll(lf, "\n", "\r\n") != crlf {
+        t.Errorf("CRLF output %q isn't the default output %q with CRLF line endings", crlf, lf)
+    }
+}
+
+func TestWithHeatmap(t *testing.T) {
+    ramp := image.NewGray(image.Rect(0, 0, 3, 2))
+    for y := 0; y < 2; y++ {
+        ramp.Pix[y * ramp.Stride], ramp.Pix[y * ramp.Stride + 1], ramp.Pix[y * ramp.Stride + 2] = 0, 0x80, 0xff
+    }
+
+    gradient := []color.Color{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+    pixels, err := NewEncoder(WithHeatmap(gradient)).Decode(ramp)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cells := pixels[0]
+    if ColorHex(cells[0].Fg) != "#ff0000" || ColorHex(cells[2].Fg) != "#0000ff" {
+        t.Errorf("black maps to %s & white to %s, want #ff0000 & #0000ff", ColorHex(cells[0].Fg), ColorHex(cells[2].Fg))
+    }
+
+    if got := ColorHex(cells[1].Fg); got != "#7f0080" && got != "#80007f" {
+        t.Errorf("middle grey maps to %s, want halfway between red & blue", got)
+    }
+
+    // The opacity of every pixel is kept
+    faint := solid(1, 2, color.NRGBA{0xff, 0xff, 0xff, 0x40})
+    if pixels, _ = NewEncoder(WithHeatmap(gradient)).Decode(faint); !SameCell(pixels[0][0].Fg, color.NRGBA{0, 0, 0xff, 0x40}) {
+        t.Errorf("semi-transparent white maps to %v, want semi-transparent blue", pixels[0][0].Fg)
+    }
+}
+
+func TestWithPixelFunc(t *testing.T) {
+    marker := func(x, y int, fg, bg color.Color) (string, bool) {
+        if x == 1 && y == 1 {
+            return "[red]X", true
+        }
+
+        return "", false
+    }
+
+    encoded, err := NewEncoder(WithPixelFunc(marker)).Encode(solid(3, 4, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The cell after the marker sets its colours again
+    want := "[#ffffff:#ffffff]▀▀▀\n[#ffffff:#ffffff]▀[red]X[#ffffff:#ffffff]▀\n"
+    if encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+
+    ansi, err := NewEncoder(WithMode(ModeANSI), WithPixelFunc(marker)).Encode(solid(3, 4, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if lines := strings.Split(ansi, "\n"); len(lines) != 3 || !strings.Contains(lines[1], "▀[red]X\x1b[") {
+        t.Errorf("ANSI output doesn't hold the marker in the middle of the second row: %q", ansi)
+    }
+}
+
+func TestWithTransparencyPreview(t *testing.T) {
+    encoded, err := NewEncoder(WithTransparencyPreview(true)).Encode(image.NewNRGBA(image.Rect(0, 0, 3, 4)))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Each cell is a square of its own, so the greys alternate along rows & down columns
+    want := "[#cccccc:#cccccc]▀[#999999:#999999]▀[#cccccc:#cccccc]▀\n" +
+        "[#999999:#999999]▀[#cccccc:#cccccc]▀[#999999:#999999]▀\n"
+    if encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+
+    if encoded, _ = NewEncoder(WithTransparencyPreview(false)).Encode(image.NewNRGBA(image.Rect(0, 0, 3, 4))); strings.Contains(encoded, "#") {
+        t.Errorf("transparency is previewed once it's turned off: %q", encoded)
+    }
+}
+
+func TestWithRounding(t *testing.T) {
+    img := image.NewRGBA64(image.Rect(0, 0, 1, 2))
+    img.SetRGBA64(0, 0, color.RGBA64{0x01ff, 0x01ff, 0x01ff, 0xffff})
+    img.SetRGBA64(0, 1, color.RGBA64{0xff80, 0xffff, 0, 0xffff})
+
+    truncated, err := NewEncoder().Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#010101:#ffff00]▀\n"; truncated != want {
+        t.Errorf("Encode() = %q, want %q", truncated, want)
+    }
+
+    rounded, err := NewEncoder(WithRounding(RoundNearest)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Rounding up stops at 0xff rather than wrapping around
+    if want := "[#020202:#ffff00]▀\n"; rounded != want {
+        t.Errorf("Encode() rounding to the nearest = %q, want %q", rounded, want)
+    }
+}
+
+func TestWithGradientBackground(t *testing.T) {
+    from, to := color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}
+    img := image.NewNRGBA(image.Rect(0, 0, 5, 4))
+
+    pixels, err := NewEncoder(WithGradientBackground(from, to, true)).Decode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, row := range pixels {
+        if !SameCell(row[0].Fg, from) || !SameCell(row[0].Bg, from) || !SameCell(row[4].Fg, to) || !SameCell(row[4].Bg, to) {
+            t.Errorf("row runs from %v to %v, want %v to %v", row[0].Fg, row[4].Fg, from, to)
+        }
+    }
+
+    if got := ColorHex(pixels[0][2].Fg); got != "#7f007f" && got != "#800080" {
+        t.Errorf("middle of the gradient is %s, want halfway between red & blue", got)
+    }
+
+    // Top to bottom, the top pixel of the first row of cells is from & the bottom one of the last row to
+    if pixels, _ = NewEncoder(WithGradientBackground(from, to, false)).Decode(img); !SameCell(pixels[0][0].Fg, from) || !SameCell(pixels[1][0].Bg, to) {
+        t.Errorf("vertical gradient runs from %v to %v, want %v to %v", pixels[0][0].Fg, pixels[1][0].Bg, from, to)
+    }
+}
+
+func TestEncodeFileResized(t *testing.T) {
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, TestPattern(4, 12)); err != nil {
+        t.Fatal(err)
+    }
+
+    filename := filepath.Join(t.TempDir(), "pattern.png")
+    if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    encoder := NewEncoder(WithMaxRows(2))
+    encoded, err := encoder.EncodeFile(filename)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if len(lines) != 2 || VisualWidth(lines[0]) != 4 {
+        t.Errorf("EncodeFile() wrote %d rows %d wide, want 2 rows 4 wide: %q", len(lines), VisualWidth(lines[0]), encoded)
+    }
+
+    if read, _ := encoder.EncodeReader(bytes.NewReader(buf.Bytes())); read != encoded {
+        t.Errorf("EncodeReader() = %q, EncodeFile() = %q", read, encoded)
+    }
+
+    if _, err = encoder.EncodeFile(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+        t.Error("EncodeFile() of a missing file succeeded")
+    }
+}
+
+func TestWithDuotone(t *testing.T) {
+    ramp := image.NewGray(image.Rect(0, 0, 256, 2))
+    for x := 0; x < 256; x++ {
+        ramp.Pix[x], ramp.Pix[ramp.Stride + x] = uint8(x), uint8(x)
+    }
+
+    amber, black := color.RGBA{0xff, 0xb0, 0, 0xff}, color.RGBA{0, 0, 0, 0xff}
+    pixels, err := NewEncoder(WithDuotone(black, amber, 0x60)).Decode(ramp)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for x, cell := range pixels[0] {
+        want := amber
+        if x < 0x60 {
+            want = black
+        }
+
+        if !SameCell(cell.Fg, want) || !SameCell(cell.Bg, want) {
+            t.Errorf("grey %#x became %v over %v, want %v", x, cell.Fg, cell.Bg, want)
+        }
+    }
+}
+
+func TestWithColumnScale(t *testing.T) {
+    img := TestPattern(5, 6)
+    plain, err := NewEncoder().Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    scaled, err := NewEncoder(WithColumnScale(2)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    rows, scaledRows := strings.Split(plain, "\n"), strings.Split(scaled, "\n")
+    if len(scaledRows) != len(rows) {
+        t.Fatalf("scaled output has %d rows, want %d", len(scaledRows), len(rows))
+    }
+
+    for i := range rows {
+        if VisualWidth(scaledRows[i]) != 2 * VisualWidth(rows[i]) {
+            t.Errorf("row %d is %d wide, want %d", i, VisualWidth(scaledRows[i]), 2 * VisualWidth(rows[i]))
+        }
+    }
+
+    // Only the glyphs are repeated, not the tags
+    if got, want := strings.Count(scaled, "["), strings.Count(plain, "["); got != want {
+        t.Errorf("scaled output holds %d tags, want %d", got, want)
+    }
+
+    if double, _ := NewEncoder(WithDoubleWide(true)).Encode(img); double != scaled {
+        t.Errorf("WithDoubleWide(true) = %q, WithColumnScale(2) = %q", double, scaled)
+    }
+}
+
+func TestWithDoubleWide(t *testing.T) {
+    img := TestPattern(5, 6)
+    double, err := NewEncoder(WithDoubleWide(true)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    decoded, err := ToImage(double)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if size := decoded.Bounds().Size(); size != image.Pt(10, 6) {
+        t.Fatalf("double wide output is %v pixels, want 10 by 6", size)
+    }
+
+    // Both halves of every wide cell take the colours of the pixels under it
+    for y := 0; y < 6; y++ {
+        for x := 0; x < 10; x++ {
+            if !SameCell(decoded.At(x, y), img.At(x / 2, y)) {
+                t.Errorf("double wide output is %s at (%d, %d), want %s", ColorHex(decoded.At(x, y)), x, y, ColorHex(img.At(x / 2, y)))
+            }
+        }
+    }
+
+    single, err := NewEncoder(WithDoubleWide(false)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); single != want {
+        t.Errorf("WithDoubleWide(false) = %q, want %q", single, want)
+    }
+}
+
+func TestWithWindowsCompat(t *testing.T) {
+    img := TestPattern(4, 4)
+
+    encoded, err := NewEncoder(WithWindowsCompat(WindowsCompat{Glyph: '█'})).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !utf8.ValidString(encoded) || strings.HasPrefix(encoded, "\ufeff") {
+        t.Errorf("output isn't UTF-8 without a byte order mark: %q", encoded)
+    }
+
+    if strings.Count(encoded, "█") != 8 || strings.Contains(encoded, "▀") || !strings.Contains(encoded, "\x1b[38;2;") {
+        t.Errorf("output isn't true colour ANSI drawn with the compatibility glyph: %q", encoded)
+    }
+
+    bom, _ := NewEncoder(WithWindowsCompat(WindowsCompat{Glyph: utf8.RuneError, BOM: true})).Encode(img)
+    if !strings.HasPrefix(bom, "\ufeff") || strings.Count(bom, "▀") != 8 {
+        t.Errorf("output doesn't start with a byte order mark & keep the half block for an invalid glyph: %q", bom)
+    }
+}
+
+// straightRed is a custom image whose RGBA() returns half opaque red with straight alpha.
+type straightRed struct{}
+
+func (straightRed) ColorModel() color.Model { return color.RGBA64Model }
+func (straightRed) Bounds() image.Rectangle { return image.Rect(0, 0, 1, 2) }
+func (straightRed) At(x, y int) color.Color { return color.RGBA64{0xffff, 0, 0, 0x8000} }
+
+func TestWithInputAlpha(t *testing.T) {
+    premultiplied, err := NewEncoder(WithInputAlpha(true)).Encode(straightRed{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    straight, err := NewEncoder(WithInputAlpha(false)).Encode(straightRed{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Taken as straight, the red is premultiplied by its alpha first
+    if want := "[#ff0000:#ff0000]▀\n"; premultiplied != want {
+        t.Errorf("taken as premultiplied = %q, want %q", premultiplied, want)
+    }
+
+    if want := "[#800000:#800000]▀\n"; straight != want {
+        t.Errorf("taken as straight = %q, want %q", straight, want)
+    }
+}
+
+func TestWithMaxCols(t *testing.T) {
+    img := TestPattern(40, 6)
+
+    encoded, err := NewEncoder(WithMaxCols(10)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    full, _ := DecodeToPixels(img)
+    for i, line := range strings.Split(strings.TrimSuffix(encoded, "\n"), "\n") {
+        if VisualWidth(line) != 10 || !strings.HasSuffix(line, "[-:-]") {
+            t.Errorf("row %d is %d cells wide, want 10 ending with a reset: %q", i, VisualWidth(line), line)
+        }
+
+        // The cells shown are those of the image at its own resolution
+        if want := (Pixels{full[i][:10]}).Encode(); line + "\n" != strings.Replace(want, "\n", "[-:-]\n", 1) {
+            t.Errorf("row %d = %q, want the first 10 cells %q", i, line, want)
+        }
+    }
+
+    whole, _ := FromImage(img)
+    if narrow, _ := NewEncoder(WithMaxCols(100)).Encode(img); narrow != whole {
+        t.Errorf("an image narrower than the limit = %q, want %q", narrow, whole)
+    }
+}
+
+func TestWithScanlines(t *testing.T) {
+    img := solid(2, 8, color.NRGBA{0xc0, 0x80, 0x40, 0xff})
+
+    pixels, err := NewEncoder(WithScanlines(0.25)).Decode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for row, cells := range pixels {
+        want := "#c08040"
+        if row % 2 == 1 {
+            want = "#906030"
+        }
+
+        for _, c := range []color.Color{cells[0].Fg, cells[0].Bg, cells[1].Fg, cells[1].Bg} {
+            if got := ColorHex(c); got != want {
+                t.Errorf("row %d holds %s, want %s", row, got, want)
+            }
+        }
+    }
+
+    // Intensities are clamped, at 1 odd rows turn black
+    if pixels, _ = NewEncoder(WithScanlines(3)).Decode(img); ColorHex(pixels[1][0].Fg) != "#000000" {
+        t.Errorf("odd rows at full intensity hold %s, want #000000", ColorHex(pixels[1][0].Fg))
+    }
+}
+
+func TestModeTwoRowSpace(t *testing.T) {
+    img := TestPattern(3, 6)
+
+    encoded, err := NewEncoder(WithMode(ModeTwoRowSpace)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if len(lines) != 6 {
+        t.Fatalf("output has %d rows, want one for each of the 6 rows of pixels", len(lines))
+    }
+
+    background := regexp.MustCompile(`^(\[:(#[0-9a-f]{6}|-)\] )+$`)
+    for y, line := range lines {
+        if !background.MatchString(line) {
+            t.Errorf("row %d doesn't only set background colours of spaces: %q", y, line)
+        }
+
+        if want := "[:" + ColorHex(img.At(1, y)) + "] "; !strings.Contains(line, want) {
+            t.Errorf("row %d = %q, want it to hold the middle pixel %q", y, line, want)
+        }
+    }
+}
+
+func TestWithChannelOrder(t *testing.T) {
+    img := solid(1, 2, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(0, 1, color.NRGBA{0x11, 0x22, 0x33, 0xff})
+
+    tests := map[string]string{
+        "bgr": "[#0000ff:#332211]▀\n",
+        "GRB": "[#00ff00:#221133]▀\n",
+        "rgb": "[#ff0000:#112233]▀\n",
+        "rrb": "[#ff0000:#112233]▀\n",
+        "rg":  "[#ff0000:#112233]▀\n",
+    }
+
+    for order, want := range tests {
+        encoded, err := NewEncoder(WithChannelOrder(order)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if encoded != want {
+            t.Errorf("order %q: Encode() = %q, want %q", order, encoded, want)
+        }
+    }
+}
+
+func TestWithColorSnap(t *testing.T) {
+    // Channels 2 above a multiple of 8 stay on it when they're a value off either way
+    base, jittered := image.NewNRGBA(image.Rect(0, 0, 6, 4)), image.NewNRGBA(image.Rect(0, 0, 6, 4))
+    for i := range base.Pix {
+        base.Pix[i], jittered.Pix[i] = 0xff, 0xff
+        if i % 4 != 3 {
+            base.Pix[i] = uint8(i * 40 % 256 / 8 * 8 + 2)
+            jittered.Pix[i] = uint8(int(base.Pix[i]) + 1 - i % 3)
+        }
+    }
+
+    snapped, err := NewEncoder(WithColorSnap(8)).Encode(base)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if other, _ := NewEncoder(WithColorSnap(8)).Encode(jittered); other != snapped {
+        t.Errorf("images a value apart snap to %q & %q", snapped, other)
+    }
+
+    if plain, _ := NewEncoder(WithColorSnap(1)).Encode(base); plain == snapped {
+        t.Error("a step of 1 snapped the colours")
+    }
+}
+
+func TestWithRowCallback(t *testing.T) {
+    img := TestPattern(4, 8)
+    plain, _ := FromImage(img)
+    want := strings.Split(strings.TrimSuffix(plain, "\n"), "\n")
+
+    var rows []string
+    encoded, err := NewEncoder(WithLineSeparator(""), WithRowCallback(func(row int, content string) {
+        if row != len(rows) {
+            t.Errorf("got row %d after %d rows", row, len(rows))
+        }
+
+        rows = append(rows, content)
+    })).Encode(img)
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if encoded != "" {
+        t.Errorf("rows were written to the output too: %q", encoded)
+    }
+
+    if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+        t.Errorf("callback got %q, want %q", rows, want)
+    }
+}
+
+func TestWithMaxRows(t *testing.T) {
+    // White over black, 20 rows of cells tall
+    tall := solid(6, 40, color.White)
+    draw.Draw(tall, image.Rect(0, 20, 6, 40), image.Black, image.Point{}, draw.Src)
+
+    encoded, err := NewEncoder(WithMaxRows(5)).Encode(tall)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if len(lines) != 5 || VisualWidth(lines[0]) != 6 {
+        t.Fatalf("WithMaxRows(5) wrote %d rows %d wide, want 5 rows 6 wide: %q", len(lines), VisualWidth(lines[0]), encoded)
+    }
+
+    // The middle row of cells is where white meets black
+    if want := "[#ffffff:#000000]▀▀▀▀▀▀"; lines[2] != want {
+        t.Errorf("middle row = %q, want %q", lines[2], want)
+    }
+
+    // Images which fit are left alone
+    short := TestPattern(6, 8)
+    encoded, err = NewEncoder(WithMaxRows(5)).Encode(short)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(short); encoded != want {
+        t.Errorf("WithMaxRows(5) of 4 rows = %q, want %q", encoded, want)
+    }
+}
+
+func TestWithRowWrapper(t *testing.T) {
+    img := TestPattern(4, 10)
+    wrapped, err := NewEncoder(WithRowWrapper(func(row int, content string) string {
+        if row == 2 {
+            return `["clickable"]` + content + `[""]`
+        }
+
+        return content
+    })).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plain, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines, want := strings.Split(wrapped, "\n"), strings.Split(plain, "\n")
+    if len(lines) != len(want) {
+        t.Fatalf("wrapped output has %d lines, want %d", len(lines), len(want))
+    }
+
+    for i := range lines {
+        if i == 2 {
+            want[i] = `["clickable"]` + want[i] + `[""]`
+        }
+
+        if lines[i] != want[i] {
+            t.Errorf("row %d = %q, want %q", i, lines[i], want[i])
+        }
+    }
+}
+
+func TestWithTransparencyPattern(t *testing.T) {
+    pattern := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    pattern.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    pattern.Set(1, 0, color.NRGBA{0, 0xff, 0, 0xff})
+    pattern.Set(0, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    pattern.Set(1, 1, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+
+    // Transparent but for an opaque black pixel, which covers the pattern
+    img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+    img.Set(3, 3, color.Black)
+
+    encoded, err := NewEncoder(WithTransparencyPattern(pattern)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#ff0000:#0000ff]▀[#00ff00:#ffffff]▀[#ff0000:#0000ff]▀[#00ff00:#ffffff]▀\n" +
+        "[#ff0000:#0000ff]▀[#00ff00:#ffffff]▀[#ff0000:#0000ff]▀[#00ff00:#000000]▀\n"
+    if encoded != want {
+        t.Errorf("WithTransparencyPattern() = %q, want %q", encoded, want)
+    }
+
+    // An empty pattern is ignored
+    empty, err := NewEncoder(WithTransparencyPattern(image.NewNRGBA(image.Rectangle{}))).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); empty != want {
+        t.Errorf("WithTransparencyPattern() of an empty pattern = %q, want %q", empty, want)
+    }
+}
+
+func TestWithMaxOutputCells(t *testing.T) {
+    // 10 cells wide & 5 rows tall
+    img := TestPattern(10, 10)
+
+    for _, limit := range []image.Point{{10, 5}, {0, 5}, {10, 0}, {80, 24}} {
+        if _, err := NewEncoder(WithMaxOutputCells(limit.X, limit.Y)).Encode(img); err != nil {
+            t.Errorf("WithMaxOutputCells(%d, %d) of 10 by 5 cells returned %v", limit.X, limit.Y, err)
+        }
+    }
+
+    for _, limit := range []image.Point{{9, 5}, {10, 4}, {0, 4}} {
+        if _, err := NewEncoder(WithMaxOutputCells(limit.X, limit.Y)).Encode(img); !errors.Is(err, ErrTooLarge) {
+            t.Errorf("WithMaxOutputCells(%d, %d) of 10 by 5 cells returned %v, want ErrTooLarge", limit.X, limit.Y, err)
+        }
+    }
+
+    // The limit applies once the image has been resized
+    if _, err := NewEncoder(WithMaxRows(4), WithMaxOutputCells(10, 4)).Encode(img); err != nil {
+        t.Errorf("WithMaxOutputCells() of an image resized to fit returned %v", err)
+    }
+}
+
+func TestWithSampleRounding(t *testing.T) {
+    // A white line a pixel wide, in the middle of the first pair of columns
+    img := solid(8, 4, color.Black)
+    draw.Draw(img, image.Rect(1, 0, 2, 4), image.White, image.Point{}, draw.Src)
+
+    centered, err := NewEncoder(WithPixelStride(2)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#ffffff:#ffffff]▀[#000000:#000000]▀▀▀\n"; centered != want {
+        t.Errorf("centre sampling = %q, want the line kept: %q", centered, want)
+    }
+
+    truncated, err := NewEncoder(WithPixelStride(2), WithSampleRounding(SampleTruncate)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#000000:#000000]▀▀▀▀\n"; truncated != want {
+        t.Errorf("truncated sampling = %q, want the line gone: %q", truncated, want)
+    }
+}
+
+func TestWithScanOrder(t *testing.T) {
+    img := TestPattern(5, 6).(*image.NRGBA)
+
+    // The image mirrored left to right, & with its rows of cells in reverse, keeping the two pixels of every cell in order
+    mirrored, flipped := image.NewNRGBA(img.Rect), image.NewNRGBA(img.Rect)
+    for y := 0; y < 6; y++ {
+        for x := 0; x < 5; x++ {
+            mirrored.Set(4 - x, y, img.At(x, y))
+            flipped.Set(x, (4 - y / 2 * 2) + y % 2, img.At(x, y))
+        }
+    }
+
+    for _, test := range []struct {
+        horizontal, vertical Direction
+        want                 image.Image
+    }{
+        {Backward, Forward, mirrored},
+        {Forward, Backward, flipped},
+    } {
+        encoded, err := NewEncoder(WithScanOrder(test.horizontal, test.vertical)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want, _ := FromImage(test.want); encoded != want {
+            t.Errorf("WithScanOrder(%d, %d) = %q, want %q", test.horizontal, test.vertical, encoded, want)
+        }
+    }
+}
+
+func TestWithAlphaCutoff(t *testing.T) {
+    // The fringe of a red sprite, fading out to the left
+    alphas := []uint8{0x10, 0x30, 0x4f, 0x50, 0xa0, 0xff}
+    img := image.NewNRGBA(image.Rect(0, 0, len(alphas), 2))
+    for x, a := range alphas {
+        img.SetNRGBA(x, 0, color.NRGBA{0xff, 0, 0, a})
+        img.SetNRGBA(x, 1, color.NRGBA{0xff, 0, 0, a})
+    }
+
+    for _, opts := range [][]Option{nil, {WithThemeBackground(color.Black)}} {
+        plain, err := NewEncoder(opts...).Decode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        pixels, err := NewEncoder(append(opts, WithAlphaCutoff(0x50))...).Decode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for x, a := range alphas {
+            fg, want := pixels[0][x].Fg, plain[0][x].Fg
+            switch {
+                case a < 0x50 && opts == nil:
+                    want = color.Transparent
+
+                case a < 0x50:
+                    want = color.Black
+            }
+
+            if color.NRGBAModel.Convert(fg) != color.NRGBAModel.Convert(want) {
+                t.Errorf("%d options: pixel at alpha %#x is %v, want %v", len(opts), a, fg, want)
+            }
+        }
+    }
+}
+
+func TestWithPixelRoles(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(0, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    img.Set(1, 0, color.NRGBA{0, 0xff, 0, 0xff})
+    img.Set(1, 1, color.White)
+
+    for top, want := range map[bool]string{
+        true:  "[#ff0000:#0000ff]▀[#00ff00:#ffffff]▀\n",
+        false: "[#0000ff:#ff0000]▀[#ffffff:#00ff00]▀\n",
+    } {
+        encoded, err := NewEncoder(WithPixelRoles(top)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if encoded != want {
+            t.Errorf("WithPixelRoles(%t) = %q, want %q", top, encoded, want)
+        }
+    }
+}
+
+func TestWithSanitizedOutput(t *testing.T) {
+    // Out of range colours, with invalid bytes & control characters around every row & in the separator
+    wrap := WithRowWrapper(func(row int, content string) string {
+        return "\xff\x00" + content + "\xc3\b"
+    })
+
+    for _, mode := range []Mode{ModeTview, ModeANSI} {
+        encoded, err := NewEncoder(WithMode(mode), wrap, WithLineSeparator("\x7f\r\n"), WithSanitizedOutput(true)).Encode(overRangeImage{})
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !utf8.ValidString(encoded) {
+            t.Errorf("mode %d: output isn't valid UTF-8: %q", mode, encoded)
+        }
+
+        if stray := strings.IndexFunc(encoded, func(r rune) bool {
+            return unicode.IsControl(r) && !strings.ContainsRune("\x1b\r\n", r)
+        }); stray >= 0 {
+            t.Errorf("mode %d: output holds a control character at %d: %q", mode, stray, encoded)
+        }
+
+        // Nothing but what was added is dropped
+        plain, err := NewEncoder(WithMode(mode), WithLineSeparator("\r\n")).Encode(overRangeImage{})
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if encoded != plain {
+            t.Errorf("mode %d: sanitized output = %q, want %q", mode, encoded, plain)
+        }
+    }
+}
+
+func TestWithLineReset(t *testing.T) {
+    img := TestPattern(3, 4)
+
+    // Every row ends back in the default colours, so whatever is put after it doesn't take them
+    for mode, reset := range map[Mode]string{ModeTview: "[-:-]", ModeANSI: "\x1b[0m", ModeANSI256: "\x1b[0m"} {
+        encoded, err := NewEncoder(WithMode(mode), WithLineReset(true)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        plain, err := NewEncoder(WithMode(mode)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        lines, plainLines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n"), strings.Split(strings.TrimSuffix(plain, "\n"), "\n")
+        for i, line := range lines {
+            if !strings.HasSuffix(line, reset) || strings.TrimSuffix(line, reset) != strings.TrimSuffix(plainLines[i], reset) {
+                t.Errorf("mode %d: row %d = %q, want %q ending with %q", mode, i, line, plainLines[i], reset)
+            }
+        }
+    }
+}
+
+func TestWithCellGlyph(t *testing.T) {
+    img := TestPattern(4, 6)
+
+    for _, mode := range []Mode{ModeTview, ModeANSI, ModeANSI256} {
+        plain, err := NewEncoder(WithMode(mode)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        squares, err := NewEncoder(WithMode(mode), WithCellGlyph('■')).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // The glyph takes the place of every half block, with the same colours
+        if want := strings.ReplaceAll(plain, "▀", "■"); squares != want || strings.Count(squares, "■") != 12 {
+            t.Errorf("mode %d: WithCellGlyph('■') = %q, want %q", mode, squares, want)
+        }
+
+        if zero, _ := NewEncoder(WithMode(mode), WithCellGlyph(0)).Encode(img); zero != plain {
+            t.Errorf("mode %d: WithCellGlyph(0) = %q, want ▀ as usual", mode, zero)
+        }
+    }
+}
+
+func TestWithGamma(t *testing.T) {
+    // White at half opacity over black
+    img := solid(2, 2, color.NRGBA{0xff, 0xff, 0xff, 0x80})
+
+    for gamma, want := range map[bool]string{false: "#808080", true: "#bcbcbc"} {
+        pixels, err := NewEncoder(WithThemeBackground(color.Black), WithGamma(gamma)).Decode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if got := ColorHex(pixels[0][0].Fg); got != want {
+            t.Errorf("WithGamma(%t) composited to %s, want %s", gamma, got, want)
+        }
+    }
+
+    // Opaque pixels are the same either way
+    opaque := TestPattern(4, 4)
+    linear, err := NewEncoder(WithThemeBackground(color.Black), WithGamma(true)).Encode(opaque)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(opaque); linear != want {
+        t.Errorf("WithGamma(true) of an opaque image = %q, want %q", linear, want)
+    }
+}
+
+func TestWithEdgeFill(t *testing.T) {
+    // A gradient getting darker towards the bottom, of an uneven height
+    img := image.NewGray(image.Rect(0, 0, 1, 5))
+    for y := range img.Pix {
+        img.Pix[y] = uint8(0xff - y * 0x30)
+    }
+
+    for fill, want := range map[EdgeFill]string{
+        EdgeReflect:   "#6f6f6f",
+        EdgeDuplicate: "#3f3f3f",
+        EdgePad:       "-",
+    } {
+        encoded, err := NewEncoder(WithEdgeFill(fill)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // The padded row is the bottom half of the last cell
+        lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+        if want = "[#3f3f3f:" + want + "]▀"; len(lines) != 3 || lines[2] != want {
+            t.Errorf("fill %d: last row = %q, want %q", fill, lines[len(lines) - 1], want)
+        }
+    }
+
+    if _, err := NewEncoder().Encode(img); err != ErrOddHeight {
+        t.Errorf("Encode() of an uneven image returned %v by default, want ErrOddHeight", err)
+    }
+}
+
+func TestWithPosterize(t *testing.T) {
+    img := TestPattern(16, 16)
+    encoded, err := NewEncoder(WithPosterize(2)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every channel of every colour written is either 00 or ff
+    hex := regexp.MustCompile(`#([0-9a-f]{2})([0-9a-f]{2})([0-9a-f]{2})`)
+    colors := hex.FindAllStringSubmatch(encoded, -1)
+    if len(colors) == 0 {
+        t.Fatalf("WithPosterize(2) = %q, want some colours", encoded)
+    }
+
+    for _, c := range colors {
+        for _, channel := range c[1:] {
+            if channel != "00" && channel != "ff" {
+                t.Errorf("WithPosterize(2) wrote %s, want every channel 00 or ff", c[0])
+            }
+        }
+    }
+
+    plain, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Fewer colours to change between take fewer tags
+    if strings.Count(encoded, "[") >= strings.Count(plain, "[") {
+        t.Errorf("WithPosterize(2) wrote as many tags as without it")
+    }
+
+    if single, _ := NewEncoder(WithPosterize(1)).Encode(img); single != plain {
+        t.Errorf("WithPosterize(1) = %q, want the colours left as they are: %q", single, plain)
+    }
+}
+
+func TestWithVignette(t *testing.T) {
+    img := solid(10, 10, color.White)
+
+    pixels, err := NewEncoder(WithVignette(0.8)).Decode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    corner, edge, center := luminance(pixels[0][0].Fg), luminance(pixels[0][5].Fg), luminance(pixels[2][5].Fg)
+    if !(corner < edge && edge < center) || center < 0.95 {
+        t.Errorf("the corner has a luminance of %.2f, the edge %.2f & the center %.2f, want them brightening to white", corner, edge, center)
+    }
+
+    if corner > 0.4 {
+        t.Errorf("corner pixel has a luminance of %.2f, want it darkened by most of the strength", corner)
+    }
+
+    plain, err := NewEncoder(WithVignette(0)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); plain != want {
+        t.Errorf("WithVignette(0) = %q, want %q", plain, want)
+    }
+}
+
+func TestWithMaxTagsPerRow(t *testing.T) {
+    // Noise, where the colours change with every cell
+    img := noise(40, 6, 1)
+
+    for _, n := range []int{1, 5, 12} {
+        encoded, err := NewEncoder(WithMaxTagsPerRow(n)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for i, line := range strings.Split(strings.TrimSuffix(encoded, "\n"), "\n") {
+            if tags := strings.Count(line, "["); tags > n || VisualWidth(line) != 40 {
+                t.Errorf("WithMaxTagsPerRow(%d): row %d has %d tags & is %d wide, want at most %d tags & 40 wide", n, i, tags, VisualWidth(line), n)
+            }
+        }
+    }
+
+    // Rows within the limit are left as they are
+    loose, err := NewEncoder(WithMaxTagsPerRow(40)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); loose != want {
+        t.Errorf("WithMaxTagsPerRow(40) = %q, want %q", loose, want)
+    }
+}
+
+func TestWithTagFormatter(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 4))
+    for x, colors := range [][2]color.Color{{color.White, color.Black}, {color.White, color.NRGBA{0xff, 0, 0, 0xff}}, {color.NRGBA{0, 0, 0xff, 0xff}, color.NRGBA{0xff, 0, 0, 0xff}}} {
+        for y := 0; y < 4; y += 2 {
+            img.Set(x, y, colors[0])
+            img.Set(x, y + 1, colors[1])
+        }
+    }
+
+    // A made-up markup, which writes a colour only where it changed
+    formatter := func(fg, bg color.Color, fgChanged, bgChanged bool) string {
+        tag := "<"
+        if fgChanged {
+            tag += "fg=" + ColorHex(fg)
+        }
+
+        if bgChanged {
+            tag += "bg=" + ColorHex(bg)
+        }
+
+        return tag + ">"
+    }
+
+    encoded, err := NewEncoder(WithTagFormatter(formatter)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every row starts over with both colours, without any tview tags
+    row := "<fg=#ffffffbg=#000000>▀<bg=#ff0000>▀<fg=#0000ff>▀\n"
+    if want := row + row; encoded != want {
+        t.Errorf("Encode() with a formatter = %q, want %q", encoded, want)
+    }
+}
@@ -0,0 +1,107 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+
+    "github.com/pkg/errors"
+)
+
+// FocusRender converts an image with the region focus drawn in focusMode & the rest around it in contextMode,
+// for showing a part of an image in more detail than its surroundings. Both modes must write the same format,
+// like ModeTview & ModeTwoRowSpace, or ModeANSI & ModeANSI16. Every line of output holds the same number of pixel rows
+// across the whole width, those of focusMode, so when contextMode holds fewer per line, like ModeTwoRowSpace does
+// next to the half-blocks of ModeTview, the context is scaled down to match & the focus region keeps its cells
+// in line with where they are in the image. The focus region is grown to start & end on whole lines.
+func FocusRender(img image.Image, focus image.Rectangle, focusMode, contextMode Mode) (encoded string, err error) {
+    if modeFormat(focusMode) != modeFormat(contextMode) {
+        return "", errors.New("pixelview: Can't combine modes which write different formats")
+    }
+
+    if err = checkHeight(img); err != nil {
+        return
+    }
+
+    bounds := img.Bounds()
+    focus = focus.Intersect(bounds)
+    if focus.Empty() {
+        return "", errors.New("pixelview: Can't focus on rectangle outside of the image")
+    }
+
+    fe, ce := NewEncoder(WithMode(focusMode)), NewEncoder(WithMode(contextMode))
+    perLine := linePixels(focusMode)
+    lines := bounds.Dy() / perLine
+
+    // Lines are counted from the top of the image, which starts on an even row
+    top := bounds.Min.Y + (focus.Min.Y - bounds.Min.Y) / perLine * perLine
+    bottom := bounds.Min.Y + (focus.Max.Y - bounds.Min.Y + perLine - 1) / perLine * perLine
+    left, right := focus.Min.X - bounds.Min.X, focus.Max.X - bounds.Min.X
+
+    context := img
+    if height := lines * linePixels(contextMode); height != bounds.Dy() {
+        context = resize(img, bounds.Dx(), height)
+    }
+
+    var b strings.Builder
+    b.WriteString(ce.header())
+
+    for line := 0; line < lines; line++ {
+        y := bounds.Min.Y + line * perLine
+        if y < top || y >= bottom {
+            b.WriteString(ce.lineSegment(context, line, 0, bounds.Dx()))
+        } else {
+            b.WriteString(ce.lineSegment(context, line, 0, left))
+            b.WriteString(fe.lineSegment(img, line, left, right))
+            b.WriteString(ce.lineSegment(context, line, right, bounds.Dx()))
+        }
+
+        b.WriteString(ce.separator)
+    }
+
+    b.WriteString(ce.footer())
+    return b.String(), nil
+}
+
+// modeFormat tells which format a mode writes, modes of the same format can be mixed on a line.
+func modeFormat(mode Mode) Mode {
+    switch mode {
+        case ModeTwoRowSpace, ModeMatte:
+            return ModeTview
+
+        case ModePagerSafe, ModeANSI256, ModeANSI16, ModeGray256:
+            return ModeANSI
+    }
+
+    return mode
+}
+
+// linePixels returns how many rows of pixels a line of output holds in a mode.
+func linePixels(mode Mode) int {
+    if mode == ModeTwoRowSpace {
+        return 1
+    }
+
+    return 2
+}
+
+// lineSegment converts the pixels from column x0 to x1 of a line of output, counted from the left
+// & top of the image, without anything carried over from the rest of the line.
+func (e *Encoder) lineSegment(img image.Image, line, x0, x1 int) string {
+    if x0 >= x1 {
+        return ""
+    }
+
+    bounds := img.Bounds()
+    if e.mode == ModeTwoRowSpace {
+        pixels := make([]color.Color, x1 - x0)
+        for x := range pixels {
+            pixels[x] = img.At(bounds.Min.X + x0 + x, bounds.Min.Y + line)
+        }
+
+        return spaceLine(pixels)
+    }
+
+    cells := rowDecoder(img)(bounds.Min.Y + line * 2)
+    return e.modeRow(line, cells[x0:x1])
+}
@@ -0,0 +1,59 @@
+package pxl
+
+import (
+    "image"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+func TestFocusRender(t *testing.T) {
+    img := TestPattern(6, 8)
+
+    // Rows 3 & 4 of pixels take the second & third lines
+    encoded, err := FocusRender(img, image.Rect(2, 3, 4, 5), ModeTview, ModeTwoRowSpace)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    detailed, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every colour of the pattern differs, so every cell is tagged
+    cell := regexp.MustCompile(`\[[^\]]*\][▀ ]`)
+    split := func(encoded string) (cells [][]string) {
+        for _, line := range strings.Split(strings.TrimSuffix(encoded, "\n"), "\n") {
+            cells = append(cells, cell.FindAllString(line, -1))
+        }
+
+        return
+    }
+
+    got, want := split(encoded), split(detailed)
+    if len(got) != 4 {
+        t.Fatalf("FocusRender() wrote %d lines, want 4: %q", len(got), encoded)
+    }
+
+    for row := range got {
+        if len(got[row]) != 6 {
+            t.Fatalf("line %d of FocusRender() has %d cells, want 6: %q", row, len(got[row]), encoded)
+        }
+
+        for col, c := range got[row] {
+            focused := row >= 1 && row <= 2 && col >= 2 && col <= 3
+            if focused && c != want[row][col] {
+                t.Errorf("focused cell %d, %d = %q, want %q", col, row, c, want[row][col])
+            }
+
+            if !focused && !strings.HasSuffix(c, " ") {
+                t.Errorf("context cell %d, %d = %q, want a space", col, row, c)
+            }
+        }
+    }
+
+    if _, err = FocusRender(img, image.Rect(2, 3, 4, 5), ModeTview, ModeANSI); err == nil {
+        t.Error("FocusRender() combining tview & ANSI output succeeded")
+    }
+}
@@ -0,0 +1,31 @@
+package pxl
+
+import (
+    "image"
+    "io"
+    "sync"
+)
+
+// formats holds the names & magic strings of the formats registered with RegisterFormat().
+var formats = struct {
+    mu        sync.Mutex
+    installed map[[2]string]bool
+}{installed: map[[2]string]bool{}}
+
+// RegisterFormat registers an image format for image.Decode() to use, like image.RegisterFormat() does,
+// but only once for every name & magic string, so plugins which may be loaded more than once, or at the same time,
+// can register their decoders without adding the same format again. It's safe for concurrent use,
+// & returns whether the format was registered, which is false if it already was.
+func RegisterFormat(name, magic string, decode func(io.Reader) (image.Image, error), decodeConfig func(io.Reader) (image.Config, error)) bool {
+    formats.mu.Lock()
+    defer formats.mu.Unlock()
+
+    key := [2]string{name, magic}
+    if formats.installed[key] {
+        return false
+    }
+
+    image.RegisterFormat(name, magic, decode, decodeConfig)
+    formats.installed[key] = true
+    return true
+}
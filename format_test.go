@@ -0,0 +1,49 @@
+package pxl
+
+import (
+    "image"
+    "io"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func TestRegisterFormat(t *testing.T) {
+    // A format whose files are a magic string followed by nothing, for a 2 by 2 image
+    decode := func(io.Reader) (image.Image, error) {
+        return image.NewNRGBA(image.Rect(0, 0, 2, 2)), nil
+    }
+
+    decodeConfig := func(io.Reader) (image.Config, error) {
+        return image.Config{Width: 2, Height: 2}, nil
+    }
+
+    var wg sync.WaitGroup
+    registered := make(chan bool, 8)
+
+    for i := 0; i < cap(registered); i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            registered <- RegisterFormat("pxltest", "PXLTEST!", decode, decodeConfig)
+        }()
+    }
+
+    wg.Wait()
+    close(registered)
+
+    count := 0
+    for ok := range registered {
+        if ok {
+            count++
+        }
+    }
+
+    if count != 1 {
+        t.Errorf("RegisterFormat() registered the same format %d times, want once", count)
+    }
+
+    if _, name, err := image.Decode(strings.NewReader("PXLTEST!")); err != nil || name != "pxltest" {
+        t.Errorf("image.Decode() of the registered format returned %q, %v", name, err)
+    }
+}
@@ -0,0 +1,48 @@
+package pxl
+
+import (
+    "image"
+    "io"
+)
+
+// FrameEncoder writes a stream of frames, like those from a camera, to a terminal as ANSI escape sequences.
+// After the first frame, only the cells which changed since the previous one are redrawn,
+// so the terminal updates smoothly. See Encoder.EncodeDiff() for more details.
+type FrameEncoder struct {
+    w    io.Writer
+    e    *Encoder
+    prev Pixels
+}
+
+// NewFrameEncoder creates a FrameEncoder writing to w, converting every frame with opts.
+func NewFrameEncoder(w io.Writer, opts ...Option) *FrameEncoder {
+    return &FrameEncoder{w: w, e: NewEncoder(opts...)}
+}
+
+// WriteFrame draws a frame, redrawing every cell if it's the first frame
+// or its size differs from the previous one.
+func (f *FrameEncoder) WriteFrame(img image.Image) error {
+    next, err := f.e.Decode(img)
+    if err != nil {
+        return err
+    }
+
+    prev := f.prev
+    if len(prev) != len(next) || (len(prev) > 0 && len(prev[0]) != len(next[0])) {
+        prev = nil
+    }
+
+    // If the write fails, what's on screen is unknown, so the next frame is drawn in full
+    f.prev = nil
+    if _, err = io.WriteString(f.w, f.e.diffANSI(prev, next)); err != nil {
+        return err
+    }
+
+    f.prev = next
+    return nil
+}
+
+// Reset makes the next frame be drawn in full, like after the terminal was cleared.
+func (f *FrameEncoder) Reset() {
+    f.prev = nil
+}
@@ -0,0 +1,48 @@
+package pxl
+
+import (
+    "bytes"
+    "image"
+    "image/color"
+    "image/draw"
+    "testing"
+)
+
+func TestFrameEncoder(t *testing.T) {
+    first := TestPattern(16, 16).(*image.NRGBA)
+    second := image.NewNRGBA(first.Rect)
+    draw.Draw(second, second.Rect, first, image.Point{}, draw.Src)
+    second.SetNRGBA(5, 6, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+
+    var buf bytes.Buffer
+    frames := NewFrameEncoder(&buf)
+
+    // The first frame & any of another size are drawn in full
+    sizes := make([]int, 0, 4)
+    for _, img := range []image.Image{first, second, TestPattern(8, 8), first} {
+        buf.Reset()
+        if err := frames.WriteFrame(img); err != nil {
+            t.Fatal(err)
+        }
+
+        sizes = append(sizes, buf.Len())
+    }
+
+    if sizes[1] >= sizes[0] || sizes[1] >= sizes[3] {
+        t.Errorf("the second frame took %d bytes, the full redraws %d & %d", sizes[1], sizes[0], sizes[3])
+    }
+
+    if sizes[3] != sizes[0] {
+        t.Errorf("the frame after a size change took %d bytes, the first frame %d", sizes[3], sizes[0])
+    }
+
+    buf.Reset()
+    frames.Reset()
+    if err := frames.WriteFrame(second); err != nil {
+        t.Fatal(err)
+    }
+
+    if buf.Len() <= sizes[1] {
+        t.Errorf("the frame after Reset() took %d bytes, no more than a partial one", buf.Len())
+    }
+}
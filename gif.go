@@ -0,0 +1,142 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/json"
+    "image"
+    "image/draw"
+    "image/gif"
+    "time"
+)
+
+// Frame is a single encoded frame of an animation, along with how long it's shown for.
+type Frame struct {
+    Encoded string
+    Delay   time.Duration
+}
+
+// FromGIF converts every frame of an animated GIF to text, see Encoder.EncodeGIF() for more details.
+func FromGIF(g *gif.GIF) (frames []Frame, err error) {
+    return NewEncoder().EncodeGIF(g)
+}
+
+// EncodeGIF converts every frame of an animated GIF to text, as it's shown after being drawn
+// over the previous frames according to their disposal methods.
+// Frames which come out identical to the one before them, which some GIFs use for timing,
+// reuse its text rather than being encoded again, unless WithTemporalDither() dithers each of them differently.
+func (e *Encoder) EncodeGIF(g *gif.GIF) (frames []Frame, err error) {
+    canvas := image.NewRGBA(gifBounds(g))
+    var shown []byte
+    var encoded string
+
+    for i, frame := range g.Image {
+        var disposal byte
+        if i < len(g.Disposal) {
+            disposal = g.Disposal[i]
+        }
+
+        var saved []byte
+        if disposal == gif.DisposalPrevious {
+            saved = append([]byte(nil), canvas.Pix...)
+        }
+
+        draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+        if e.temporalDither {
+            // Steps coprime with the sizes of the matrices go through every phase of them
+            shifted := *e
+            shifted.ditherPhase = image.Pt(i * 3, i * 5)
+            if encoded, err = shifted.Encode(canvas); err != nil {
+                return nil, err
+            }
+        } else if shown == nil || !bytes.Equal(canvas.Pix, shown) {
+            if encoded, err = e.Encode(canvas); err != nil {
+                return nil, err
+            }
+
+            shown = append(shown[:0], canvas.Pix...)
+        }
+
+        var delay time.Duration
+        if i < len(g.Delay) {
+            delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+        }
+
+        frames = append(frames, Frame{encoded, delay})
+
+        switch disposal {
+            case gif.DisposalBackground:
+                draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+
+            case gif.DisposalPrevious:
+                copy(canvas.Pix, saved)
+        }
+    }
+
+    return
+}
+
+// gifBounds returns the logical screen of a GIF, or the area its frames cover if it has none.
+func gifBounds(g *gif.GIF) image.Rectangle {
+    bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+    if bounds.Empty() {
+        for _, frame := range g.Image {
+            bounds = bounds.Union(frame.Bounds())
+        }
+    }
+
+    return bounds
+}
+
+// castHeader is the first line of an asciinema v2 recording.
+type castHeader struct {
+    Version int `json:"version"`
+    Width   int `json:"width"`
+    Height  int `json:"height"`
+}
+
+// ToAsciinema converts an animated GIF to an asciinema v2 recording, so it can be played back
+// in an asciinema player. Every frame is an output event, drawn as true colour ANSI escape sequences
+// from the top-left corner of the terminal at the time the frames before it add up to.
+func ToAsciinema(g *gif.GIF) ([]byte, error) {
+    frames, err := NewEncoder(WithMode(ModeANSI), WithLineSeparator("\r\n")).EncodeGIF(g)
+    if err != nil {
+        return nil, err
+    }
+
+    // The terminal has a line more than the frames, for the cursor to rest on without scrolling them
+    size := gifBounds(g).Size()
+    line, err := json.Marshal(castHeader{2, size.X, size.Y / 2 + 1})
+    if err != nil {
+        return nil, err
+    }
+
+    cast := append(line, '\n')
+    var elapsed time.Duration
+
+    for _, frame := range frames {
+        if line, err = json.Marshal([]interface{}{elapsed.Seconds(), "o", "\x1b[H" + frame.Encoded}); err != nil {
+            return nil, err
+        }
+
+        cast = append(append(cast, line...), '\n')
+        elapsed += frame.Delay
+    }
+
+    return cast, nil
+}
+
+// MergeDuplicates joins consecutive frames with the same text into one,
+// which is shown for as long as all of them together.
+func MergeDuplicates(frames []Frame) (merged []Frame) {
+    for _, frame := range frames {
+        if last := len(merged) - 1; last >= 0 && merged[last].Encoded == frame.Encoded {
+            merged[last].Delay += frame.Delay
+            continue
+        }
+
+        merged = append(merged, frame)
+    }
+
+    return
+}
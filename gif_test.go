@@ -0,0 +1,121 @@
+package pxl
+
+import (
+    "encoding/json"
+    "image"
+    "image/color"
+    "image/gif"
+    "math"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestEncodeGIFReusesIdenticalFrames(t *testing.T) {
+    palette := color.Palette{color.Transparent, color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+    frame := func(index uint8) *image.Paletted {
+        img := image.NewPaletted(image.Rect(0, 0, 2, 2), palette)
+        for i := range img.Pix {
+            img.Pix[i] = index
+        }
+
+        return img
+    }
+
+    // The second frame is transparent, so it leaves the red of the first one as it was
+    g := &gif.GIF{
+        Image:  []*image.Paletted{frame(1), frame(0), frame(2)},
+        Delay:  []int{10, 20, 30},
+        Config: image.Config{Width: 2, Height: 2},
+    }
+
+    encodes := 0
+    frames, err := NewEncoder(WithRowWrapper(func(row int, content string) string {
+        encodes++
+        return content
+    })).EncodeGIF(g)
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if encodes != 2 {
+        t.Errorf("frames were encoded %d times, want 2", encodes)
+    }
+
+    if len(frames) != 3 || frames[1].Encoded != frames[0].Encoded || frames[2].Encoded == frames[1].Encoded {
+        t.Fatalf("EncodeGIF() = %q, want the second frame to be the first one again", frames)
+    }
+
+    merged := MergeDuplicates(frames)
+    if len(merged) != 2 || merged[0].Delay != 300 * time.Millisecond || merged[1].Delay != 300 * time.Millisecond {
+        t.Errorf("MergeDuplicates() = %v, want 2 frames of 300ms", merged)
+    }
+}
+
+func TestToAsciinema(t *testing.T) {
+    palette := color.Palette{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+    g := &gif.GIF{Delay: []int{10, 25, 5}, Config: image.Config{Width: 3, Height: 4}}
+    for i := range g.Delay {
+        img := image.NewPaletted(image.Rect(0, 0, 3, 4), palette)
+        img.Pix[i] = 1
+        g.Image = append(g.Image, img)
+    }
+
+    cast, err := ToAsciinema(g)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(string(cast), "\n"), "\n")
+    if len(lines) != 4 {
+        t.Fatalf("ToAsciinema() wrote %d lines, want a header & 3 events: %q", len(lines), cast)
+    }
+
+    var header castHeader
+    if err = json.Unmarshal([]byte(lines[0]), &header); err != nil || header != (castHeader{2, 3, 3}) {
+        t.Errorf("header = %q, want version 2 & a 3 by 3 terminal", lines[0])
+    }
+
+    // The events start at the times the delays of the frames before them add up to
+    for i, want := range []float64{0, 0.1, 0.35} {
+        var event []interface{}
+        if err = json.Unmarshal([]byte(lines[i + 1]), &event); err != nil || len(event) != 3 {
+            t.Fatalf("event %d = %q, want [time, \"o\", data]", i, lines[i + 1])
+        }
+
+        data, _ := event[2].(string)
+        if at, _ := event[0].(float64); math.Abs(at - want) > 1e-9 || event[1] != "o" || !strings.HasPrefix(data, "\x1b[H\x1b[") {
+            t.Errorf("event %d = %q, want output at %gs from the top-left corner", i, lines[i + 1], want)
+        }
+    }
+}
+
+func TestWithTemporalDither(t *testing.T) {
+    // The same grey gradient in every frame
+    var palette color.Palette
+    for i := 0; i < 16; i++ {
+        palette = append(palette, color.Gray{uint8(i * 0x11)})
+    }
+
+    frame := image.NewPaletted(image.Rect(0, 0, 16, 8), palette)
+    for i := range frame.Pix {
+        frame.Pix[i] = uint8(i % 16)
+    }
+
+    g := &gif.GIF{Image: []*image.Paletted{frame, frame, frame}, Delay: []int{10, 10, 10}, Config: image.Config{Width: 16, Height: 8}}
+
+    // Temporal dithering shifts the pattern from frame to frame, so no two in a row are the same
+    for _, temporal := range []bool{false, true} {
+        frames, err := NewEncoder(WithMaxColors(4), WithDither(DitherBayer4), WithTemporalDither(temporal)).EncodeGIF(g)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for i := 1; i < len(frames); i++ {
+            if same := frames[i].Encoded == frames[i - 1].Encoded; same == temporal {
+                t.Errorf("temporal %t: frame %d is the same as the one before it: %t", temporal, i, same)
+            }
+        }
+    }
+}
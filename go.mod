@@ -2,4 +2,19 @@ module github.com/abdfnx/pxl
 
 go 1.17
 
-require github.com/pkg/errors v0.9.1
+require (
+	github.com/gdamore/tcell/v2 v2.5.4
+	github.com/mattn/go-runewidth v0.0.14
+	github.com/pkg/errors v0.9.1
+	github.com/rivo/tview v0.0.0-20220307222120-9994674d60a8
+	golang.org/x/image v0.5.0
+)
+
+require (
+	github.com/gdamore/encoding v1.0.0 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
+	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
+	golang.org/x/text v0.7.0 // indirect
+)
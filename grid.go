@@ -0,0 +1,210 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+
+    "github.com/pkg/errors"
+)
+
+// ToneMap is the way values of a float grid are compressed after being scaled to the range of the gradient.
+type ToneMap int
+
+const (
+    // ToneLinear leaves the scaled values as they are, it's the default.
+    ToneLinear ToneMap = iota
+
+    // ToneReinhard applies the global operator of Reinhard et al., relative to the log-average of the values,
+    // which compresses the highest values most while keeping the ordinary ones apart.
+    ToneReinhard
+
+    // ToneLog maps values onto the logarithm of how many times the smallest value above min they are,
+    // so equal ratios take equal steps along the gradient, like the decibels of a spectrogram.
+    ToneLog
+)
+
+// toneKey is the value the log-average of a grid is mapped to, the middle grey of Reinhard et al.
+const toneKey = 0.18
+
+// WithToneMap compresses the range of the values EncodeFloatGrid() maps onto a gradient, once they're scaled from min to max,
+// so a few extreme values don't squeeze all the others into the same colour. The default is ToneLinear.
+// It also compresses the brightness of images whose colours go past white, like HDR renders with colour types
+// returning channels above 0xffff, so the highlights keep their detail rather than all clipping to white.
+// The brightest pixel is mapped to white. Images within the usual range are left as they are by every operator,
+// & those of the standard library's types, which can't go past it, aren't even scanned for their brightest pixel.
+func WithToneMap(operator ToneMap) Option {
+    return func(e *Encoder) {
+        e.toneMap = operator
+    }
+}
+
+// toneMappedImage compresses the brightness of an image to fit between black & white, keeping the hue of every pixel.
+type toneMappedImage struct {
+    image.Image
+    operator ToneMap
+    white    float64
+}
+
+// toneMapImage returns an image compressed by operator, measuring the brightest pixel once for all of them,
+// or the image itself if none of its pixels are brighter than white.
+func toneMapImage(img image.Image, operator ToneMap) image.Image {
+    switch img.(type) {
+        case *image.NRGBA, *image.RGBA, *image.NRGBA64, *image.RGBA64, *image.Gray, *image.Gray16,
+            *image.YCbCr, *image.NYCbCrA, *image.CMYK, *image.Alpha, *image.Alpha16:
+            return img
+    }
+
+    bounds := img.Bounds()
+    white := 1.0
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            white = math.Max(white, hdrLuminance(img.At(x, y)))
+        }
+    }
+
+    if white <= 1 {
+        return img
+    }
+
+    return toneMappedImage{img, operator, white}
+}
+
+// hdrLuminance is like luminance() but doesn't clamp channels past 0xffff.
+func hdrLuminance(c color.Color) float64 {
+    r, g, b, _ := c.RGBA()
+    return (0.299 * float64(r) + 0.587 * float64(g) + 0.114 * float64(b)) / 0xffff
+}
+
+func (img toneMappedImage) At(x, y int) color.Color {
+    c := img.Image.At(x, y)
+    r, g, b, a := c.RGBA()
+    if a > 0xffff {
+        a = 0xffff
+    }
+
+    l := hdrLuminance(c)
+    if l == 0 {
+        return color.RGBA64{0, 0, 0, uint16(a)}
+    }
+
+    // The extended operator of Reinhard et al. maps white onto 1 rather than infinity
+    mapped := l * (1 + l / (img.white * img.white)) / (1 + l)
+    if img.operator == ToneLog {
+        mapped = math.Log1p(l) / math.Log1p(img.white)
+    }
+
+    scale := func(v uint32) uint16 {
+        return uint16(math.Min(math.Round(float64(v) * mapped / l), float64(a)))
+    }
+
+    return color.RGBA64{scale(r), scale(g), scale(b), uint16(a)}
+}
+
+// FromFloatGrid converts a grid of values, like a depth map, to text formatted for tview,
+// see Encoder.EncodeFloatGrid() for more details.
+func FromFloatGrid(grid [][]float64, min, max float64, gradient []color.Color) (encoded string, err error) {
+    return NewEncoder().EncodeFloatGrid(grid, min, max, gradient)
+}
+
+// EncodeFloatGrid converts a grid of values, like a depth map, to text. Every value is scaled from the range min to max
+// onto the gradient, values outside of it take the colour of the nearest end. NaN values are transparent.
+// The grid is indexed as grid[y][x] & every row must be just as long, its height must be even,
+// like the height of images given to FromImage().
+func (e *Encoder) EncodeFloatGrid(grid [][]float64, min, max float64, gradient []color.Color) (encoded string, err error) {
+    if len(gradient) == 0 {
+        err = errors.New("pixelview: Can't map values onto an empty gradient")
+        return
+    }
+
+    if !(max > min) {
+        err = errors.New("pixelview: Can't map values from an empty range")
+        return
+    }
+
+    var width int
+    if len(grid) > 0 {
+        width = len(grid[0])
+    }
+
+    for _, row := range grid {
+        if len(row) != width {
+            err = errors.New("pixelview: Can't process a grid with rows of different lengths")
+            return
+        }
+    }
+
+    tone := toneMapper(e.toneMap, grid, min, max)
+
+    img := image.NewRGBA64(image.Rect(0, 0, width, len(grid)))
+    for y, row := range grid {
+        for x, v := range row {
+            if math.IsNaN(v) {
+                continue
+            }
+
+            img.Set(x, y, sampleGradient(gradient, tone((v - min) / (max - min))))
+        }
+    }
+
+    return e.Encode(img)
+}
+
+// toneMapper returns the function an operator compresses values scaled from min to max with.
+// Values are clamped to the range first, since the ends of the gradient stand for anything beyond it.
+func toneMapper(operator ToneMap, grid [][]float64, min, max float64) func(t float64) float64 {
+    clamp := func(t float64) float64 {
+        return math.Max(0, math.Min(1, t))
+    }
+
+    if operator == ToneLinear {
+        return func(t float64) float64 {
+            return t
+        }
+    }
+
+    // The log-average needs an offset for values of 0
+    const delta = 1e-6
+
+    var sum float64
+    var count int
+    smallest := math.Inf(1)
+
+    for _, row := range grid {
+        for _, v := range row {
+            if math.IsNaN(v) {
+                continue
+            }
+
+            t := clamp((v - min) / (max - min))
+            sum += math.Log(delta + t)
+            count++
+
+            if t > 0 && t < smallest {
+                smallest = t
+            }
+        }
+    }
+
+    // Values are counted in units which put the top of the range at scale,
+    // it's mapped back onto the last colour of the gradient
+    scale := 1.0
+    switch {
+        case operator == ToneLog && !math.IsInf(smallest, 1):
+            scale = 1 / smallest
+
+        case operator == ToneReinhard && count > 0:
+            scale = toneKey / math.Exp(sum / float64(count))
+    }
+
+    return func(t float64) float64 {
+        l := clamp(t) * scale
+
+        if operator == ToneLog {
+            return math.Log1p(l) / math.Log1p(scale)
+        }
+
+        return l / (1 + l) * (1 + scale) / scale
+    }
+}
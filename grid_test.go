@@ -0,0 +1,140 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+    "regexp"
+    "strconv"
+    "testing"
+)
+
+// hdrColor is a colour whose channels may go past 0xffff, like those of an HDR render.
+type hdrColor struct{ r, g, b uint32 }
+
+func (c hdrColor) RGBA() (r, g, b, a uint32) {
+    return c.r, c.g, c.b, 0xffff
+}
+
+// hdrImage is an image of hdrColor pixels, each w pixels wide row ramping up to peak times white.
+type hdrImage struct {
+    w, h int
+    peak float64
+}
+
+func (img hdrImage) ColorModel() color.Model { return color.RGBA64Model }
+func (img hdrImage) Bounds() image.Rectangle { return image.Rect(0, 0, img.w, img.h) }
+
+func (img hdrImage) At(x, y int) color.Color {
+    v := uint32(float64(x + 1) / float64(img.w) * img.peak * 0xffff)
+    return hdrColor{v, v, v}
+}
+
+func TestWithToneMapLeavesOrdinaryImages(t *testing.T) {
+    for _, img := range []image.Image{TestPattern(8, 4), hdrImage{8, 4, 1}} {
+        want, err := NewEncoder().Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for _, operator := range []ToneMap{ToneReinhard, ToneLog} {
+            got, err := NewEncoder(WithToneMap(operator)).Encode(img)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if got != want {
+                t.Errorf("ToneMap %d changed an image within white: %q, want %q", operator, got, want)
+            }
+        }
+    }
+}
+
+func TestWithToneMapCompressesHDR(t *testing.T) {
+    img := hdrImage{8, 2, 4}
+
+    for _, operator := range []ToneMap{ToneReinhard, ToneLog} {
+        mapped := toneMapImage(img, operator)
+
+        // The brightest pixel maps to white & the rest stay apart rather than clipping
+        prev := -1.0
+        for x := 0; x < 8; x++ {
+            l := luminance(mapped.At(x, 0))
+            if l <= prev {
+                t.Errorf("ToneMap %d: pixel %d is no brighter than the one before it", operator, x)
+            }
+
+            prev = l
+        }
+
+        if r, _, _, _ := mapped.At(7, 0).RGBA(); r != 0xffff {
+            t.Errorf("ToneMap %d: brightest pixel maps to %#x, want white", operator, r)
+        }
+    }
+}
+
+func TestFromFloatGrid(t *testing.T) {
+    grid := [][]float64{{-5, 0, 5, 10, 20}, {-5, 0, 5, 10, math.NaN()}}
+    gradient := []color.Color{color.RGBA{0, 0, 0xff, 0xff}, color.RGBA{0xff, 0, 0, 0xff}}
+
+    encoded, err := FromFloatGrid(grid, 0, 10, gradient)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The ends of the range take the ends of the gradient, as do values beyond them, NaN is transparent
+    if want := "[#0000ff:#0000ff]▀▀[#800080:#800080]▀[#ff0000:#ff0000]▀[:-]▀\n"; encoded != want {
+        t.Errorf("FromFloatGrid() = %q, want %q", encoded, want)
+    }
+
+    if _, err = FromFloatGrid([][]float64{{0, 1}, {0}}, 0, 1, gradient); err == nil {
+        t.Error("FromFloatGrid() of rows of different lengths succeeded")
+    }
+}
+
+func TestEncodeFloatGridToneMap(t *testing.T) {
+    // A single outlier, 3 orders of magnitude above the rest
+    grid := [][]float64{{0, 1, 10, 1000}, {0, 1, 10, 1000}}
+    gradient := []color.Color{color.Black, color.White}
+
+    linear, err := NewEncoder().EncodeFloatGrid(grid, 0, 1000, gradient)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Linearly 1 & 10 are all but black
+    if want := "[#000000:#000000]▀▀[#020202:#020202]▀[#ffffff:#ffffff]▀\n"; linear != want {
+        t.Errorf("EncodeFloatGrid() = %q, want %q", linear, want)
+    }
+
+    tag := regexp.MustCompile(`\[#([0-9a-f]{2})[0-9a-f]{4}:[^\]]*\]▀`)
+    for _, operator := range []ToneMap{ToneReinhard, ToneLog} {
+        mapped, err := NewEncoder(WithToneMap(operator)).EncodeFloatGrid(grid, 0, 1000, gradient)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // Every value gets a grey of its own, the outlier still white & the others visibly apart from black
+        tags := tag.FindAllStringSubmatch(mapped, -1)
+        if len(tags) != 4 {
+            t.Fatalf("ToneMap %d: EncodeFloatGrid() = %q, want 4 greys", operator, mapped)
+        }
+
+        levels := make([]int64, len(tags))
+        for i, m := range tags {
+            levels[i], _ = strconv.ParseInt(m[1], 16, 0)
+        }
+
+        if levels[0] != 0 || levels[1] < 0x10 || levels[2] < levels[1] + 0x20 || levels[3] != 0xff {
+            t.Errorf("ToneMap %d: EncodeFloatGrid() = %q, want 1 & 10 well apart between black & white", operator, mapped)
+        }
+    }
+
+    if _, err = FromFloatGrid(grid, 1, 1, gradient); err == nil {
+        t.Error("FromFloatGrid() of an empty range succeeded")
+    }
+
+    if _, err = FromFloatGrid(grid, 0, 1, nil); err == nil {
+        t.Error("FromFloatGrid() onto an empty gradient succeeded")
+    }
+}
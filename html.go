@@ -0,0 +1,44 @@
+package pxl
+
+import (
+    "html"
+    "strings"
+)
+
+const (
+    htmlOpen  = `<pre style="line-height:1">`
+    htmlClose = "</pre>"
+)
+
+// htmlRow emits every run of identical cells as a single styled <span>, cells being identical
+// when their colours are written the same way, like Encode() takes them to be.
+func (e *Encoder) htmlRow(row int, cells []Cell) string {
+    var b strings.Builder
+    var run []Cell
+
+    flush := func() {
+        if len(run) > 0 {
+            b.WriteString(`<span style="color:` + ColorString(run[0].Fg, e.notation))
+            b.WriteString(`;background-color:` + ColorString(run[0].Bg, e.notation) + `">`)
+            b.WriteString(strings.Repeat(html.EscapeString(e.cellGlyph()), len(run)) + "</span>")
+            run = run[:0]
+        }
+    }
+
+    for col, cell := range cells {
+        s, ok := e.substitute(col, row, cell)
+        if ok || (len(run) > 0 && !(SameCell(cell.Fg, run[0].Fg) && SameCell(cell.Bg, run[0].Bg))) {
+            flush()
+        }
+
+        if ok {
+            b.WriteString(s)
+            continue
+        }
+
+        run = append(run, cell)
+    }
+
+    flush()
+    return b.String()
+}
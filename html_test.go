@@ -0,0 +1,57 @@
+package pxl
+
+import (
+    "image/color"
+    "testing"
+)
+
+func TestColorString(t *testing.T) {
+    c := color.NRGBA{0x12, 0xab, 0xff, 0xff}
+
+    if got := ColorString(c, NotationHex); got != "#12abff" {
+        t.Errorf("ColorString(NotationHex) = %q, want #12abff", got)
+    }
+
+    if got := ColorString(c, NotationRGB); got != "rgb(18,171,255)" {
+        t.Errorf("ColorString(NotationRGB) = %q, want rgb(18,171,255)", got)
+    }
+}
+
+func TestModeHTMLNotation(t *testing.T) {
+    img := solid(2, 2, color.NRGBA{0x12, 0xab, 0xff, 0xff})
+    img.Set(1, 1, color.RGBA{0x12, 0xab, 0xfe, 0xff})
+
+    hex, err := NewEncoder(WithMode(ModeHTML)).Encode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := htmlOpen + `<span style="color:#12abff;background-color:#12abff">▀</span>` +
+        `<span style="color:#12abff;background-color:#12abfe">▀</span>` + "\n" + htmlClose
+    if hex != want {
+        t.Errorf("hex HTML = %q, want %q", hex, want)
+    }
+
+    rgb, err := NewEncoder(WithMode(ModeHTML), WithColorNotation(NotationRGB)).Encode(solid(2, 2, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want = htmlOpen + `<span style="color:rgb(255,255,255);background-color:rgb(255,255,255)">▀▀</span>` + "\n" + htmlClose
+    if rgb != want {
+        t.Errorf("rgb HTML = %q, want %q", rgb, want)
+    }
+}
+
+func TestModeHTMLRunsOfMixedTypes(t *testing.T) {
+    encoded, err := NewEncoder(WithMode(ModeHTML)).Encode(mixedTypes())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := htmlOpen + `<span style="color:#ff0000;background-color:#000000">▀▀▀</span>` +
+        `<span style="color:#ffffff;background-color:#000000">▀</span>` + "\n" + htmlClose
+    if encoded != want {
+        t.Errorf("HTML = %q, want %q", encoded, want)
+    }
+}
@@ -0,0 +1,77 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/binary"
+
+    "github.com/pkg/errors"
+)
+
+// ErrColorProfile is returned along with the output by an Encoder created with WithIgnoreColorProfile(false),
+// when the image read has an embedded ICC colour profile. The output is still valid, but its colours
+// may differ from those a browser or image viewer which applies the profile would show.
+var ErrColorProfile = errors.New("pixelview: Image has an embedded colour profile which isn't applied")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// hasColorProfile tells whether the data of a JPEG or PNG file embeds an ICC colour profile,
+// in an APP2 segment or an iCCP chunk respectively. Other formats are never reported to have one.
+func hasColorProfile(data []byte) bool {
+    switch {
+        case bytes.HasPrefix(data, []byte{0xff, markerSOI}):
+            return jpegColorProfile(data)
+
+        case bytes.HasPrefix(data, pngSignature):
+            return pngColorProfile(data)
+    }
+
+    return false
+}
+
+// jpegColorProfile looks for an ICC profile in the segments in front of the first scan.
+func jpegColorProfile(data []byte) bool {
+    const markerAPP2 = 0xe2
+
+    for pos := 2; pos + 4 <= len(data) && data[pos] == 0xff; {
+        marker := data[pos + 1]
+        if marker == markerSOS || marker == markerEOI {
+            return false
+        }
+
+        end := pos + 2 + int(binary.BigEndian.Uint16(data[pos + 2:]))
+        if end > len(data) {
+            return false
+        }
+
+        if marker == markerAPP2 && bytes.HasPrefix(data[pos + 4:end], []byte("ICC_PROFILE\x00")) {
+            return true
+        }
+
+        pos = end
+    }
+
+    return false
+}
+
+// pngColorProfile looks for an iCCP chunk in front of the image data.
+func pngColorProfile(data []byte) bool {
+    for pos := len(pngSignature); pos + 8 <= len(data); {
+        length, kind := int(binary.BigEndian.Uint32(data[pos:])), string(data[pos + 4:pos + 8])
+
+        switch kind {
+            case "iCCP":
+                return true
+
+            case "IDAT":
+                return false
+        }
+
+        // The type & CRC take 8 bytes along with the data
+        pos += length + 12
+        if length < 0 || pos < 0 {
+            return false
+        }
+    }
+
+    return false
+}
@@ -0,0 +1,69 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/binary"
+    "hash/crc32"
+    "image/jpeg"
+    "image/png"
+    "testing"
+)
+
+// withProfile embeds a dummy ICC profile in a PNG or JPEG, as an iCCP chunk after the header
+// or an APP2 segment after the start of image marker.
+func withProfile(data []byte) []byte {
+    var chunk bytes.Buffer
+
+    if bytes.HasPrefix(data, pngSignature) {
+        content := []byte("iCCPsRGB\x00\x00\x78\x9c\x03\x00\x00\x00\x00\x01")
+        binary.Write(&chunk, binary.BigEndian, uint32(len(content) - 4))
+        chunk.Write(content)
+        binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(content))
+
+        // The signature & the IHDR chunk take 33 bytes
+        return append(append(append([]byte{}, data[:33]...), chunk.Bytes()...), data[33:]...)
+    }
+
+    content := []byte("ICC_PROFILE\x00\x01\x01")
+    chunk.Write([]byte{0xff, 0xe2})
+    binary.Write(&chunk, binary.BigEndian, uint16(len(content) + 2))
+    chunk.Write(content)
+
+    return append(append(append([]byte{}, data[:2]...), chunk.Bytes()...), data[2:]...)
+}
+
+func TestWithIgnoreColorProfile(t *testing.T) {
+    img := TestPattern(8, 8)
+
+    var pngData, jpegData bytes.Buffer
+    if err := png.Encode(&pngData, img); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, data := range [][]byte{pngData.Bytes(), jpegData.Bytes()} {
+        profiled := withProfile(data)
+        plain, err := NewEncoder().EncodeReader(bytes.NewReader(data))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // By default the profile is ignored without a word
+        encoded, err := NewEncoder().EncodeReader(bytes.NewReader(profiled))
+        if err != nil || encoded != plain {
+            t.Errorf("EncodeReader() of a profiled image returned %v, output the same as without a profile: %t", err, encoded == plain)
+        }
+
+        warning := NewEncoder(WithIgnoreColorProfile(false))
+        if encoded, err = warning.EncodeReader(bytes.NewReader(profiled)); err != ErrColorProfile || encoded != plain {
+            t.Errorf("warning EncodeReader() of a profiled image returned %v, output the same: %t, want ErrColorProfile", err, encoded == plain)
+        }
+
+        if _, err = warning.EncodeReader(bytes.NewReader(data)); err != nil {
+            t.Errorf("warning EncodeReader() of an image without a profile returned %v", err)
+        }
+    }
+}
@@ -0,0 +1,149 @@
+package pxl
+
+import (
+    "image"
+    "math"
+)
+
+// RenderInteractive converts an image like FromImage() does, along with a function mapping the cell at col & row
+// of the output back to the pixel of the image it shows, for interactive tools. See Encoder.EncodeInteractive().
+func RenderInteractive(img image.Image) (text string, mapper func(col, row int) (imgX, imgY int), err error) {
+    return NewEncoder().EncodeInteractive(img)
+}
+
+// EncodeInteractive converts an image to text, along with a function mapping the cell at col & row of the output,
+// counted from its top-left corner, back to the pixel of the image it shows. Cells show two pixels,
+// the one above the other, & the top one is returned, the bottom one is the pixel below it.
+// The mapping accounts for the options which move, repeat or scale pixels, like WithPixelStride(), WithMaxRows(),
+// WithColumnScale() & WithScanOrder(), as well as the border & box around the image.
+// Cells outside of the image, or padding which isn't taken from it, map to -1, -1.
+func (e *Encoder) EncodeInteractive(img image.Image) (text string, mapper func(col, row int) (imgX, imgY int), err error) {
+    if text, err = e.Encode(img); err != nil {
+        return "", nil, err
+    }
+
+    bounds := img.Bounds()
+    w, h := bounds.Dx(), bounds.Dy()
+
+    // Every step of prepare() which moves pixels maps those of its result back onto the ones it took them from,
+    // the steps are undone last to first
+    var steps []func(x, y int) (int, int)
+
+    if e.stride > 1 {
+        n, offset := e.stride, e.stride / 2
+        if e.sampling == SampleTruncate {
+            offset = 0
+        }
+
+        srcW, srcH := w, h
+        steps = append(steps, func(x, y int) (int, int) {
+            col := x * n + offset
+            if col >= srcW {
+                col = srcW - 1
+            }
+
+            pair := y / 2 * n + offset
+            if last := (srcH + 1) / 2 - 1; pair > last {
+                pair = last
+            }
+
+            return col, pair * 2 + y % 2
+        })
+
+        size := strided{img, n, offset}.Bounds().Size()
+        w, h = size.X, size.Y
+    }
+
+    if e.mirror != nil {
+        srcH, rows := h, e.mirror.rows
+        if rows > h {
+            rows = h
+        }
+
+        steps = append(steps, func(x, y int) (int, int) {
+            if y >= srcH {
+                y = srcH - 1 - (y - srcH)
+            }
+
+            return x, y
+        })
+
+        h += rows
+    }
+
+    if e.maxRows > 0 && h > e.maxRows * 2 {
+        srcH, scaledH := h, e.maxRows * 2
+        steps = append(steps, func(x, y int) (int, int) {
+            return x, int(math.Floor((float64(y) + 0.5) * float64(srcH) / float64(scaledH)))
+        })
+
+        h = scaledH
+    }
+
+    if h % 2 != 0 {
+        srcH := h
+        if e.fill == EdgeCrop {
+            h--
+        } else {
+            h++
+            fill := e.fill
+            steps = append(steps, func(x, y int) (int, int) {
+                if y < srcH {
+                    return x, y
+                }
+
+                if fill == EdgePad {
+                    return -1, -1
+                }
+
+                return x, edgeIndex(y, 0, srcH, fill)
+            })
+        }
+    }
+
+    width, lines := e.imageCells(image.Pt(w, h))
+    left, _ := padding(width, e.box.X)
+    top, _ := padding(lines, e.box.Y)
+    if e.border != nil {
+        left++
+        top++
+    }
+
+    mapper = func(col, row int) (imgX, imgY int) {
+        col, row = col - left, row - top
+        if col < 0 || col >= width || row < 0 || row >= lines {
+            return -1, -1
+        }
+
+        // Rows are reversed before they're clipped to WithMaxCols()
+        scale := e.colScale
+        if scale < 1 {
+            scale = 1
+        }
+
+        if e.scanX == Backward {
+            col = w * scale - 1 - col
+        }
+
+        x := col / scale
+
+        var y int
+        if e.mode == ModeTwoRowSpace {
+            y = scanIndex(row / 2, lines / 2, e.scanY) * 2 + row % 2
+        } else {
+            y = scanIndex(row, lines, e.scanY) * 2
+        }
+
+        for i := len(steps) - 1; i >= 0 && x >= 0; i-- {
+            x, y = steps[i](x, y)
+        }
+
+        if x < 0 {
+            return -1, -1
+        }
+
+        return bounds.Min.X + x, bounds.Min.Y + y
+    }
+
+    return
+}
@@ -0,0 +1,68 @@
+package pxl
+
+import (
+    "image"
+    "image/draw"
+    "testing"
+)
+
+func TestRenderInteractive(t *testing.T) {
+    // At an offset, which the mapped pixels are in
+    img := image.NewNRGBA(image.Rect(3, -4, 11, 4))
+    draw.Draw(img, img.Rect, TestPattern(8, 8), image.Point{}, draw.Src)
+
+    text, mapper, err := RenderInteractive(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); text != want {
+        t.Errorf("RenderInteractive() = %q, want %q", text, want)
+    }
+
+    for _, cell := range []image.Point{{0, 0}, {5, 2}, {7, 3}} {
+        if x, y := mapper(cell.X, cell.Y); x != 3 + cell.X || y != -4 + cell.Y * 2 {
+            t.Errorf("cell %v maps to (%d, %d), want (%d, %d)", cell, x, y, 3 + cell.X, -4 + cell.Y * 2)
+        }
+    }
+
+    for _, cell := range []image.Point{{8, 0}, {0, 4}, {-1, 0}} {
+        if x, y := mapper(cell.X, cell.Y); x != -1 || y != -1 {
+            t.Errorf("cell %v outside of the image maps to (%d, %d), want (-1, -1)", cell, x, y)
+        }
+    }
+
+    // Every cell scaled down by half shows the pixels it maps to, the bottom one under the top one
+    text, mapper, err = NewEncoder(WithPixelStride(2)).EncodeInteractive(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    decoded, err := ToImage(text)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if size := decoded.Bounds().Size(); size != image.Pt(4, 4) {
+        t.Fatalf("scaled down output is %v pixels, want 4 by 4", size)
+    }
+
+    for row := 0; row < 2; row++ {
+        for col := 0; col < 4; col++ {
+            x, y := mapper(col, row)
+            if !SameCell(decoded.At(col, row * 2), img.At(x, y)) || !SameCell(decoded.At(col, row * 2 + 1), img.At(x, y + 1)) {
+                t.Errorf("cell (%d, %d) maps to (%d, %d), which it doesn't show", col, row, x, y)
+            }
+        }
+    }
+
+    // Resized rows map to the middle of the rows they're averaged from
+    _, mapper, err = NewEncoder(WithMaxRows(2)).EncodeInteractive(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if x, y := mapper(6, 1); x != 9 || y != 1 {
+        t.Errorf("cell (6, 1) resized to half the height maps to (%d, %d), want (9, 1)", x, y)
+    }
+}
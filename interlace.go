@@ -0,0 +1,217 @@
+package pxl
+
+import (
+    "bytes"
+    "compress/zlib"
+    "encoding/binary"
+    "hash/crc32"
+    "image"
+    "image/color"
+    "image/png"
+    "io"
+    "io/ioutil"
+)
+
+// adam7 holds the offsets & steps of the 7 passes of an Adam7 interlaced PNG, along with how many pixels
+// wide & tall the blocks are which every known pixel stands for once the pass is decoded.
+var adam7 = [7]struct {
+    x, y, dx, dy int
+    block        image.Point
+}{
+    {0, 0, 8, 8, image.Pt(8, 8)},
+    {4, 0, 8, 8, image.Pt(4, 8)},
+    {0, 4, 4, 8, image.Pt(4, 4)},
+    {2, 0, 4, 4, image.Pt(2, 4)},
+    {0, 2, 2, 4, image.Pt(2, 2)},
+    {1, 0, 2, 2, image.Pt(1, 2)},
+    {0, 1, 1, 2, image.Pt(1, 1)},
+}
+
+// interlaceScanner walks the chunks of a PNG as its data arrives, to find out how many passes of an interlaced one
+// can be decompressed so far.
+type interlaceScanner struct {
+    pos    int
+    done   bool
+    passes int
+
+    width, height, bitsPerPixel int
+
+    // The chunks before the image data, which the previews are built with, & the compressed image data
+    header, compressed []byte
+
+    // How much of the compressed data was decompressed last time, & how much data that gave
+    inflatedFrom, inflated int
+}
+
+// advance parses as much of buf as it can, returning a preview for every pass which was completed.
+// It gives up on anything which isn't an interlaced PNG.
+func (s *interlaceScanner) advance(buf []byte) (previews []image.Image) {
+    if s.pos == 0 && !s.done {
+        if len(buf) < len(pngSignature) {
+            return
+        }
+
+        if !bytes.HasPrefix(buf, pngSignature) {
+            s.done = true
+            return
+        }
+
+        s.pos = len(pngSignature)
+    }
+
+    grew := false
+    for !s.done && s.pos + 8 <= len(buf) {
+        length := int(binary.BigEndian.Uint32(buf[s.pos:]))
+        end := s.pos + 12 + length
+        if length < 0 || end > len(buf) || end < s.pos {
+            break
+        }
+
+        kind, data := string(buf[s.pos + 4:s.pos + 8]), buf[s.pos + 8:s.pos + 8 + length]
+        switch kind {
+            case "IHDR":
+                if !s.readHeader(data) {
+                    s.done = true
+                }
+
+            case "IDAT":
+                s.compressed = append(s.compressed, data...)
+                grew = true
+
+            case "IEND":
+                s.done = true
+        }
+
+        if kind != "IDAT" && len(s.compressed) == 0 {
+            s.header = append(s.header, buf[s.pos:end]...)
+        }
+
+        s.pos = end
+    }
+
+    if grew {
+        if preview := s.preview(); preview != nil {
+            previews = append(previews, preview)
+        }
+    }
+
+    return
+}
+
+// readHeader reads the size & pixel format of an IHDR chunk, returning false if the image isn't interlaced.
+func (s *interlaceScanner) readHeader(data []byte) bool {
+    if len(data) != 13 || data[12] != 1 {
+        return false
+    }
+
+    channels := map[byte]int{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}[data[9]]
+    s.width, s.height = int(binary.BigEndian.Uint32(data)), int(binary.BigEndian.Uint32(data[4:]))
+    s.bitsPerPixel = channels * int(data[8])
+    return channels > 0 && s.width > 0 && s.height > 0
+}
+
+// passSize returns how many bytes of decompressed data a pass takes, each row starting with its filter type.
+func (s *interlaceScanner) passSize(pass int) int {
+    p := adam7[pass]
+    cols, rows := (s.width - p.x + p.dx - 1) / p.dx, (s.height - p.y + p.dy - 1) / p.dy
+    if cols <= 0 || rows <= 0 {
+        return 0
+    }
+
+    return rows * (1 + (cols * s.bitsPerPixel + 7) / 8)
+}
+
+// preview decodes the passes completed so far into an image, if there are more of them than last time
+// but not all of them, which are left to the full decode.
+func (s *interlaceScanner) preview() image.Image {
+    // The data is decompressed from the start every time, so to keep that from taking quadratic time
+    // it waits for a quarter more data than last time, or as much as the next pass takes at the ratio so far
+    next := s.inflatedFrom * 5 / 4
+    if s.inflated > 0 {
+        var size int
+        for pass := 0; pass <= s.passes && pass < len(adam7); pass++ {
+            size += s.passSize(pass)
+        }
+
+        if estimate := int(int64(s.inflatedFrom) * int64(size) / int64(s.inflated)); estimate > next {
+            next = estimate
+        }
+    }
+
+    if len(s.compressed) < next {
+        return nil
+    }
+
+    r, err := zlib.NewReader(bytes.NewReader(s.compressed))
+    if err != nil {
+        return nil
+    }
+
+    // Truncated data decompresses as far as it goes before the error
+    inflated, _ := ioutil.ReadAll(r)
+    s.inflatedFrom, s.inflated = len(s.compressed), len(inflated)
+
+    passes, size := 0, 0
+    for passes < len(adam7) && size + s.passSize(passes) <= len(inflated) {
+        size += s.passSize(passes)
+        passes++
+    }
+
+    if passes <= s.passes || passes == len(adam7) {
+        return nil
+    }
+
+    s.passes = passes
+
+    // The passes which haven't arrived are filled with zeros, which are rows without a filter
+    total := size
+    for pass := passes; pass < len(adam7); pass++ {
+        total += s.passSize(pass)
+    }
+
+    raw := append(inflated[:size:size], make([]byte, total - size)...)
+
+    var data bytes.Buffer
+    w := zlib.NewWriter(&data)
+    w.Write(raw)
+    w.Close()
+
+    var file bytes.Buffer
+    file.Write(pngSignature)
+    file.Write(s.header)
+    writeChunk(&file, "IDAT", data.Bytes())
+    writeChunk(&file, "IEND", nil)
+
+    img, err := png.Decode(&file)
+    if err != nil {
+        return nil
+    }
+
+    return blockImage{img, adam7[passes - 1].block}
+}
+
+// writeChunk writes a PNG chunk along with its length & checksum.
+func writeChunk(w io.Writer, kind string, data []byte) {
+    var length [4]byte
+    binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+    w.Write(length[:])
+
+    chunk := append([]byte(kind), data...)
+    w.Write(chunk)
+
+    var sum [4]byte
+    binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(chunk))
+    w.Write(sum[:])
+}
+
+// blockImage shows every block of an image in the colour of its top-left pixel,
+// which is the one the first passes of an interlaced PNG hold.
+type blockImage struct {
+    image.Image
+    block image.Point
+}
+
+func (img blockImage) At(x, y int) color.Color {
+    min := img.Bounds().Min
+    return img.Image.At(x - (x - min.X) % img.block.X, y - (y - min.Y) % img.block.Y)
+}
@@ -0,0 +1,80 @@
+package pxl
+
+import (
+    "encoding/json"
+    "image"
+)
+
+// jsonRun is a run of identical cells, the colours are written as #rrggbb like ColorHex() does,
+// or as - for fully transparent ones, like tags do.
+type jsonRun struct {
+    Fg    string `json:"fg"`
+    Bg    string `json:"bg"`
+    Count int    `json:"count"`
+}
+
+// ToJSON converts an image to JSON for renderers which aren't terminals, like a canvas.
+// The output holds an array for every row of cells, each made of the runs of identical cells in the row
+// in the form {"fg":"#rrggbb","bg":"#rrggbb","count":n}. Fully transparent colours are written as -,
+// for the renderer to leave to its own background, like tview does for tags.
+func ToJSON(img image.Image) (encoded []byte, err error) {
+    pixels, err := DecodeToPixels(img)
+    if err != nil {
+        return
+    }
+
+    rows := make([][]jsonRun, len(pixels))
+    for y, cells := range pixels {
+        rows[y] = cellRuns(cells)
+    }
+
+    return json.Marshal(rows)
+}
+
+// Patch is a run of identical cells for a terminal emulator to draw, like xterm.js in a browser,
+// without parsing escape sequences. Count cells starting at column Col of row Row are drawn as ▀
+// in the colours Fg & Bg, written as #rrggbb like ColorHex() does, or - for the default colour
+// where they're fully transparent.
+type Patch struct {
+    Row   int    `json:"row"`
+    Col   int    `json:"col"`
+    Fg    string `json:"fg"`
+    Bg    string `json:"bg"`
+    Count int    `json:"count"`
+}
+
+// ToTerminalPatches converts an image to the patches which draw it, made of the same runs of cells ToJSON() writes,
+// from left to right & top to bottom.
+func ToTerminalPatches(img image.Image) (patches []Patch, err error) {
+    pixels, err := DecodeToPixels(img)
+    if err != nil {
+        return
+    }
+
+    for y, cells := range pixels {
+        col := 0
+        for _, run := range cellRuns(cells) {
+            patches = append(patches, Patch{y, col, run.Fg, run.Bg, run.Count})
+            col += run.Count
+        }
+    }
+
+    return
+}
+
+// cellRuns splits a row of cells into runs of identical cells, which are those whose colours
+// are written the same way, like Encode() takes them to be.
+func cellRuns(cells []Cell) (runs []jsonRun) {
+    runs = []jsonRun{}
+
+    for x, cell := range cells {
+        if x > 0 && SameCell(cell.Fg, cells[x - 1].Fg) && SameCell(cell.Bg, cells[x - 1].Bg) {
+            runs[len(runs) - 1].Count++
+            continue
+        }
+
+        runs = append(runs, jsonRun{tagColor(cell.Fg), tagColor(cell.Bg), 1})
+    }
+
+    return
+}
@@ -0,0 +1,117 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+// mixedTypes returns a 4 by 2 image whose columns hold the same colour as different colour types,
+// then a column of another colour.
+func mixedTypes() image.Image {
+    img := image.NewRGBA64(image.Rect(0, 0, 4, 2))
+    img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+    img.Set(1, 0, color.RGBA{255, 0, 0, 255})
+    img.Set(2, 0, color.RGBA64{0xffff, 0, 0, 0xffff})
+    img.Set(3, 0, color.White)
+
+    for x := 0; x < 4; x++ {
+        img.Set(x, 1, color.Black)
+    }
+
+    return typedImage{img}
+}
+
+// typedImage returns the colours of an image as the types set on it, rather than converting them
+// to the colour model of the image.
+type typedImage struct {
+    *image.RGBA64
+}
+
+func (img typedImage) At(x, y int) color.Color {
+    switch x {
+        case 0:
+            return color.NRGBAModel.Convert(img.RGBA64.At(x, y))
+
+        case 1:
+            return color.RGBAModel.Convert(img.RGBA64.At(x, y))
+    }
+
+    return img.RGBA64.At(x, y)
+}
+
+func TestToJSON(t *testing.T) {
+    encoded, err := ToJSON(mixedTypes())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := `[[{"fg":"#ff0000","bg":"#000000","count":3},{"fg":"#ffffff","bg":"#000000","count":1}]]`
+    if string(encoded) != want {
+        t.Errorf("ToJSON() = %s, want %s", encoded, want)
+    }
+
+    if _, err = ToJSON(solid(2, 3, color.White)); err != ErrOddHeight {
+        t.Errorf("ToJSON() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestToTerminalPatches(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 4))
+    for i := range img.Pix {
+        img.Pix[i] = 0xff
+    }
+
+    img.Set(2, 2, color.Black)
+
+    patches, err := ToTerminalPatches(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The flat row is a single run, the other is split where its colours change
+    want := []Patch{
+        {0, 0, "#ffffff", "#ffffff", 3},
+        {1, 0, "#ffffff", "#ffffff", 2},
+        {1, 2, "#000000", "#ffffff", 1},
+    }
+
+    if len(patches) != len(want) || patches[0] != want[0] || patches[1] != want[1] || patches[2] != want[2] {
+        t.Errorf("ToTerminalPatches() = %v, want %v", patches, want)
+    }
+
+    mixed, err := ToTerminalPatches(mixedTypes())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(mixed) != 2 || mixed[0].Count != 3 {
+        t.Errorf("equal colours of different types split a patch: %v", mixed)
+    }
+}
+
+func TestToJSONTransparency(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 1, color.NRGBA{0x12, 0x34, 0x56, 0})
+
+    encoded, err := ToJSON(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Both bottom pixels are transparent, whatever their colour channels hold, so they're a single run
+    if want := `[[{"fg":"#ff0000","bg":"-","count":2}]]`; string(encoded) != want {
+        t.Errorf("ToJSON() = %s, want %s", encoded, want)
+    }
+
+    patches, err := ToTerminalPatches(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(patches) != 1 || patches[0] != (Patch{0, 0, "#ff0000", "-", 2}) {
+        t.Errorf("ToTerminalPatches() = %v, want a single patch on the default background", patches)
+    }
+}
@@ -0,0 +1,653 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "math"
+    "regexp"
+    "strings"
+
+    "github.com/mattn/go-runewidth"
+    "github.com/pkg/errors"
+)
+
+// tagPattern matches text tview would take for a colour or region tag, the same way tview.Escape() does.
+var tagPattern = regexp.MustCompile(`(\[[a-zA-Z0-9_,;: \-\."#]+\[*)\]`)
+
+// escapePattern matches the ANSI escape sequences the ANSI modes write: SGR & other CSI sequences, & OSC 8 hyperlinks.
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]|\x1b\\][^\x07]*\x07")
+
+// Paginate converts an image like FromImage() does, but splits the output into pages
+// of at most rowsPerPage rows each, so they can be shown in a fixed viewport without re-encoding.
+// Every page starts on an even row of pixels, the last page may be shorter than the others.
+func Paginate(img image.Image, rowsPerPage int) (pages []string, err error) {
+    if rowsPerPage < 1 {
+        err = errors.New("pixelview: Can't paginate with less than one row per page")
+        return
+    }
+
+    pixels, err := DecodeToPixels(img)
+    if err != nil {
+        return
+    }
+
+    for start := 0; start < len(pixels); start += rowsPerPage {
+        end := start + rowsPerPage
+        if end > len(pixels) {
+            end = len(pixels)
+        }
+
+        pages = append(pages, pixels[start:end].Encode())
+    }
+
+    return
+}
+
+// Rendered is an encoded image along with the number of rows of output it takes,
+// for scrolling through an image taller than the viewport it's shown in.
+type Rendered struct {
+    Encoded string
+    Rows    int
+}
+
+// FromImageScrollable scales an image to cols pixels wide, keeping its aspect ratio, & converts it like FromImage() does.
+// It's meant for images much taller than they're wide, like screenshots of whole webpages, which are better
+// scrolled through than shrunk to fit. The height is rounded to an even number of pixels.
+func FromImageScrollable(img image.Image, cols int) (rendered Rendered, err error) {
+    if cols < 1 {
+        err = errors.New("pixelview: Can't fit image to less than one column")
+        return
+    }
+
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 {
+        return
+    }
+
+    height := fitHeight(size, cols)
+    rendered.Encoded, err = FromImage(resize(img, cols, height))
+    rendered.Rows = height / 2
+    return
+}
+
+// ForTableCell scales an image to cellCols pixels wide, keeping its aspect ratio, & converts it like FromImage() does,
+// for showing thumbnails in the cells of a tview.Table. The output has no trailing line separator,
+// as a cell holds a single piece of content, & width is how many columns wide it is, which is always cellCols.
+func ForTableCell(img image.Image, cellCols int) (text string, width int, err error) {
+    if cellCols < 1 {
+        return "", 0, errors.New("pixelview: Can't fit image to less than one column")
+    }
+
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 {
+        return "", 0, errors.New("pixelview: Can't fit empty image to a table cell")
+    }
+
+    if text, err = FromImage(resize(img, cellCols, fitHeight(size, cellCols))); err != nil {
+        return "", 0, err
+    }
+
+    return strings.TrimSuffix(text, "\n"), cellCols, nil
+}
+
+// RenderPair converts an image at two widths, like a thumbnail & the full view of a gallery, scaling each
+// like FromImageScrollable() does. The image is only scaled down from its own size once, to the larger width,
+// & the smaller one is scaled from that, which reads far fewer pixels for large photos.
+func RenderPair(img image.Image, thumbCols, fullCols int) (thumb, full string, err error) {
+    if thumbCols < 1 || fullCols < 1 {
+        return "", "", errors.New("pixelview: Can't fit image to less than one column")
+    }
+
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 {
+        return "", "", errors.New("pixelview: Can't fit empty image to columns")
+    }
+
+    small, large := thumbCols, fullCols
+    if small > large {
+        small, large = large, small
+    }
+
+    larger := resize(img, large, fitHeight(size, large))
+    smaller := larger
+    if small != large {
+        smaller = resize(larger, small, fitHeight(size, small))
+    }
+
+    if thumb, err = FromImage(smaller); err != nil {
+        return "", "", err
+    }
+
+    if full, err = FromImage(larger); err != nil {
+        return "", "", err
+    }
+
+    if thumbCols > fullCols {
+        thumb, full = full, thumb
+    }
+
+    return
+}
+
+// Tile repeats a small image over cols by rows cells, like FromImage() converts it, for patterned backdrops.
+// The tiles start at the top-left cell, those along the right & bottom edges are cut where they don't fit.
+// Images with an uneven height tile seamlessly too, as the rows of pixels run on across cells.
+func Tile(img image.Image, cols, rows int) (encoded string, err error) {
+    if cols < 1 || rows < 1 {
+        return "", errors.New("pixelview: Can't tile image over less than one cell")
+    }
+
+    if img.Bounds().Empty() {
+        return "", errors.New("pixelview: Can't tile an empty image")
+    }
+
+    return FromImage(tiled{img, cols, rows * 2})
+}
+
+// FitBytes converts an image like FromImage() does, scaled down as little as needed for the output to take
+// at most maxBytes bytes, for sending to clients with a cap on the size of a frame. The aspect ratio
+// is kept & the height rounded to an even number of pixels. Sizes which EstimateSize() puts over the budget
+// are skipped without being encoded, so as the estimate is close but not exact, the result may be a little smaller
+// than the largest size which would have fit. It's an error if not even a single cell fits.
+func FitBytes(img image.Image, maxBytes int) (encoded string, err error) {
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 {
+        return FromImage(img)
+    }
+
+    rate, tagBytes := sampleTags(img)
+    for width := size.X; width >= 1; {
+        height := fitHeight(size, width)
+
+        over := sizeEstimate(size, rate, tagBytes, width)
+        if over <= maxBytes {
+            scaled := img
+            if width != size.X || height != size.Y {
+                scaled = resize(img, width, height)
+            }
+
+            if encoded, err = FromImage(scaled); err != nil || len(encoded) <= maxBytes {
+                return
+            }
+
+            over = len(encoded)
+        }
+
+        // Shrinks by as much as the output is over the budget, which grows with the number of cells
+        next := int(float64(width) * math.Sqrt(float64(maxBytes) / float64(over)))
+        if next >= width {
+            next = width - 1
+        }
+
+        width = next
+    }
+
+    return "", errors.Errorf("pixelview: Can't fit image in %d bytes", maxBytes)
+}
+
+// EstimateSize estimates how many bytes the output of FromImage() takes for an image scaled to width pixels wide,
+// keeping its aspect ratio, without scaling or encoding it. Like SizeForTagBudget(), it looks at how often
+// neighbouring cells change colour, & how long their tags are, in a sample of rows, so the busier parts
+// of an image it misses make it come out low. Empty images & widths below one pixel take no bytes.
+func EstimateSize(img image.Image, width int) int {
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 || width < 1 {
+        return 0
+    }
+
+    rate, tagBytes := sampleTags(img)
+    return sizeEstimate(size, rate, tagBytes, width)
+}
+
+// sizeEstimate estimates the bytes of output of an image of the given size scaled to width pixels wide,
+// from the rate at which its cells change colour & the average bytes of the tags written when they do.
+func sizeEstimate(size image.Point, rate, tagBytes float64, width int) int {
+    height := fitHeight(size, width)
+
+    // Every row starts with a tag of both colours, & the others come from changes along it
+    first := float64(len("[#000000:#000000]"))
+    tags := float64(height / 2) * (first + rate * float64(width - 1) * tagBytes)
+    return minBytes(width, height) + int(math.Round(tags))
+}
+
+// SizeForTagBudget returns the size to scale an image to, keeping its aspect ratio, for its output to hold at most
+// about maxTags colour tags, which bounds how long a terminal takes to redraw it better than a limit on pixels does
+// for busy images. The tags are estimated from how often neighbouring cells change colour in a sample of rows,
+// so the result is an estimate too. The height is rounded to an even number of pixels, images which already fit
+// keep their width. Empty images return a size of zero.
+func SizeForTagBudget(img image.Image, maxTags int) (w, h int) {
+    size := img.Bounds().Size()
+    if size.X <= 0 || size.Y <= 0 {
+        return 0, 0
+    }
+
+    rate := changeRate(img)
+
+    // Every row starts with a tag, & the others come from changes along it
+    tags := func(width int) float64 {
+        return float64(fitHeight(size, width) / 2) * (1 + rate * float64(width - 1))
+    }
+
+    w = size.X
+    if tags(w) > float64(maxTags) {
+        // Tags grow with the square of the scale for busy images, & linearly for flat ones
+        a, b := rate * float64(size.X) * float64(size.Y) / 2, (1 - rate) * float64(size.Y) / 2
+        scale := float64(maxTags) / b
+        if a > 0 {
+            scale = (math.Sqrt(b * b + 4 * a * float64(maxTags)) - b) / (2 * a)
+        }
+
+        w = int(math.Min(float64(size.X), math.Max(1, math.Floor(scale * float64(size.X)))))
+        for w > 1 && tags(w) > float64(maxTags) {
+            w--
+        }
+    }
+
+    return w, fitHeight(size, w)
+}
+
+// tagSampleRows is how many rows of cells changeRate() looks at.
+const tagSampleRows = 64
+
+// changeRate returns how often a cell has other colours than the one to its left, from 0 to 1,
+// in up to tagSampleRows rows of cells spread evenly over the image.
+func changeRate(img image.Image) float64 {
+    rate, _ := sampleTags(img)
+    return rate
+}
+
+// sampleTags returns how often a cell has other colours than the one to its left, like changeRate() does,
+// along with the average bytes of the tags Encode() writes for those changes.
+func sampleTags(img image.Image) (rate, tagBytes float64) {
+    bounds := img.Bounds()
+    decode := rowDecoder(img)
+
+    rows := bounds.Dy() / 2
+    step := 1
+    if rows > tagSampleRows {
+        step = rows / tagSampleRows
+    }
+
+    var changes, pairs, bytes int
+    for row := 0; row < rows; row += step {
+        cells := decode(bounds.Min.Y + row * 2)
+        if len(cells) == 0 {
+            continue
+        }
+
+        prevfg, prevbg := cells[0].Fg, cells[0].Bg
+        for x := 1; x < len(cells); x++ {
+            if tag := len(Encode(cells[x].Fg, cells[x].Bg, &prevfg, &prevbg)) - len("▀"); tag > 0 {
+                changes++
+                bytes += tag
+            }
+        }
+
+        pairs += len(cells) - 1
+    }
+
+    if pairs <= 0 || changes == 0 {
+        return 0, 0
+    }
+
+    return float64(changes) / float64(pairs), float64(bytes) / float64(changes)
+}
+
+// fitHeight returns the height of an image of the given size scaled to width pixels wide,
+// rounded to an even number of pixels.
+func fitHeight(size image.Point, width int) int {
+    height := int(math.Round(float64(size.Y) * float64(width) / float64(size.X) / 2)) * 2
+    if height < 2 {
+        height = 2
+    }
+
+    return height
+}
+
+// minBytes returns the fewest bytes the output of an image of width by height pixels can take:
+// the glyph of every cell & the line separator of every row.
+func minBytes(width, height int) int {
+    return height / 2 * (width * len("▀") + len("\n"))
+}
+
+// Window returns up to rows rows of the output starting with the row top, for showing in a viewport.
+// Each row is self-contained, so any of them can be shown without the ones before it.
+func (r Rendered) Window(top, rows int) string {
+    lines := strings.SplitAfter(r.Encoded, "\n")
+    if len(lines) > r.Rows {
+        lines = lines[:r.Rows]
+    }
+
+    if top < 0 {
+        top = 0
+    }
+
+    if top > len(lines) {
+        top = len(lines)
+    }
+
+    end := top + rows
+    if end > len(lines) || rows < 0 {
+        end = len(lines)
+    }
+
+    return strings.Join(lines[top:end], "")
+}
+
+// FromImageWithCaption converts an image like FromImage() does, followed by a line
+// with the caption centered below it. The caption is cut short if it's wider than the image,
+// & escaped so tview prints any text in square brackets as is, rather than taking it for a tag.
+func FromImageWithCaption(img image.Image, caption string) (encoded string, err error) {
+    encoded, err = FromImage(img)
+    if err != nil {
+        return
+    }
+
+    return encoded + captionLine(caption, img.Bounds().Dx()) + "\n", nil
+}
+
+// captionLine centers the caption in a line of the given width, in the default colours.
+// Wide characters, like those of CJK text & emoji, take two columns of it.
+func captionLine(caption string, width int) string {
+    text, size := cutText(caption, width)
+    left := (width - size) / 2
+    right := width - size - left
+    return "[-:-]" + strings.Repeat(" ", left) + Escape(text) + strings.Repeat(" ", right)
+}
+
+// DimWithOverlay converts an image like FromImage() does, darkened by dim from 0 for not at all to 1 for black,
+// with the lines of overlay, like a loading indicator, written in white in the middle of it. Each line is centered
+// over the cells it covers, with both halves of all of them averaged as its background, cut short if it's wider
+// than the image, & escaped like captions are.
+func DimWithOverlay(img image.Image, dim float64, overlay string) (encoded string, err error) {
+    pixels, err := DecodeToPixels(img)
+    if err != nil {
+        return
+    }
+
+    factor := 1 - math.Max(0, math.Min(1, dim))
+    for _, cells := range pixels {
+        for x, cell := range cells {
+            cells[x] = Cell{darken(cell.Fg, factor), darken(cell.Bg, factor)}
+        }
+    }
+
+    lines := strings.Split(overlay, "\n")
+    top := (len(pixels) - len(lines)) / 2
+    width := img.Bounds().Dx()
+
+    // The first cell of a line holds all of its text, the others it covers are left out
+    pixelFunc := func(x, y int, _, _ color.Color) (string, bool) {
+        if y < top || y >= top + len(lines) {
+            return "", false
+        }
+
+        text, size := cutText(lines[y - top], width)
+        left := (width - size) / 2
+        switch {
+            case x < left || x >= left + size:
+                return "", false
+
+            case x > left:
+                return "", true
+        }
+
+        var sum [4]uint64
+        for _, cell := range pixels[y][left:left + size] {
+            for _, c := range []color.Color{cell.Fg, cell.Bg} {
+                r, g, b, a := c.RGBA()
+                sum[0], sum[1], sum[2], sum[3] = sum[0] + uint64(r), sum[1] + uint64(g), sum[2] + uint64(b), sum[3] + uint64(a)
+            }
+        }
+
+        n := uint64(size * 2)
+        average := func(v uint64) uint16 {
+            return uint16((v + n / 2) / n)
+        }
+
+        background := color.RGBA64{average(sum[0]), average(sum[1]), average(sum[2]), average(sum[3])}
+        return "[#ffffff:" + tagColor(background) + "]" + Escape(text), true
+    }
+
+    return NewEncoder(WithPixelFunc(pixelFunc)).EncodePixels(pixels), nil
+}
+
+// PaletteSwatch returns a legend of the colours of a palette for tview, on a single line ending with a newline.
+// Every colour is a solid cell followed by its label, or by its hex value when labels has none for it.
+// Labels are escaped like captions are.
+func PaletteSwatch(palette color.Palette, labels []string) string {
+    var b strings.Builder
+
+    for i, c := range palette {
+        if i > 0 {
+            b.WriteString("  ")
+        }
+
+        label := ColorHex(c)
+        if i < len(labels) {
+            label = labels[i]
+        }
+
+        hex := ColorHex(c)
+        b.WriteString("[" + hex + ":" + hex + "]▀[-:-] " + Escape(label))
+    }
+
+    b.WriteString("\n")
+    return b.String()
+}
+
+// ColorGrid lays out the colours of a palette for tview as solid cells, cols to a row from left to right
+// & top to bottom, for picking a colour from in a user interface. The last row is cut short
+// when the palette doesn't fill it. Less than one column counts as one.
+func ColorGrid(palette color.Palette, cols int) string {
+    if cols < 1 {
+        cols = 1
+    }
+
+    var pixels Pixels
+    for i, c := range palette {
+        if i % cols == 0 {
+            pixels = append(pixels, nil)
+        }
+
+        pixels[len(pixels) - 1] = append(pixels[len(pixels) - 1], Cell{c, c})
+    }
+
+    return pixels.Encode()
+}
+
+// Escape makes tview print text as is, even if parts of it look like tags,
+// by adding a [ before the closing bracket of anything tag-like, just like tview.Escape().
+func Escape(text string) string {
+    return tagPattern.ReplaceAllString(text, "$1[]")
+}
+
+// StackVertical joins encoded images into a single block, one below the other.
+// Lines narrower than the widest one are padded with spaces in the default colours,
+// so the blocks line up on the left & the right. Every line ends with a newline, like FromImage() output.
+func StackVertical(blocks ...string) string {
+    var lines []string
+    var width int
+
+    for _, block := range blocks {
+        if block == "" {
+            continue
+        }
+
+        for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+            if w := textWidth(line); w > width {
+                width = w
+            }
+
+            lines = append(lines, line)
+        }
+    }
+
+    var b strings.Builder
+    for _, line := range lines {
+        b.WriteString(line)
+        if pad := width - textWidth(line); pad > 0 {
+            b.WriteString("[-:-]" + strings.Repeat(" ", pad))
+        }
+
+        b.WriteString("\n")
+    }
+
+    return b.String()
+}
+
+// textWidth counts the cells tview would print a line of text in, leaving out tags
+// & counting escaped brackets as one, & wide characters as two like tview does.
+func textWidth(line string) int {
+    printed := tagPattern.ReplaceAllStringFunc(line, func(tag string) string {
+        if strings.HasSuffix(tag, "[]") {
+            return tag[:len(tag) - 2] + "]"
+        }
+
+        return ""
+    })
+
+    return runewidth.StringWidth(printed)
+}
+
+// cutText cuts text short to fit in width columns, returning it with the columns it takes,
+// which are counted like textWidth() does, so wide characters take two.
+func cutText(text string, width int) (string, int) {
+    text = runewidth.Truncate(text, width, "")
+    return text, runewidth.StringWidth(text)
+}
+
+// VisualWidth counts the columns a line of output takes on screen, like the glyphs of its cells,
+// leaving out what isn't printed: tview tags, ANSI escape sequences & the byte order mark.
+// Wide characters, like those of CJK text & emoji, count as two columns.
+// It's meant for lining up captions & borders with encoded images, where len() counts far too many.
+func VisualWidth(line string) int {
+    line = strings.ReplaceAll(escapePattern.ReplaceAllString(line, ""), "\ufeff", "")
+    return textWidth(line)
+}
+
+// MaxPixels returns the size of the largest image that fits in cols by rows terminal cells,
+// since every cell holds a column of two pixels. Negative sizes count as zero.
+func MaxPixels(cols, rows int) (w, h int) {
+    if cols < 0 {
+        cols = 0
+    }
+
+    if rows < 0 {
+        rows = 0
+    }
+
+    return cols, rows * 2
+}
+
+// reflect returns the image with up to rows of pixels from the bottom of it mirrored below it,
+// their opacity falling linearly by fade from the first mirrored row to the last.
+func reflect(img image.Image, rows int, fade float64) image.Image {
+    bounds := img.Bounds()
+    if rows > bounds.Dy() {
+        rows = bounds.Dy()
+    }
+
+    reflected := image.NewRGBA64(image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y + rows))
+    draw.Draw(reflected, bounds, img, bounds.Min, draw.Src)
+
+    for i := 0; i < rows; i++ {
+        opacity := 1 - fade * float64(i + 1) / float64(rows)
+
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            reflected.Set(x, bounds.Max.Y + i, darkenAlpha(img.At(x, bounds.Max.Y - 1 - i), opacity))
+        }
+    }
+
+    return reflected
+}
+
+// tiled repeats an image over a larger area starting at the origin, sampling it modulo its size.
+type tiled struct {
+    image.Image
+    width, height int
+}
+
+func (t tiled) Bounds() image.Rectangle {
+    return image.Rect(0, 0, t.width, t.height)
+}
+
+func (t tiled) At(x, y int) color.Color {
+    bounds := t.Image.Bounds()
+    return t.Image.At(bounds.Min.X + x % bounds.Dx(), bounds.Min.Y + y % bounds.Dy())
+}
+
+// strided is an image made of every nth column & every nth pair of rows of another,
+// the ones offset from the start of every n. Its height is uneven if the height of the other image is.
+type strided struct {
+    image.Image
+    n, offset int
+}
+
+func (s strided) Bounds() image.Rectangle {
+    size := s.Image.Bounds().Size()
+    pairs := (size.Y + 1) / 2
+
+    width := (size.X + s.n - 1) / s.n
+    height := (pairs + s.n - 1) / s.n * 2 - size.Y % 2
+    return image.Rect(0, 0, width, height)
+}
+
+func (s strided) At(x, y int) color.Color {
+    bounds := s.Image.Bounds()
+
+    // The last columns & pairs of rows may not reach the offset
+    col := x * s.n + s.offset
+    if col >= bounds.Dx() {
+        col = bounds.Dx() - 1
+    }
+
+    pair := y / 2 * s.n + s.offset
+    if last := (bounds.Dy() + 1) / 2 - 1; pair > last {
+        pair = last
+    }
+
+    return s.Image.At(bounds.Min.X + col, bounds.Min.Y + pair * 2 + y % 2)
+}
+
+// resize scales an image to width by height pixels. Every pixel of the result is the average
+// of the pixels it covers, weighted by how much of each it covers, so detail isn't skipped when scaling down.
+func resize(img image.Image, width, height int) image.Image {
+    bounds := img.Bounds()
+    resized := image.NewRGBA64(image.Rect(0, 0, width, height))
+    scaleX, scaleY := float64(bounds.Dx()) / float64(width), float64(bounds.Dy()) / float64(height)
+
+    for y := 0; y < height; y++ {
+        top, bottom := float64(y) * scaleY, float64(y + 1) * scaleY
+
+        for x := 0; x < width; x++ {
+            left, right := float64(x) * scaleX, float64(x + 1) * scaleX
+            var sum [4]float64
+
+            for sy := int(top); float64(sy) < bottom && sy < bounds.Dy(); sy++ {
+                weightY := math.Min(bottom, float64(sy + 1)) - math.Max(top, float64(sy))
+
+                for sx := int(left); float64(sx) < right && sx < bounds.Dx(); sx++ {
+                    weight := weightY * (math.Min(right, float64(sx + 1)) - math.Max(left, float64(sx)))
+                    r, g, b, a := img.At(bounds.Min.X + sx, bounds.Min.Y + sy).RGBA()
+
+                    sum[0] += float64(r) * weight
+                    sum[1] += float64(g) * weight
+                    sum[2] += float64(b) * weight
+                    sum[3] += float64(a) * weight
+                }
+            }
+
+            area := scaleX * scaleY
+            resized.SetRGBA64(x, y, color.RGBA64{
+                uint16(math.Round(sum[0] / area)),
+                uint16(math.Round(sum[1] / area)),
+                uint16(math.Round(sum[2] / area)),
+                uint16(math.Round(sum[3] / area)),
+            })
+        }
+    }
+
+    return resized
+}
@@ -0,0 +1,511 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+func TestFitBytes(t *testing.T) {
+    img := TestPattern(64, 64)
+
+    var prev int
+    for _, budget := range []int{2000, 8000, 32000} {
+        encoded, err := FitBytes(img, budget)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(encoded) > budget {
+            t.Errorf("FitBytes(%d) wrote %d bytes", budget, len(encoded))
+        }
+
+        width := VisualWidth(strings.SplitN(encoded, "\n", 2)[0])
+        if width <= prev {
+            t.Errorf("FitBytes(%d) is %d cells wide, no wider than for a smaller budget", budget, width)
+        }
+
+        prev = width
+    }
+
+    full, _ := FromImage(img)
+    if encoded, err := FitBytes(img, len(full)); err != nil || encoded != full {
+        t.Errorf("FitBytes() scaled down an image which fits already")
+    }
+
+    if _, err := FitBytes(img, 3); err == nil {
+        t.Error("FitBytes() fit an image in less than a cell")
+    }
+}
+
+func TestEstimateSize(t *testing.T) {
+    images := map[string]image.Image{
+        "pattern": TestPattern(48, 32),
+        "solid":   solid(48, 32, color.White),
+        "noise":   noise(48, 32, 3),
+    }
+
+    for name, img := range images {
+        encoded, err := FromImage(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        estimate := EstimateSize(img, 48)
+        if d := float64(estimate - len(encoded)) / float64(len(encoded)); d < -0.1 || d > 0.1 {
+            t.Errorf("%s: EstimateSize() = %d, output takes %d bytes", name, estimate, len(encoded))
+        }
+    }
+
+    if EstimateSize(TestPattern(8, 8), 0) != 0 {
+        t.Error("EstimateSize() of no width isn't zero")
+    }
+}
+
+func TestFromImageWithCaptionWideText(t *testing.T) {
+    tests := []struct {
+        caption string
+        width   int
+        want    string
+    }{
+        {"cat", 7, "[-:-]  cat  "},
+        {"[red]", 7, "[-:-] [red[] "},
+        {"日本語", 8, "[-:-] 日本語 "},
+        {"日本語", 5, "[-:-]日本 "},
+        {"🙂 ok", 4, "[-:-]🙂 o"},
+    }
+
+    for _, test := range tests {
+        img := solid(test.width, 2, color.White)
+
+        encoded, err := FromImageWithCaption(img, test.caption)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+        if lines[1] != test.want {
+            t.Errorf("caption %q below %d columns = %q, want %q", test.caption, test.width, lines[1], test.want)
+        }
+
+        if w := VisualWidth(lines[1]); w != test.width {
+            t.Errorf("caption %q takes %d columns, want %d", test.caption, w, test.width)
+        }
+    }
+}
+
+func TestDimWithOverlayWideText(t *testing.T) {
+    encoded, err := DimWithOverlay(solid(9, 6, color.White), 0.5, "読み込み中\nloading...")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if len(lines) != 3 {
+        t.Fatalf("DimWithOverlay() wrote %d lines, want 3", len(lines))
+    }
+
+    for i, line := range lines {
+        if w := VisualWidth(line); w != 9 {
+            t.Errorf("line %d, %q, takes %d columns, want 9", i, line, w)
+        }
+    }
+
+    if !strings.Contains(lines[0], "読み込み") || strings.Contains(lines[0], "中") {
+        t.Errorf("wide overlay line wasn't cut to fit: %q", lines[0])
+    }
+
+    if !strings.HasPrefix(lines[2], "[#808080:#808080]▀▀▀▀▀▀▀▀▀") {
+        t.Errorf("line below the overlay isn't dimmed: %q", lines[2])
+    }
+}
+
+func TestPaginate(t *testing.T) {
+    img := TestPattern(5, 40)
+
+    pages, err := Paginate(img, 6)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(pages) != 4 {
+        t.Fatalf("got %d pages, want 4", len(pages))
+    }
+
+    for i, want := range []int{6, 6, 6, 2} {
+        if rows := strings.Count(pages[i], "\n"); rows != want {
+            t.Errorf("page %d has %d rows, want %d", i, rows, want)
+        }
+    }
+
+    whole, _ := FromImage(img)
+    if joined := strings.Join(pages, ""); joined != whole {
+        t.Errorf("pages joined = %q, FromImage() = %q", joined, whole)
+    }
+
+    if _, err = Paginate(img, 0); err == nil {
+        t.Error("Paginate() with no rows a page succeeded")
+    }
+}
+
+func TestMaxPixels(t *testing.T) {
+    tests := []struct {
+        cols, rows int
+        w, h       int
+    }{
+        {80, 24, 80, 48},
+        {1, 1, 1, 2},
+        {0, 5, 0, 10},
+        {-3, -1, 0, 0},
+    }
+
+    for _, test := range tests {
+        if w, h := MaxPixels(test.cols, test.rows); w != test.w || h != test.h {
+            t.Errorf("MaxPixels(%d, %d) = %d, %d, want %d, %d", test.cols, test.rows, w, h, test.w, test.h)
+        }
+    }
+}
+
+func TestStackVertical(t *testing.T) {
+    narrow, _ := FromImage(TestPattern(4, 2))
+    wide, _ := FromImage(TestPattern(6, 4))
+
+    stacked := StackVertical(narrow, "", wide)
+    lines := strings.Split(strings.TrimSuffix(stacked, "\n"), "\n")
+    if len(lines) != 3 {
+        t.Fatalf("StackVertical() wrote %d lines, want 3: %q", len(lines), stacked)
+    }
+
+    // The tags of the narrow block don't count towards its width
+    if want := strings.TrimSuffix(narrow, "\n") + "[-:-]  "; lines[0] != want {
+        t.Errorf("narrow line = %q, want %q", lines[0], want)
+    }
+
+    for i, line := range lines {
+        if w := VisualWidth(line); w != 6 {
+            t.Errorf("line %d is %d wide, want 6", i, w)
+        }
+    }
+
+    if !strings.HasSuffix(stacked, wide) {
+        t.Errorf("the widest block is changed: %q", stacked)
+    }
+}
+
+func TestWithReflection(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 4))
+    for x := 0; x < 2; x++ {
+        img.Set(x, 0, color.NRGBA{0xff, 0, 0, 0xff})
+        img.Set(x, 1, color.NRGBA{0, 0xff, 0, 0xff})
+        img.Set(x, 2, color.NRGBA{0, 0, 0xff, 0xff})
+        img.Set(x, 3, color.White)
+    }
+
+    pixels, err := NewEncoder(WithReflection(2, 1), WithThemeBackground(color.Black)).Decode(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(pixels) != 4 {
+        t.Fatalf("got %d rows of cells, want the 2 of the image & 2 of its reflection", len(pixels))
+    }
+
+    // From the bottom of the image up, fading a quarter more every row until only the background is left
+    want := []string{"#bfbfbf", "#000080", "#004000", "#000000"}
+    for i, hex := range want {
+        cell := pixels[2 + i / 2][0]
+        c := cell.Fg
+        if i % 2 == 1 {
+            c = cell.Bg
+        }
+
+        if got := ColorHex(c); got != hex {
+            t.Errorf("row %d of the reflection is %s, want %s", i, got, hex)
+        }
+    }
+}
+
+func TestWithPixelStride(t *testing.T) {
+    img := TestPattern(8, 8).(*image.NRGBA)
+    full, _ := DecodeToPixels(img)
+
+    for sampling, offset := range map[Sampling]int{SampleCenter: 1, SampleTruncate: 0} {
+        pixels, err := NewEncoder(WithPixelStride(2), WithSampleRounding(sampling)).Decode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(pixels) != 2 || len(pixels[0]) != 4 {
+            t.Fatalf("got %d rows of %d cells, want 2 rows of 4", len(pixels), len(pixels[0]))
+        }
+
+        // Each cell is one of the full rendering, every other column & row of it
+        for row := range pixels {
+            for col, cell := range pixels[row] {
+                if want := full[row * 2 + offset][col * 2 + offset]; !SameCell(cell.Fg, want.Fg) || !SameCell(cell.Bg, want.Bg) {
+                    t.Errorf("sampling %d: cell %d, %d is %v, want %v", sampling, col, row, cell, want)
+                }
+            }
+        }
+    }
+}
+
+func TestPaletteSwatch(t *testing.T) {
+    palette := color.Palette{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0x80, 0, 0xff}, color.RGBA{0x12, 0x34, 0x56, 0xff}}
+    swatch := PaletteSwatch(palette, []string{"red", "[green]"})
+
+    // The last colour has no label, so it's labelled with its hex value
+    want := "[#ff0000:#ff0000]▀[-:-] red  [#008000:#008000]▀[-:-] [green[]  [#123456:#123456]▀[-:-] #123456\n"
+    if swatch != want {
+        t.Errorf("PaletteSwatch() = %q, want %q", swatch, want)
+    }
+
+    if cells := strings.Count(swatch, "▀"); cells != len(palette) {
+        t.Errorf("PaletteSwatch() drew %d cells for %d colours", cells, len(palette))
+    }
+}
+
+func TestFromImageScrollable(t *testing.T) {
+    rendered, err := FromImageScrollable(TestPattern(200, 800), 40)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // 40 by 160 pixels, 2 to a cell
+    lines := strings.Split(strings.TrimSuffix(rendered.Encoded, "\n"), "\n")
+    if rendered.Rows != 80 || len(lines) != 80 || VisualWidth(lines[0]) != 40 {
+        t.Errorf("FromImageScrollable() = %d rows, %d lines %d wide, want 80 rows 40 wide", rendered.Rows, len(lines), VisualWidth(lines[0]))
+    }
+
+    if _, err = FromImageScrollable(TestPattern(200, 800), 0); err == nil {
+        t.Error("FromImageScrollable() to no columns succeeded")
+    }
+}
+
+func TestVisualWidth(t *testing.T) {
+    tests := []struct {
+        line  string
+        width int
+    }{
+        {"[#ff0000:#0000ff]▀[#00ff00:]▀▀[-:-]  [:#123456]▄", 6},
+        {"[#ff0000:-]▁▃█[\"region\"]▀[\"\"]", 4},
+        {"\x1b[38;2;255;0;0;49m▀\x1b[48;2;0;0;255m▀\x1b[0m\x1b[K", 2},
+        {"\ufeff[#ffffff:#000000]▀ 画像", 6},
+        {"", 0},
+    }
+
+    for _, test := range tests {
+        if got := VisualWidth(test.line); got != test.width {
+            t.Errorf("VisualWidth(%q) = %d, want %d", test.line, got, test.width)
+        }
+    }
+}
+
+func TestColorGrid(t *testing.T) {
+    var palette color.Palette
+    for i := 0; i < 7; i++ {
+        palette = append(palette, color.RGBA{uint8(i * 0x20), 0, 0xff, 0xff})
+    }
+
+    // 3 to a row, with the last row cut short
+    grid := ColorGrid(palette, 3)
+    lines := strings.Split(strings.TrimSuffix(grid, "\n"), "\n")
+    if len(lines) != 3 {
+        t.Fatalf("ColorGrid() wrote %d rows, want 3: %q", len(lines), grid)
+    }
+
+    cell := regexp.MustCompile(`\[(#[0-9a-f]{6}):(#[0-9a-f]{6})\]▀`)
+    for row, line := range lines {
+        cells := cell.FindAllStringSubmatch(line, -1)
+        want := 3
+        if row == 2 {
+            want = 1
+        }
+
+        if len(cells) != want {
+            t.Errorf("row %d holds %d cells, want %d: %q", row, len(cells), want, line)
+        }
+
+        for col, c := range cells {
+            if hex := ColorHex(palette[row * 3 + col]); c[1] != hex || c[2] != hex {
+                t.Errorf("cell %d, %d is %s over %s, want %s", col, row, c[1], c[2], hex)
+            }
+        }
+    }
+
+    if single := ColorGrid(palette[:2], 0); single != "[#0000ff:#0000ff]▀\n[#2000ff:#2000ff]▀\n" {
+        t.Errorf("ColorGrid() of no columns = %q, want a single column", single)
+    }
+}
+
+func TestSizeForTagBudget(t *testing.T) {
+    flat := solid(64, 64, color.White)
+    busy := noise(64, 64, 1)
+
+    fw, fh := SizeForTagBudget(flat, 200)
+    bw, bh := SizeForTagBudget(busy, 200)
+
+    if bw >= fw || bh >= fh || bh % 2 != 0 {
+        t.Errorf("busy image scaled to %d by %d, flat one to %d by %d, want the busy one smaller & of an even height", bw, bh, fw, fh)
+    }
+
+    // The busy image fits the budget once scaled, or near enough for an estimate
+    encoded, err := FromImage(resize(busy, bw, bh))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tags := strings.Count(encoded, "["); tags > 250 {
+        t.Errorf("busy image scaled to %d by %d has %d tags, want about 200", bw, bh, tags)
+    }
+
+    if w, h := SizeForTagBudget(flat, 1000); w != 64 || h != 64 {
+        t.Errorf("flat image within the budget scaled to %d by %d, want it left at 64 by 64", w, h)
+    }
+}
+
+func TestTile(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 0, color.NRGBA{0, 0xff, 0, 0xff})
+    img.Set(0, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    img.Set(1, 1, color.White)
+
+    encoded, err := Tile(img, 8, 8)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    row := strings.Repeat("[#ff0000:#0000ff]▀[#00ff00:#ffffff]▀", 4) + "\n"
+    if want := strings.Repeat(row, 8); encoded != want {
+        t.Errorf("Tile() = %q, want %q", encoded, want)
+    }
+
+    // The rows of an image of an uneven height run on from one cell into the next
+    stripes := solid(1, 3, color.Black)
+    stripes.Set(0, 1, color.White)
+    if encoded, _ = Tile(stripes, 1, 3); encoded != "[#000000:#ffffff]▀\n[#000000:#000000]▀\n[#ffffff:#000000]▀\n" {
+        t.Errorf("Tile() of an uneven image = %q", encoded)
+    }
+
+    if _, err = Tile(img, 0, 8); err == nil {
+        t.Error("Tile() over no columns succeeded")
+    }
+}
+
+func TestForTableCell(t *testing.T) {
+    for _, img := range []image.Image{TestPattern(64, 48), TestPattern(5, 40)} {
+        text, width, err := ForTableCell(img, 12)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if width != 12 || strings.HasSuffix(text, "\n") {
+            t.Errorf("ForTableCell() of %v = %q, %d wide, want 12 wide without a trailing newline", img.Bounds(), text, width)
+        }
+
+        for i, line := range strings.Split(text, "\n") {
+            if VisualWidth(line) != 12 {
+                t.Errorf("line %d of the cell of %v is %d wide, want 12", i, img.Bounds(), VisualWidth(line))
+            }
+        }
+    }
+
+    if _, _, err := ForTableCell(TestPattern(4, 4), 0); err == nil {
+        t.Error("ForTableCell() of no columns succeeded")
+    }
+}
+
+// countedImage counts how many pixels are read from an image.
+type countedImage struct {
+    image.Image
+    reads *int
+}
+
+func (img countedImage) At(x, y int) color.Color {
+    *img.reads++
+    return img.Image.At(x, y)
+}
+
+func TestRenderPair(t *testing.T) {
+    reads := 0
+    img := countedImage{TestPattern(120, 80), &reads}
+
+    thumb, full, err := RenderPair(img, 12, 60)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, test := range []struct {
+        encoded    string
+        cols, rows int
+    }{{thumb, 12, 4}, {full, 60, 20}} {
+        lines := strings.Split(strings.TrimSuffix(test.encoded, "\n"), "\n")
+        if len(lines) != test.rows || VisualWidth(lines[0]) != test.cols {
+            t.Errorf("rendered %d rows %d wide, want %d rows %d wide", len(lines), VisualWidth(lines[0]), test.rows, test.cols)
+        }
+    }
+
+    // The image itself is only read once, for the larger of the two
+    if reads != 120 * 80 {
+        t.Errorf("read %d pixels of a 120 by 80 image, want each read once", reads)
+    }
+
+    if swappedThumb, swappedFull, _ := RenderPair(img, 60, 12); swappedThumb != full || swappedFull != thumb {
+        t.Error("RenderPair() with the larger width first didn't render the same pair")
+    }
+
+    if _, _, err = RenderPair(img, 0, 60); err == nil {
+        t.Error("RenderPair() to 0 columns succeeded")
+    }
+}
+
+func TestDimWithOverlay(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+    for i := range img.Pix {
+        img.Pix[i] = 0xff
+    }
+
+    img.Set(0, 3, color.NRGBA{0, 0, 0xff, 0xff})
+
+    encoded, err := DimWithOverlay(img, 0.5, "abc")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The text takes the middle of the top row, 2 cells from the left & 3 from the right,
+    // & every colour is halved, the text's background too
+    want := "[#808080:#808080]▀▀[#ffffff:#808080]abc[#808080:#808080]▀▀▀\n" +
+        "[#808080:#000080]▀[:#808080]▀▀▀▀▀▀▀\n"
+    if encoded != want {
+        t.Errorf("DimWithOverlay() = %q, want %q", encoded, want)
+    }
+
+    // The background of the text averages both halves of every cell under it, not only those of the first
+    mixed := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+    for x, colors := range [][2]color.Color{{color.Black, color.Black}, {color.White, color.White}, {color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}}, {color.Black, color.Black}} {
+        mixed.Set(x, 0, colors[0])
+        mixed.Set(x, 1, colors[1])
+    }
+
+    averaged, err := DimWithOverlay(mixed, 0, "ab")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#000000:#000000]▀[#ffffff:#bf80bf]ab[#000000:#000000]▀\n"; averaged != want {
+        t.Errorf("DimWithOverlay() over cells of different colours = %q, want %q", averaged, want)
+    }
+
+    // Lines wider than the image are cut to fit it
+    cut, err := DimWithOverlay(img, 1, "0123456789\nx")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#ffffff:#000000]01234567\n[#000000:#000000]▀▀▀[#ffffff:#000000]x[#000000:#000000]▀▀▀▀\n"; cut != want {
+        t.Errorf("DimWithOverlay() of a wide overlay = %q, want %q", cut, want)
+    }
+}
@@ -0,0 +1,25 @@
+package pxl
+
+import (
+    "go/token"
+    "image"
+    "strconv"
+
+    "github.com/pkg/errors"
+)
+
+// ToGoLiteral converts an image like FromImage() does & returns the output as the declaration of a Go constant
+// named varName, like `const splash = "[#ffffff:#000000]▀..."`, for baking a rendering into a program
+// without shipping the image with it. The snippet ends with a newline.
+func ToGoLiteral(img image.Image, varName string) (snippet string, err error) {
+    if !token.IsIdentifier(varName) {
+        return "", errors.Errorf("pixelview: Can't declare a constant named %q", varName)
+    }
+
+    encoded, err := FromImage(img)
+    if err != nil {
+        return
+    }
+
+    return "const " + varName + " = " + strconv.Quote(encoded) + "\n", nil
+}
@@ -0,0 +1,41 @@
+package pxl
+
+import (
+    "go/ast"
+    "go/parser"
+    "go/token"
+    "strconv"
+    "testing"
+)
+
+func TestToGoLiteral(t *testing.T) {
+    img := TestPattern(4, 4)
+    snippet, err := ToGoLiteral(img, "splash")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    file, err := parser.ParseFile(token.NewFileSet(), "splash.go", "package splash\n\n" + snippet, 0)
+    if err != nil {
+        t.Fatalf("ToGoLiteral() = %q, which doesn't parse: %v", snippet, err)
+    }
+
+    decl, ok := file.Decls[0].(*ast.GenDecl)
+    if !ok || decl.Tok != token.CONST || len(file.Decls) != 1 {
+        t.Fatalf("ToGoLiteral() = %q, want a single constant", snippet)
+    }
+
+    spec := decl.Specs[0].(*ast.ValueSpec)
+    literal, err := strconv.Unquote(spec.Values[0].(*ast.BasicLit).Value)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); spec.Names[0].Name != "splash" || literal != want {
+        t.Errorf("ToGoLiteral() declares %s = %q, want splash = %q", spec.Names[0].Name, literal, want)
+    }
+
+    if _, err = ToGoLiteral(img, "1st"); err == nil {
+        t.Error("ToGoLiteral() of an invalid name succeeded")
+    }
+}
@@ -0,0 +1,116 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+
+    "github.com/pkg/errors"
+)
+
+// EncodeWithMask converts an image like FromImage() does, along with a mask of its alpha channel
+// for compositing it some other way. The mask is formatted the same, every pixel set to a grey
+// from black for fully transparent to white for opaque, so its cells line up with the colour output.
+func EncodeWithMask(img image.Image) (encoded, mask string, err error) {
+    if encoded, err = FromImage(img); err != nil {
+        return
+    }
+
+    mask, err = FromImage(alphaImage{img})
+    return
+}
+
+// EncodeLayers converts an image into two layers which line up cell for cell, for renderers which lay foreground
+// & background colours separately: the top pixels of the cells drawn as ▀ in fgLayer, & the bottom ones as ▄ in bgLayer.
+// Both are formatted like ModeMatte, so the half of every cell which is in the other layer is left
+// to the default background, & drawing one layer over the other shows the whole image.
+func EncodeLayers(img image.Image) (fgLayer, bgLayer string, err error) {
+    e := NewEncoder(WithMode(ModeMatte))
+    pixels, err := e.Decode(img)
+    if err != nil {
+        return
+    }
+
+    tops, bottoms := make(Pixels, len(pixels)), make(Pixels, len(pixels))
+    for y, row := range pixels {
+        tops[y], bottoms[y] = make([]Cell, len(row)), make([]Cell, len(row))
+        for x, cell := range row {
+            tops[y][x] = Cell{cell.Fg, color.Transparent}
+            bottoms[y][x] = Cell{color.Transparent, cell.Bg}
+        }
+    }
+
+    return e.EncodePixels(tops), e.EncodePixels(bottoms), nil
+}
+
+// ApplyMask is the inverse of the mask of EncodeWithMask(), it returns a copy of rgb with the alpha of every pixel
+// set from the luminance of the same pixel of mask, from transparent for black to opaque for white,
+// like when an asset pipeline stores the two in separate files. Both images must be the same size.
+func ApplyMask(rgb, mask image.Image) (image.Image, error) {
+    bounds, maskMin := rgb.Bounds(), mask.Bounds().Min
+    if bounds.Size() != mask.Bounds().Size() {
+        return nil, errors.New("pixelview: Can't apply mask of a different size than the image")
+    }
+
+    masked := image.NewNRGBA(bounds)
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            c := color.NRGBAModel.Convert(rgb.At(x, y)).(color.NRGBA)
+            c.A = uint8(math.Round(luminance(mask.At(maskMin.X + x - bounds.Min.X, maskMin.Y + y - bounds.Min.Y)) * 0xff))
+            masked.SetNRGBA(x, y, c)
+        }
+    }
+
+    return masked, nil
+}
+
+// FromImageCircle converts an image cut to the largest circle which fits in it, centered, like the avatars
+// of profile pictures, with the corners outside of it drawn in bg. Pixels on the edge of the circle are blended
+// with bg by how much of them it covers, so it looks round rather than jagged. With a nil bg the corners are
+// left transparent, for tview to show its own background.
+func FromImageCircle(img image.Image, bg color.Color) (encoded string, err error) {
+    var opts []Option
+    if bg != nil {
+        opts = append(opts, WithThemeBackground(bg))
+    }
+
+    return NewEncoder(opts...).Encode(circleImage{img})
+}
+
+// circleImage fades out the pixels of an image outside the largest circle which fits in it.
+type circleImage struct {
+    image.Image
+}
+
+func (img circleImage) At(x, y int) color.Color {
+    bounds := img.Bounds()
+    radius := float64(bounds.Dx()) / 2
+    if bounds.Dy() < bounds.Dx() {
+        radius = float64(bounds.Dy()) / 2
+    }
+
+    dx := float64(x - bounds.Min.X) + 0.5 - float64(bounds.Dx()) / 2
+    dy := float64(y - bounds.Min.Y) + 0.5 - float64(bounds.Dy()) / 2
+
+    // The edge runs through the pixels it's less than half a pixel away from
+    coverage := math.Max(0, math.Min(radius - math.Hypot(dx, dy) + 0.5, 1))
+    if coverage == 1 {
+        return img.Image.At(x, y)
+    }
+
+    return darkenAlpha(img.Image.At(x, y), coverage)
+}
+
+// alphaImage shows the alpha channel of an image as opaque greys.
+type alphaImage struct {
+    image.Image
+}
+
+func (img alphaImage) ColorModel() color.Model {
+    return color.Gray16Model
+}
+
+func (img alphaImage) At(x, y int) color.Color {
+    _, _, _, a := img.Image.At(x, y).RGBA()
+    return color.Gray16{uint16(a)}
+}
@@ -0,0 +1,150 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+func TestEncodeWithMask(t *testing.T) {
+    // Opaque, half & fully transparent pixels along the top, the other way round along the bottom
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+    for x, a := range []uint8{0xff, 0x80, 0} {
+        img.SetNRGBA(x, 0, color.NRGBA{0xff, 0, 0, a})
+        img.SetNRGBA(x, 1, color.NRGBA{0, 0, 0xff, 0xff - a})
+    }
+
+    encoded, mask, err := EncodeWithMask(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(img); encoded != want {
+        t.Errorf("EncodeWithMask() = %q, FromImage() = %q", encoded, want)
+    }
+
+    if want := "[#ffffff:#000000]▀[#808080:#7f7f7f]▀[#000000:#ffffff]▀\n"; mask != want {
+        t.Errorf("mask = %q, want %q", mask, want)
+    }
+
+    if strings.Count(mask, "▀") != strings.Count(encoded, "▀") {
+        t.Errorf("mask %q doesn't line up with %q", mask, encoded)
+    }
+}
+
+func TestEncodeLayers(t *testing.T) {
+    pattern := TestPattern(5, 6)
+
+    fgLayer, bgLayer, err := EncodeLayers(pattern)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    fgLines, bgLines := strings.Split(fgLayer, "\n"), strings.Split(bgLayer, "\n")
+    if len(fgLines) != len(bgLines) {
+        t.Fatalf("layers are %d & %d rows", len(fgLines), len(bgLines))
+    }
+
+    for i := range fgLines {
+        if VisualWidth(fgLines[i]) != VisualWidth(bgLines[i]) || strings.Contains(fgLines[i], "▄") || strings.Contains(bgLines[i], "▀") {
+            t.Errorf("row %d of the layers doesn't line up as ▀ cells over ▄ ones: %q & %q", i, fgLines[i], bgLines[i])
+        }
+    }
+
+    // A ▄ cell is the ▀ cell with its colours the other way round
+    top, err := ToImage(fgLayer)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    flipped := regexp.MustCompile(`\[([^:\]]*):([^\]]*)\]`).ReplaceAllString(bgLayer, "[$2:$1]")
+    bottom, err := ToImage(strings.ReplaceAll(flipped, "▄", "▀"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for y := 0; y < 6; y += 2 {
+        for x := 0; x < 5; x++ {
+            if !SameCell(top.At(x, y), pattern.At(x, y)) || !SameCell(bottom.At(x, y + 1), pattern.At(x, y + 1)) {
+                t.Errorf("layers combine to %s over %s at (%d, %d), want %s over %s", ColorHex(top.At(x, y)), ColorHex(bottom.At(x, y + 1)),
+                    x, y, ColorHex(pattern.At(x, y)), ColorHex(pattern.At(x, y + 1)))
+            }
+
+            // The other half of every cell is left out of each layer
+            if _, _, _, a := top.At(x, y + 1).RGBA(); a != 0 {
+                t.Errorf("fgLayer draws the bottom pixel at (%d, %d)", x, y + 1)
+            }
+
+            if _, _, _, a := bottom.At(x, y).RGBA(); a != 0 {
+                t.Errorf("bgLayer draws the top pixel at (%d, %d)", x, y)
+            }
+        }
+    }
+}
+
+func TestApplyMask(t *testing.T) {
+    // A mask fading from black on the left to white on the right, at an offset of its own
+    levels := []uint8{0, 0x40, 0x80, 0xc0, 0xff}
+    mask := image.NewGray(image.Rect(5, 5, 10, 7))
+    for y := 5; y < 7; y++ {
+        for x, level := range levels {
+            mask.SetGray(x + 5, y, color.Gray{level})
+        }
+    }
+
+    masked, err := ApplyMask(solid(5, 2, color.White), mask)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for x, want := range levels {
+        if c := masked.At(x, 1).(color.NRGBA); c != (color.NRGBA{0xff, 0xff, 0xff, want}) {
+            t.Errorf("pixel %d is %v, want white at alpha %#x", x, c, want)
+        }
+    }
+
+    if _, err = ApplyMask(solid(5, 2, color.White), solid(5, 3, color.White)); err == nil {
+        t.Error("ApplyMask() of a mask of a different size succeeded")
+    }
+}
+
+func TestFromImageCircle(t *testing.T) {
+    img := solid(8, 8, color.White)
+    navy := color.RGBA{0, 0, 0x80, 0xff}
+
+    encoded, err := FromImageCircle(img, navy)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    pixels, err := NewEncoder(WithThemeBackground(navy)).Decode(circleImage{img})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := pixels.Encode(); encoded != want {
+        t.Errorf("FromImageCircle() = %q, want %q", encoded, want)
+    }
+
+    // The corners are the background, the center the image & the edge in between
+    for _, test := range []struct {
+        x, y int
+        want string
+    }{{0, 0, "#000080"}, {7, 7, "#000080"}, {3, 3, "#ffffff"}, {4, 4, "#ffffff"}} {
+        cell := pixels[test.y / 2][test.x]
+        if got := ColorHex([]color.Color{cell.Fg, cell.Bg}[test.y % 2]); got != test.want {
+            t.Errorf("pixel %d, %d = %s, want %s", test.x, test.y, got, test.want)
+        }
+    }
+
+    if edge := ColorHex(pixels[0][1].Fg); edge == "#000080" || edge == "#ffffff" {
+        t.Errorf("pixel 1, 0 on the edge = %s, want a blend", edge)
+    }
+
+    // Without a background the corner pixels are left to tview
+    if transparent, _ := FromImageCircle(img, nil); !strings.HasPrefix(transparent, "[-:") {
+        t.Errorf("FromImageCircle() without a background = %q, want transparent corners", transparent)
+    }
+}
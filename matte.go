@@ -0,0 +1,75 @@
+package pxl
+
+import (
+    "image/color"
+    "strings"
+)
+
+// matteRow emits a row of cells in ModeMatte, where transparent pixels are left to the default background
+// so the image can be laid over anything. A cell whose bottom pixel is the only opaque one is drawn
+// with the lower half block, a cell without any is a space.
+func (e *Encoder) matteRow(row int, cells []Cell) string {
+    var b strings.Builder
+    prevfg, prevbg := "", ""
+    var region string
+
+    for col, cell := range cells {
+        b.WriteString(e.regionTag(col, row, len(cells), &region))
+        if s, ok := e.substitute(col, row, cell); ok {
+            b.WriteString(s)
+            prevfg, prevbg = "", ""
+            continue
+        }
+
+        top, topOK := e.matteColor(cell.Fg)
+        bottom, bottomOK := e.matteColor(cell.Bg)
+
+        fg, bg, glyph := top, bottom, "▀"
+        switch {
+            case topOK && !bottomOK:
+                bg = "-"
+
+            case !topOK && bottomOK:
+                fg, bg, glyph = bottom, "-", "▄"
+
+            case !topOK && !bottomOK:
+                // The foreground of a space doesn't show, so it's left as it is
+                fg, bg, glyph = prevfg, "-", " "
+        }
+
+        b.WriteString(changedTag(fg, bg, prevfg, prevbg))
+        b.WriteString(glyph)
+        prevfg, prevbg = fg, bg
+    }
+
+    b.WriteString(e.regionTag(len(cells), row, len(cells), &region))
+    return b.String()
+}
+
+// matteColor returns the hex colour of an opaque pixel, written fully opaque, or false if the pixel
+// is more transparent than the threshold.
+func (e *Encoder) matteColor(c color.Color) (string, bool) {
+    n := color.NRGBAModel.Convert(c).(color.NRGBA)
+    if n.A < e.matteThreshold {
+        return "", false
+    }
+
+    return ColorHex(color.NRGBA{n.R, n.G, n.B, 0xff}), true
+}
+
+// changedTag returns the tag setting the fg & bg colours which differ from the previous ones, if any.
+func changedTag(fg, bg, prevfg, prevbg string) string {
+    switch {
+        case fg == prevfg && bg == prevbg:
+            return ""
+
+        case fg == prevfg:
+            return "[:" + bg + "]"
+
+        case bg == prevbg:
+            return "[" + fg + ":]"
+
+        default:
+            return "[" + fg + ":" + bg + "]"
+    }
+}
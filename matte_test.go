@@ -0,0 +1,35 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+func TestModeMatte(t *testing.T) {
+    // A sprite of red over blue, the blue at the bottom faint, in a transparent frame
+    sprite := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+    sprite.Set(1, 1, color.NRGBA{0xff, 0, 0, 0xff})
+    sprite.Set(1, 2, color.NRGBA{0xff, 0, 0, 0xff})
+    sprite.Set(2, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    sprite.Set(2, 2, color.NRGBA{0, 0, 0xff, 0x60})
+
+    encoded, err := NewEncoder(WithMode(ModeMatte)).Encode(sprite)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Transparent halves are left to the default background, transparent cells are spaces over it
+    if want := "[:-] [#ff0000:]▄[#0000ff:]▄ \n[:-] [#ff0000:]▀  \n"; encoded != want {
+        t.Errorf("Encode() = %q, want %q", encoded, want)
+    }
+
+    encoded, err = NewEncoder(WithMode(ModeMatte), WithMatteThreshold(0x50)).Encode(sprite)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[:-] [#ff0000:]▄[#0000ff:]▄ \n[:-] [#ff0000:]▀[#0000ff:]▀ \n"; encoded != want {
+        t.Errorf("with a lower threshold Encode() = %q, want %q", encoded, want)
+    }
+}
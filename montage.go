@@ -0,0 +1,95 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+
+    "github.com/pkg/errors"
+)
+
+// LabeledImage is an image of a montage with the label written below it.
+type LabeledImage struct {
+    Image image.Image
+    Label string
+}
+
+// Montage lays images out in a grid cols images wide, left to right, then top to bottom, each in a border
+// with its label centered below it, for showing many thumbnails at once. Every image is converted with opts
+// & centered in a box as large as the largest one, so the cells of the grid line up, with a blank column
+// between them. Labels are cut short & escaped like captions are. The montage is formatted for tview
+// whatever mode opts set, & it's drawn in a single grey border unless opts set another.
+func Montage(items []LabeledImage, cols int, opts ...Option) (string, error) {
+    if cols < 1 {
+        return "", errors.New("pixelview: Can't lay out montage less than one image wide")
+    }
+
+    if len(items) == 0 {
+        return "", errors.New("pixelview: Can't lay out montage without images")
+    }
+
+    opts = append([]Option{WithBorder(BorderSingle, color.Gray{0x80})}, opts...)
+    opts = append(opts, WithMode(ModeTview), WithLineSeparator("\n"))
+
+    // The images are converted once to find the largest, then again centered in boxes of its size
+    var width, height int
+    for _, item := range items {
+        lines, err := montageLines(NewEncoder(opts...), item.Image)
+        if err != nil {
+            return "", err
+        }
+
+        if len(lines) > height {
+            height = len(lines)
+        }
+
+        if w := VisualWidth(lines[0]); w > width {
+            width = w
+        }
+    }
+
+    // The box goes inside the border, which takes a cell on every side
+    boxed := NewEncoder(append(opts, WithBox(width - 2, height - 2))...)
+
+    var b strings.Builder
+    for start := 0; start < len(items); start += cols {
+        end := start + cols
+        if end > len(items) {
+            end = len(items)
+        }
+
+        var tiles [][]string
+        for _, item := range items[start:end] {
+            lines, err := montageLines(boxed, item.Image)
+            if err != nil {
+                return "", err
+            }
+
+            tiles = append(tiles, append(lines, captionLine(item.Label, width)))
+        }
+
+        for line := 0; line <= height; line++ {
+            for i, tile := range tiles {
+                if i > 0 {
+                    b.WriteString("[-:-] ")
+                }
+
+                b.WriteString(tile[line])
+            }
+
+            b.WriteString("\n")
+        }
+    }
+
+    return b.String(), nil
+}
+
+// montageLines converts an image of a montage, split into its lines.
+func montageLines(e *Encoder, img image.Image) ([]string, error) {
+    encoded, err := e.Encode(img)
+    if err != nil {
+        return nil, err
+    }
+
+    return strings.Split(strings.TrimSuffix(encoded, "\n"), "\n"), nil
+}
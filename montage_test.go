@@ -0,0 +1,56 @@
+package pxl
+
+import (
+    "image/color"
+    "strings"
+    "testing"
+)
+
+func TestMontage(t *testing.T) {
+    items := []LabeledImage{
+        {solid(2, 2, color.White), "one"},
+        {solid(4, 4, color.Black), "two"},
+        {TestPattern(3, 2), "three"},
+        {solid(1, 2, color.White), "four"},
+    }
+
+    montage, err := Montage(items, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(montage, "\n"), "\n")
+    if len(lines) != 10 {
+        t.Fatalf("montage of 4 images 2 wide is %d lines, want 10: %q", len(lines), montage)
+    }
+
+    // Every box is as large as the largest image, 4 by 2 cells, & there's a blank column between them
+    boxes := []string{
+        "┌────┐ ┌────┐",
+        "│    │ │    │",
+        "│    │ │    │",
+        "└────┘ └────┘",
+    }
+
+    want := append(append(append(boxes, " one    two  "), boxes...), "three   four ")
+    for i, line := range lines {
+        // With the half blocks of the images blanked out, to leave the grid
+        printed := tagPattern.ReplaceAllString(line, "")
+        if i % 5 != 4 {
+            printed = strings.ReplaceAll(printed, "▀", " ")
+        }
+
+        if printed != want[i] {
+            t.Errorf("line %d of the montage prints %q, want %q", i, printed, want[i])
+        }
+    }
+
+    // The images are converted in their boxes
+    if !strings.Contains(lines[1], "[#000000:#000000]▀▀▀▀") || !strings.Contains(lines[6], "[#ffffff:#ffffff]▀") {
+        t.Errorf("montage doesn't hold the images in its boxes: %q", montage)
+    }
+
+    if _, err = Montage(items, 0); err == nil {
+        t.Error("Montage() 0 images wide succeeded")
+    }
+}
@@ -0,0 +1,126 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/binary"
+    "io"
+
+    "github.com/pkg/errors"
+    "golang.org/x/image/tiff"
+)
+
+// FromReaderPages converts every page of a multi-page TIFF, like a scanned document, to a formatted string.
+// Other formats only hold a single image, which is converted like FromReader() does, as the only page.
+func FromReaderPages(reader io.Reader) (pages []string, err error) {
+    data, err := io.ReadAll(reader)
+    if err != nil {
+        return
+    }
+
+    order, first, ok := tiffHeader(data)
+    if !ok {
+        encoded, err := FromReader(bytes.NewReader(data))
+        if err != nil {
+            return nil, err
+        }
+
+        return []string{encoded}, nil
+    }
+
+    offsets, err := tiffPages(data, order, first)
+    if err != nil {
+        return
+    }
+
+    for _, offset := range offsets {
+        page := &tiffPage{data: data}
+        order.PutUint32(page.offset[:], offset)
+
+        img, err := tiff.Decode(io.NewSectionReader(page, 0, int64(len(data))))
+        if err != nil {
+            return nil, err
+        }
+
+        encoded, err := FromImage(img)
+        if err != nil {
+            return nil, err
+        }
+
+        pages = append(pages, encoded)
+    }
+
+    return
+}
+
+// tiffHeader reads the byte order & the offset of the first IFD of a TIFF file,
+// ok is false if data isn't a TIFF file.
+func tiffHeader(data []byte) (order binary.ByteOrder, first uint32, ok bool) {
+    if len(data) < 8 {
+        return
+    }
+
+    switch string(data[:4]) {
+        case "II*\x00":
+            order = binary.LittleEndian
+
+        case "MM\x00*":
+            order = binary.BigEndian
+
+        default:
+            return
+    }
+
+    return order, order.Uint32(data[4:8]), true
+}
+
+// tiffPages follows the chain of IFDs, which each describe a page, returning their offsets.
+func tiffPages(data []byte, order binary.ByteOrder, offset uint32) (offsets []uint32, err error) {
+    seen := make(map[uint32]bool)
+
+    for offset != 0 {
+        if seen[offset] || int64(offset) + 2 > int64(len(data)) {
+            err = errors.New("pixelview: Can't read pages of malformed TIFF")
+            return
+        }
+
+        seen[offset] = true
+        offsets = append(offsets, offset)
+
+        // An IFD is a count of entries, the 12 byte entries & the offset of the next IFD
+        next := int64(offset) + 2 + int64(order.Uint16(data[offset:])) * 12
+        if next + 4 > int64(len(data)) {
+            err = errors.New("pixelview: Can't read pages of malformed TIFF")
+            return
+        }
+
+        offset = order.Uint32(data[next:])
+    }
+
+    return
+}
+
+// tiffPage reads a TIFF file as if the IFD at offset was its first one,
+// so the decoder, which only ever decodes the first IFD, decodes that page.
+type tiffPage struct {
+    data   []byte
+    offset [4]byte
+}
+
+func (p *tiffPage) ReadAt(b []byte, off int64) (n int, err error) {
+    if off >= int64(len(p.data)) {
+        return 0, io.EOF
+    }
+
+    n = copy(b, p.data[off:])
+    for i := range p.offset {
+        if j := int64(4 + i) - off; j >= 0 && j < int64(n) {
+            b[j] = p.offset[i]
+        }
+    }
+
+    if n < len(b) {
+        err = io.EOF
+    }
+
+    return
+}
@@ -0,0 +1,84 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/binary"
+    "image"
+    "image/color"
+    "image/png"
+    "testing"
+)
+
+// grayTIFF writes a little-endian, uncompressed TIFF file with a page for each image.
+func grayTIFF(pages ...*image.Gray) []byte {
+    var buf bytes.Buffer
+    buf.WriteString("II*\x00\x00\x00\x00\x00")
+
+    // Where the offset of the next IFD goes, starting with the first one in the header
+    link := 4
+    for _, img := range pages {
+        w, h := img.Rect.Dx(), img.Rect.Dy()
+        strip := buf.Len()
+        for y := 0; y < h; y++ {
+            buf.Write(img.Pix[y * img.Stride:y * img.Stride + w])
+        }
+
+        if buf.Len() % 2 == 1 {
+            buf.WriteByte(0)
+        }
+
+        binary.LittleEndian.PutUint32(buf.Bytes()[link:], uint32(buf.Len()))
+
+        // Tag, type (3 is SHORT & 4 LONG), count & value, in ascending order of tags
+        entries := [][4]uint32{
+            {256, 4, 1, uint32(w)}, {257, 4, 1, uint32(h)}, {258, 3, 1, 8}, {259, 3, 1, 1}, {262, 3, 1, 1},
+            {273, 4, 1, uint32(strip)}, {277, 3, 1, 1}, {278, 4, 1, uint32(h)}, {279, 4, 1, uint32(w * h)},
+        }
+
+        binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+        for _, e := range entries {
+            binary.Write(&buf, binary.LittleEndian, uint16(e[0]))
+            binary.Write(&buf, binary.LittleEndian, uint16(e[1]))
+            binary.Write(&buf, binary.LittleEndian, e[2])
+            binary.Write(&buf, binary.LittleEndian, e[3])
+        }
+
+        link = buf.Len()
+        binary.Write(&buf, binary.LittleEndian, uint32(0))
+    }
+
+    return buf.Bytes()
+}
+
+func TestFromReaderPages(t *testing.T) {
+    first := image.NewGray(image.Rect(0, 0, 3, 2))
+    second := image.NewGray(image.Rect(0, 0, 2, 4))
+    for i := range second.Pix {
+        second.Pix[i] = 0xff
+    }
+
+    pages, err := FromReaderPages(bytes.NewReader(grayTIFF(first, second)))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(pages) != 2 {
+        t.Fatalf("got %d pages, want 2", len(pages))
+    }
+
+    for i, img := range []image.Image{first, second} {
+        if want, _ := FromImage(img); pages[i] != want {
+            t.Errorf("page %d = %q, want %q", i, pages[i], want)
+        }
+    }
+
+    // Any other format is a single page
+    var buf bytes.Buffer
+    if err = png.Encode(&buf, solid(2, 2, color.White)); err != nil {
+        t.Fatal(err)
+    }
+
+    if pages, err = FromReaderPages(&buf); err != nil || len(pages) != 1 {
+        t.Errorf("FromReaderPages() of a PNG returned %d pages & %v, want a single page", len(pages), err)
+    }
+}
@@ -0,0 +1,211 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "github.com/pkg/errors"
+)
+
+// ToImage is the inverse of FromImage(), it parses text formatted for tview back into an image,
+// with two pixels for every half block. Colours carry over from tag to tag like they do in tview,
+// so cells before the first tag are transparent, as are colours written as -. Tags in other forms than [fg:bg], [fg:] & [:bg],
+// text other than half blocks & rows of different widths are errors.
+func ToImage(encoded string) (img image.Image, err error) {
+    lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+    if encoded == "" {
+        lines = nil
+    }
+
+    var rows [][]Cell
+    var fg, bg color.Color = color.RGBA{}, color.RGBA{}
+
+    for _, line := range lines {
+        var row []Cell
+
+        for line != "" {
+            if line[0] == '[' {
+                end := strings.IndexByte(line, ']')
+                if end < 0 {
+                    err = errors.Errorf("pixelview: Can't parse unterminated tag %q", line)
+                    return
+                }
+
+                if fg, bg, err = parseTag(line[1:end], fg, bg); err != nil {
+                    return
+                }
+
+                line = line[end + 1:]
+                continue
+            }
+
+            if !strings.HasPrefix(line, "▀") {
+                err = errors.Errorf("pixelview: Can't parse %q as a cell", []rune(line)[0])
+                return
+            }
+
+            row = append(row, Cell{fg, bg})
+            line = line[len("▀"):]
+        }
+
+        if len(rows) > 0 && len(row) != len(rows[0]) {
+            err = errors.New("pixelview: Can't parse rows of different widths")
+            return
+        }
+
+        rows = append(rows, row)
+    }
+
+    var width int
+    if len(rows) > 0 {
+        width = len(rows[0])
+    }
+
+    decoded := image.NewRGBA(image.Rect(0, 0, width, len(rows) * 2))
+    for y, row := range rows {
+        for x, cell := range row {
+            decoded.Set(x, y * 2, cell.Fg)
+            decoded.Set(x, y * 2 + 1, cell.Bg)
+        }
+    }
+
+    return decoded, nil
+}
+
+// RenderToPNG paints text formatted for tview, as parsed by ToImage(), the way a terminal shows it,
+// every cell taking cellW by cellH pixels with its top half in the fg colour & the rest in the bg one,
+// for screenshots of the output in documentation. The image can be written out with png.Encode().
+func RenderToPNG(encoded string, cellW, cellH int) (img image.Image, err error) {
+    if cellW < 1 || cellH < 2 {
+        err = errors.New("pixelview: Can't render cells smaller than 1 by 2 pixels")
+        return
+    }
+
+    pixels, err := ToImage(encoded)
+    if err != nil {
+        return
+    }
+
+    size := pixels.Bounds().Size()
+    rendered := image.NewRGBA(image.Rect(0, 0, size.X * cellW, size.Y / 2 * cellH))
+
+    for y := 0; y < rendered.Rect.Dy(); y++ {
+        // The top half of a row of cells shows the top pixels, the rest the bottom ones
+        py := y / cellH * 2
+        if y % cellH >= cellH / 2 {
+            py++
+        }
+
+        for x := 0; x < rendered.Rect.Dx(); x++ {
+            rendered.Set(x, y, pixels.At(x / cellW, py))
+        }
+    }
+
+    return rendered, nil
+}
+
+// sgrPattern matches SGR escape sequences, capturing their parameters.
+var sgrPattern = regexp.MustCompile("\x1b\\[([0-9;]*)m")
+
+// DetectMode reports which mode most likely wrote some output, for tools taking output from different sources.
+// ANSI output is told apart by the colours of its SGR sequences, tview output by the glyphs between its tags,
+// & anything else is taken for ModeASCII. ModePagerSafe writes the same sequences as ModeANSI, so it's reported as that,
+// & output in greys only from the 256 colour palette as ModeGray256.
+func DetectMode(s string) Mode {
+    if strings.Contains(s, "<pre") && strings.Contains(s, "</pre>") {
+        return ModeHTML
+    }
+
+    if sequences := sgrPattern.FindAllStringSubmatch(s, -1); len(sequences) > 0 {
+        trueColor, palette, gray := false, false, true
+        for _, seq := range sequences {
+            params := strings.Split(seq[1], ";")
+            for i := 0; i < len(params); i++ {
+                if (params[i] != "38" && params[i] != "48") || i + 1 >= len(params) {
+                    continue
+                }
+
+                switch params[i + 1] {
+                    case "2":
+                        trueColor = true
+                        i += 4
+
+                    case "5":
+                        if i + 2 < len(params) {
+                            n, _ := strconv.Atoi(params[i + 2])
+                            palette, gray = true, gray && n >= 232
+                        }
+
+                        i += 2
+                }
+            }
+        }
+
+        switch {
+            case trueColor:
+                return ModeANSI
+
+            case palette && gray:
+                return ModeGray256
+
+            case palette:
+                return ModeANSI256
+        }
+
+        return ModeANSI16
+    }
+
+    if tagPattern.MatchString(s) {
+        text := tagPattern.ReplaceAllString(s, "")
+        blocks, spaces := strings.Contains(text, "▀"), strings.Contains(text, " ")
+
+        switch {
+            case blocks && spaces:
+                return ModeMatte
+
+            case spaces:
+                return ModeTwoRowSpace
+        }
+
+        return ModeTview
+    }
+
+    return ModeASCII
+}
+
+// parseTag applies the colours of a tag, without its brackets, to the current fg & bg colours.
+func parseTag(tag string, fg, bg color.Color) (color.Color, color.Color, error) {
+    parts := strings.Split(tag, ":")
+    if len(parts) != 2 || (parts[0] == "" && parts[1] == "") {
+        return nil, nil, errors.Errorf("pixelview: Can't parse tag %q", "[" + tag + "]")
+    }
+
+    var err error
+    for i, part := range parts {
+        if part == "-" {
+            parts[i] = ""
+            if i == 0 {
+                fg = color.RGBA{}
+            } else {
+                bg = color.RGBA{}
+            }
+        }
+    }
+
+    if parts[0] != "" {
+        if fg, err = ParseHex(parts[0]); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    if parts[1] != "" {
+        if bg, err = ParseHex(parts[1]); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    return fg, bg, nil
+}
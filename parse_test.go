@@ -0,0 +1,135 @@
+package pxl
+
+import (
+    "bytes"
+    "image"
+    "image/color"
+    "image/draw"
+    "image/png"
+    "testing"
+)
+
+func TestToImageRoundTrip(t *testing.T) {
+    img := TestPattern(9, 6).(*image.NRGBA)
+    img.Set(4, 3, color.Transparent)
+
+    encoded, err := FromImage(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    decoded, err := ToImage(encoded)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if decoded.Bounds() != img.Rect {
+        t.Fatalf("ToImage() is %v, want %v", decoded.Bounds(), img.Rect)
+    }
+
+    for y := 0; y < 6; y++ {
+        for x := 0; x < 9; x++ {
+            if !SameCell(decoded.At(x, y), img.At(x, y)) {
+                t.Errorf("pixel %d, %d is %v, want %v", x, y, decoded.At(x, y), img.At(x, y))
+            }
+        }
+    }
+
+    // Bare half blocks & partial tags carry the colours before them on
+    decoded, err = ToImage("[#ff0000:#00ff00]▀[:#0000ff]▀▀\n")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := []color.RGBA{{0xff, 0, 0, 0xff}, {0, 0xff, 0, 0xff}, {0xff, 0, 0, 0xff}, {0, 0, 0xff, 0xff}, {0xff, 0, 0, 0xff}, {0, 0, 0xff, 0xff}}
+    for i, c := range want {
+        if got := decoded.At(i / 2, i % 2); !SameCell(got, c) {
+            t.Errorf("pixel %d, %d is %v, want %v", i / 2, i % 2, got, c)
+        }
+    }
+
+    for _, invalid := range []string{"[red]▀\n", "▀x\n", "▀▀\n▀\n"} {
+        if _, err = ToImage(invalid); err == nil {
+            t.Errorf("ToImage(%q) succeeded", invalid)
+        }
+    }
+}
+
+func TestRenderToPNG(t *testing.T) {
+    teal := color.NRGBA{0, 0x80, 0x80, 0xff}
+    encoded, err := FromImage(solid(3, 2, teal))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    img, err := RenderToPNG(encoded, 4, 8)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var buf bytes.Buffer
+    if err = png.Encode(&buf, img); err != nil {
+        t.Fatal(err)
+    }
+
+    decoded, err := png.Decode(&buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if decoded.Bounds() != image.Rect(0, 0, 12, 8) {
+        t.Fatalf("rendered PNG is %v, want 12 by 8", decoded.Bounds())
+    }
+
+    for y := 0; y < 8; y++ {
+        for x := 0; x < 12; x++ {
+            if got := ColorHex(decoded.At(x, y)); got != "#008080" {
+                t.Fatalf("pixel %d, %d of the rendered PNG = %s, want #008080", x, y, got)
+            }
+        }
+    }
+
+    // The top half of a cell is painted in its foreground colour, the bottom half in its background
+    halves, err := RenderToPNG("[#ff0000:#0000ff]▀\n", 1, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if top, bottom := ColorHex(halves.At(0, 1)), ColorHex(halves.At(0, 2)); top != "#ff0000" || bottom != "#0000ff" {
+        t.Errorf("cell is painted %s over %s, want #ff0000 over #0000ff", top, bottom)
+    }
+
+    if _, err = RenderToPNG(encoded, 1, 1); err == nil {
+        t.Error("RenderToPNG() to cells 1 pixel tall succeeded")
+    }
+}
+
+func TestDetectMode(t *testing.T) {
+    // With a transparent corner, which ModeMatte leaves as spaces
+    img := TestPattern(8, 8).(*image.NRGBA)
+    draw.Draw(img, image.Rect(0, 0, 2, 4), image.Transparent, image.Point{}, draw.Src)
+
+    modes := map[Mode]Mode{
+        ModeTview: ModeTview, ModeTwoRowSpace: ModeTwoRowSpace, ModeMatte: ModeMatte, ModeHTML: ModeHTML, ModeASCII: ModeASCII,
+        ModeANSI: ModeANSI, ModePagerSafe: ModeANSI, ModeANSI256: ModeANSI256, ModeANSI16: ModeANSI16, ModeGray256: ModeGray256,
+    }
+
+    for mode, want := range modes {
+        encoded, err := NewEncoder(WithMode(mode)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if got := DetectMode(encoded); got != want {
+            t.Errorf("DetectMode() of mode %d output = %d, want %d", mode, got, want)
+        }
+    }
+
+    if got := DetectMode("[#ff0000:#0000ff]▀[#00ff00:]▀\n"); got != ModeTview {
+        t.Errorf("DetectMode() of a tview line = %d, want ModeTview", got)
+    }
+
+    if got := DetectMode("\x1b[38;2;255;0;0;48;2;0;0;255m▀\x1b[0m\n"); got != ModeANSI {
+        t.Errorf("DetectMode() of an ANSI line = %d, want ModeANSI", got)
+    }
+}
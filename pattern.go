@@ -0,0 +1,43 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+)
+
+// TestPattern returns a w by h pixel *image.NRGBA which is always drawn the same, for tests, benchmarks & examples.
+// Red rises from left to right & green from top to bottom, over a checkerboard of squares 4 pixels wide in blue,
+// so neighbouring cells hardly ever share colours. An uneven height is rounded up, so the image can always be converted.
+func TestPattern(w, h int) image.Image {
+    if w < 0 {
+        w = 0
+    }
+
+    if h < 0 {
+        h = 0
+    }
+
+    h += h % 2
+    img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+    ramp := func(i, n int) uint8 {
+        if n < 2 {
+            return 0
+        }
+
+        return uint8(i * 0xff / (n - 1))
+    }
+
+    for y := 0; y < h; y++ {
+        for x := 0; x < w; x++ {
+            blue := uint8(0x40)
+            if (x / 4 + y / 4) % 2 == 1 {
+                blue = 0xc0
+            }
+
+            img.SetNRGBA(x, y, color.NRGBA{ramp(x, w), ramp(y, h), blue, 0xff})
+        }
+    }
+
+    return img
+}
@@ -0,0 +1,36 @@
+package pxl
+
+import (
+    "bytes"
+    "image"
+    "testing"
+)
+
+func TestTestPattern(t *testing.T) {
+    for _, size := range []image.Point{{16, 10}, {7, 5}, {1, 1}, {0, 0}} {
+        first, ok := TestPattern(size.X, size.Y).(*image.NRGBA)
+        if !ok {
+            t.Fatalf("TestPattern(%d, %d) isn't an *image.NRGBA", size.X, size.Y)
+        }
+
+        // An uneven height is rounded up
+        want := image.Rect(0, 0, size.X, size.Y + size.Y % 2)
+        if first.Rect != want {
+            t.Errorf("TestPattern(%d, %d) is %v, want %v", size.X, size.Y, first.Rect, want)
+        }
+
+        if second := TestPattern(size.X, size.Y).(*image.NRGBA); !bytes.Equal(first.Pix, second.Pix) {
+            t.Errorf("TestPattern(%d, %d) drew different pixels on another call", size.X, size.Y)
+        }
+
+        if err := CanRender(first); err != nil {
+            t.Errorf("TestPattern(%d, %d) can't be rendered: %v", size.X, size.Y, err)
+        }
+    }
+
+    // Neighbouring pixels differ, so a test can tell them apart
+    pattern := TestPattern(8, 8)
+    if SameCell(pattern.At(0, 0), pattern.At(1, 0)) || SameCell(pattern.At(0, 0), pattern.At(0, 1)) {
+        t.Error("TestPattern() drew neighbouring pixels the same")
+    }
+}
@@ -0,0 +1,302 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+
+    "github.com/pkg/errors"
+)
+
+// Cell is a single character of output, it holds the colours of the two
+// pixels it represents: Fg is the top pixel & Bg is the bottom one.
+type Cell struct {
+    Fg, Bg color.Color
+}
+
+// Pixels is the intermediate representation between decoding an image and
+// emitting it as text, it holds one row of cells for every two rows of pixels.
+// It can be freely modified before being encoded.
+type Pixels [][]Cell
+
+// DecodeToPixels pairs the pixels of an image into cells without emitting any tags.
+// Like FromImage(), it can't process an image with an uneven height.
+// The bounds of the image may start anywhere, even at negative coordinates,
+// the first cell is always its top-left corner.
+func DecodeToPixels(img image.Image) (pixels Pixels, err error) {
+    if err = checkHeight(img); err != nil {
+        return
+    }
+
+    decode := rowDecoder(img)
+    for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y += 2 {
+        pixels = append(pixels, decode(y))
+    }
+
+    return
+}
+
+// Encode converts the cells into a string formatted for tview,
+// it produces the same output as FromImage() does for the source image.
+func (p Pixels) Encode() string {
+    return NewEncoder().EncodePixels(p)
+}
+
+// tviewRow emits a row of cells with tview colour tags.
+func (e *Encoder) tviewRow(row int, cells []Cell) string {
+    var b strings.Builder
+    var prevfg, prevbg color.Color
+    var region string
+
+    for col, cell := range cells {
+        b.WriteString(e.regionTag(col, row, len(cells), &region))
+        if s, ok := e.substitute(col, row, cell); ok {
+            b.WriteString(s)
+            prevfg, prevbg = nil, nil
+            continue
+        }
+
+        if e.tagFormatter != nil {
+            fgChanged, bgChanged := !SameCell(cell.Fg, prevfg), !SameCell(cell.Bg, prevbg)
+            if fgChanged || bgChanged {
+                b.WriteString(e.tagFormatter(cell.Fg, cell.Bg, fgChanged, bgChanged))
+            }
+
+            b.WriteString(e.cellGlyph())
+            prevfg, prevbg = cell.Fg, cell.Bg
+            continue
+        }
+
+        b.WriteString(e.glyphed(Encode(cell.Fg, cell.Bg, &prevfg, &prevbg)))
+    }
+
+    b.WriteString(e.regionTag(len(cells), row, len(cells), &region))
+    return b.String()
+}
+
+// ErrOddHeight is returned for images with an uneven height, which can't be paired into cells.
+var ErrOddHeight = errors.New("pixelview: Can't process image with uneven height")
+
+// CanRender returns the error FromImage() would for an image, without converting it.
+func CanRender(img image.Image) error {
+    return checkHeight(img)
+}
+
+// checkHeight returns ErrOddHeight for images with an uneven height,
+// which can't be paired into cells.
+func checkHeight(img image.Image) error {
+    if (img.Bounds().Max.Y - img.Bounds().Min.Y) % 2 != 0 {
+        return ErrOddHeight
+    }
+
+    return nil
+}
+
+// fitCells makes the size of an image a multiple of the cells of a mode, which are cols by rows pixels,
+// by padding or cropping its right & bottom edges as fill says. With EdgeError an image which doesn't fit is an error.
+// Every mode goes through it, so they all handle the edges the same way.
+func fitCells(img image.Image, cols, rows int, fill EdgeFill) (image.Image, error) {
+    bounds := img.Bounds()
+    extraX, extraY := bounds.Dx() % cols, bounds.Dy() % rows
+    if extraX == 0 && extraY == 0 {
+        return img, nil
+    }
+
+    switch fill {
+        case EdgePad, EdgeDuplicate, EdgeReflect:
+            if extraX > 0 {
+                extraX -= cols
+            }
+
+            if extraY > 0 {
+                extraY -= rows
+            }
+
+            return &paddedImage{img, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X - extraX, bounds.Max.Y - extraY), fill}, nil
+
+        case EdgeCrop:
+            return subImage(img, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X - extraX, bounds.Max.Y - extraY)), nil
+    }
+
+    if extraY > 0 && rows == 2 {
+        return nil, checkHeight(img)
+    }
+
+    if extraY > 0 {
+        return nil, errors.Errorf("pixelview: Can't process image with a height that isn't a multiple of %d", rows)
+    }
+
+    return nil, errors.Errorf("pixelview: Can't process image with a width that isn't a multiple of %d", cols)
+}
+
+// paddedImage extends an image to larger bounds, with transparent pixels for EdgePad
+// or the pixels along its edges for EdgeDuplicate & EdgeReflect.
+type paddedImage struct {
+    image.Image
+    bounds image.Rectangle
+    fill   EdgeFill
+}
+
+func (img *paddedImage) Bounds() image.Rectangle {
+    return img.bounds
+}
+
+func (img *paddedImage) At(x, y int) color.Color {
+    inner := img.Image.Bounds()
+    if (image.Point{x, y}.In(inner)) {
+        return img.Image.At(x, y)
+    }
+
+    if img.fill == EdgePad {
+        return color.Transparent
+    }
+
+    return img.Image.At(edgeIndex(x, inner.Min.X, inner.Max.X, img.fill), edgeIndex(y, inner.Min.Y, inner.Max.Y, img.fill))
+}
+
+// edgeIndex maps a coordinate past the end of the range from min to max back inside it,
+// onto the last one for EdgeDuplicate, or mirrored about it for EdgeReflect.
+func edgeIndex(i, min, max int, fill EdgeFill) int {
+    if i < max {
+        return i
+    }
+
+    if fill == EdgeReflect {
+        i = 2 * (max - 1) - i
+    }
+
+    if i >= max || i < min {
+        return max - 1
+    }
+
+    return i
+}
+
+// rowDecoder returns a function pairing the pixel rows y & y+1 into cells,
+// using the cheapest pixel access the image type allows.
+func rowDecoder(img image.Image) func(y int) []Cell {
+    switch v := img.(type) {
+        default:
+            return func(y int) []Cell {
+                return genericRow(img, y)
+            }
+
+        case *image.Paletted:
+            palette := clearTransparent(v.Palette)
+            return func(y int) []Cell {
+                return palettedRow(v, palette, y)
+            }
+
+        case *image.NRGBA:
+            return func(y int) []Cell {
+                return nrgbaRow(v, y)
+            }
+
+        case *image.RGBA:
+            return func(y int) []Cell {
+                return rgbaRow(v, y)
+            }
+
+        case *image.YCbCr:
+            return func(y int) []Cell {
+                return ycbcrRow(v, y)
+            }
+    }
+}
+
+func genericRow(img image.Image, y int) []Cell {
+    row := make([]Cell, 0, img.Bounds().Dx())
+
+    for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+        row = append(row, Cell{img.At(x, y), img.At(x, y + 1)})
+    }
+
+    return row
+}
+
+// palettedRow looks the pixels up in palette rather than img.Palette, see clearTransparent().
+func palettedRow(img *image.Paletted, palette color.Palette, y int) []Cell {
+    row := make([]Cell, 0, img.Rect.Dx())
+
+    for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+        i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X)
+        row = append(row, Cell{palette[img.Pix[i]], palette[img.Pix[i + img.Stride]]})
+    }
+
+    return row
+}
+
+// clearTransparent replaces the fully transparent entries of a GIF or PNG8 palette with color.Transparent.
+// The RGB values of those entries are meaningless & may not even be validly premultiplied,
+// so they must not leak into the output, this way they're composited like any other transparent pixel.
+// The palette is returned as is if it has no transparent entries. An empty palette has no colours to draw
+// the pixels in, so it's taken as a single transparent entry rather than indexed out of range.
+func clearTransparent(palette color.Palette) color.Palette {
+    if len(palette) == 0 {
+        return color.Palette{color.Transparent}
+    }
+
+    var cleared color.Palette
+
+    for i, c := range palette {
+        if _, _, _, a := c.RGBA(); a != 0 || c == color.Transparent {
+            continue
+        }
+
+        if cleared == nil {
+            cleared = append(color.Palette(nil), palette...)
+        }
+
+        cleared[i] = color.Transparent
+    }
+
+    if cleared == nil {
+        return palette
+    }
+
+    return cleared
+}
+
+func nrgbaRow(img *image.NRGBA, y int) []Cell {
+    row := make([]Cell, 0, img.Rect.Dx())
+
+    for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+        i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X) * 4
+        fg := color.NRGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+        i += img.Stride
+        bg := color.NRGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+        row = append(row, Cell{fg, bg})
+    }
+
+    return row
+}
+
+// ycbcrRow finds the chroma of every pixel with COffset(), which knows how many pixels share it
+// for every subsampling ratio.
+func ycbcrRow(img *image.YCbCr, y int) []Cell {
+    row := make([]Cell, 0, img.Rect.Dx())
+    pixel := func(x, y int) color.YCbCr {
+        i := img.COffset(x, y)
+        return color.YCbCr{img.Y[img.YOffset(x, y)], img.Cb[i], img.Cr[i]}
+    }
+
+    for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+        row = append(row, Cell{pixel(x, y), pixel(x, y + 1)})
+    }
+
+    return row
+}
+
+func rgbaRow(img *image.RGBA, y int) []Cell {
+    row := make([]Cell, 0, img.Rect.Dx())
+
+    for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+        i := (y - img.Rect.Min.Y) * img.Stride + (x - img.Rect.Min.X) * 4
+        fg := color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+        i += img.Stride
+        bg := color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+        row = append(row, Cell{fg, bg})
+    }
+
+    return row
+}
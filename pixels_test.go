@@ -0,0 +1,145 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "testing"
+)
+
+func TestDecodeToPixelsRoundTrip(t *testing.T) {
+    pattern := TestPattern(13, 10)
+
+    rgba := image.NewRGBA(pattern.Bounds())
+    draw.Draw(rgba, rgba.Rect, pattern, image.Point{}, draw.Src)
+
+    gray := image.NewGray(pattern.Bounds())
+    draw.Draw(gray, gray.Rect, pattern, image.Point{}, draw.Src)
+
+    for _, img := range []image.Image{pattern, rgba, gray} {
+        pixels, err := DecodeToPixels(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(pixels) != 5 || len(pixels[0]) != 13 {
+            t.Fatalf("%T decoded to %d rows of %d cells, want 5 of 13", img, len(pixels), len(pixels[0]))
+        }
+
+        want, err := FromImage(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if got := pixels.Encode(); got != want {
+            t.Errorf("%T: Pixels.Encode() = %q, FromImage() = %q", img, got, want)
+        }
+    }
+}
+
+func TestPixelsCanBeModified(t *testing.T) {
+    pixels, err := DecodeToPixels(solid(2, 2, color.White))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    pixels[0][1] = Cell{color.Black, color.White}
+
+    if got, want := pixels.Encode(), "[#ffffff:#ffffff]▀[#000000:]▀\n"; got != want {
+        t.Errorf("Pixels.Encode() = %q, want %q", got, want)
+    }
+
+    if _, err = DecodeToPixels(solid(2, 3, color.White)); err != ErrOddHeight {
+        t.Errorf("DecodeToPixels() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestTransparentPaletteEntries(t *testing.T) {
+    // Index 0 is transparent with leftover RGB values, which aren't even validly premultiplied
+    palette := color.Palette{color.RGBA{0x12, 0x34, 0x56, 0}, color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0xff, 0, 0xff}}
+
+    for _, size := range []int{2, 3} {
+        img := image.NewPaletted(image.Rect(0, 0, 2, 2), palette[:size])
+        img.SetColorIndex(1, 0, 1)
+
+        encoded, err := FromImage(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want := "[-:-]▀[#ff0000:]▀\n"; encoded != want {
+            t.Errorf("%d colours: FromImage() = %q, want %q", size, encoded, want)
+        }
+
+        themed, err := NewEncoder(WithThemeBackground(color.RGBA{0x20, 0x20, 0x20, 0xff})).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want := "[#202020:#202020]▀[#ff0000:]▀\n"; themed != want {
+            t.Errorf("%d colours: over a theme = %q, want %q", size, themed, want)
+        }
+    }
+}
+
+func TestFitCellsBrailleSized(t *testing.T) {
+    // 3 by 5 pixels against cells of 2 by 4, like braille's, with its own value in every pixel
+    img := image.NewGray(image.Rect(0, 0, 3, 5))
+    for i := range img.Pix {
+        img.Pix[i] = uint8(i * 10 + 10)
+    }
+
+    at := func(fitted image.Image, x, y int) string {
+        return ColorHex(fitted.At(x, y))
+    }
+
+    padded, err := fitCells(img, 2, 4, EdgePad)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if padded.Bounds() != image.Rect(0, 0, 4, 8) {
+        t.Fatalf("padded image is %v, want 4 by 8", padded.Bounds())
+    }
+
+    if _, _, _, a := padded.At(3, 0).RGBA(); a != 0 || at(padded, 2, 4) != at(img, 2, 4) {
+        t.Errorf("padding isn't transparent past the right edge, or covers the image")
+    }
+
+    for fill, want := range map[EdgeFill][2]image.Point{EdgeDuplicate: {{2, 4}, {2, 4}}, EdgeReflect: {{1, 3}, {1, 4}}} {
+        fitted, _ := fitCells(img, 2, 4, fill)
+
+        // The corner past both edges & the column past the right edge on the last row
+        if at(fitted, 3, 5) != at(img, want[0].X, want[0].Y) || at(fitted, 3, 4) != at(img, want[1].X, want[1].Y) {
+            t.Errorf("fill %d: corner is %s, right of the last row %s, want the pixels at %v & %v",
+                fill, at(fitted, 3, 5), at(fitted, 3, 4), want[0], want[1])
+        }
+    }
+
+    if cropped, _ := fitCells(img, 2, 4, EdgeCrop); cropped.Bounds() != image.Rect(0, 0, 2, 4) {
+        t.Errorf("cropped image is %v, want 2 by 4", cropped.Bounds())
+    }
+
+    if _, err = fitCells(img, 2, 4, EdgeError); err == nil {
+        t.Error("fitCells() of an image which doesn't fit succeeded with EdgeError")
+    }
+}
+
+func TestCanRender(t *testing.T) {
+    for _, img := range []image.Image{solid(3, 4, color.White), TestPattern(5, 2), image.NewNRGBA(image.Rect(0, -3, 2, 3))} {
+        if err := CanRender(img); err != nil {
+            t.Errorf("CanRender() of a %v image returned %v", img.Bounds(), err)
+        }
+    }
+
+    for _, img := range []image.Image{solid(3, 5, color.White), image.NewPaletted(image.Rect(0, -3, 2, 0), color.Palette{color.Black})} {
+        err := CanRender(img)
+        if err != ErrOddHeight {
+            t.Errorf("CanRender() of a %v image returned %v, want ErrOddHeight", img.Bounds(), err)
+        }
+
+        if _, ferr := FromImage(img); ferr != err {
+            t.Errorf("CanRender() of a %v image returned %v, FromImage() %v", img.Bounds(), err, ferr)
+        }
+    }
+}
@@ -0,0 +1,231 @@
+package pxl
+
+import (
+    "bytes"
+    "encoding/base64"
+    "fmt"
+    "image"
+    "image/color"
+    "image/png"
+    "strings"
+
+    "github.com/pkg/errors"
+)
+
+// ColorProfile is what a terminal is able to display, as found by the caller's own detection.
+type ColorProfile int
+
+const (
+    // ProfileASCII is for terminals without any colour.
+    ProfileASCII ColorProfile = iota
+
+    // ProfileANSI16 is for terminals with the 16 standard colours.
+    ProfileANSI16
+
+    // ProfileANSI256 is for terminals with the xterm 256 colour palette.
+    ProfileANSI256
+
+    // ProfileTrueColor is for terminals with 24 bit colour.
+    ProfileTrueColor
+
+    // ProfileKitty is for terminals which implement the kitty graphics protocol.
+    ProfileKitty
+
+    // ProfileSixel is for terminals which display sixel graphics.
+    ProfileSixel
+)
+
+// EncodeFor converts an image to the best output for a terminal with the given profile,
+// ready to be written straight to it.
+func EncodeFor(img image.Image, profile ColorProfile) (encoded string, err error) {
+    switch profile {
+        case ProfileASCII:
+            return NewEncoder(WithMode(ModeASCII)).Encode(img)
+
+        case ProfileANSI16:
+            return NewEncoder(WithMode(ModeANSI16)).Encode(img)
+
+        case ProfileANSI256:
+            return NewEncoder(WithMode(ModeANSI256)).Encode(img)
+
+        case ProfileTrueColor:
+            return NewEncoder(WithMode(ModeANSI)).Encode(img)
+
+        case ProfileKitty:
+            return ToKitty(img)
+
+        case ProfileSixel:
+            return ToSixel(img), nil
+    }
+
+    err = errors.Errorf("pixelview: Unknown colour profile %d", profile)
+    return
+}
+
+// asciiRamp goes from the lightest character to the densest one.
+const asciiRamp = " .:-=+*#%@"
+
+// asciiRow emits a character for every cell in ModeASCII, picked by the average luminance of its pixels.
+func (e *Encoder) asciiRow(row int, cells []Cell) string {
+    var b strings.Builder
+
+    for col, cell := range cells {
+        if s, ok := e.substitute(col, row, cell); ok {
+            b.WriteString(s)
+            continue
+        }
+
+        l := (luminance(Composite(cell.Fg, color.Black)) + luminance(Composite(cell.Bg, color.Black))) / 2
+        i := int(l * float64(len(asciiRamp)))
+        if i >= len(asciiRamp) {
+            i = len(asciiRamp) - 1
+        }
+
+        b.WriteByte(asciiRamp[i])
+    }
+
+    return b.String()
+}
+
+// ToKitty converts an image to the escape sequences of the kitty graphics protocol, which display it
+// at full resolution from the cursor position. The image is sent as a PNG, split in chunks
+// as the protocol requires.
+func ToKitty(img image.Image) (encoded string, err error) {
+    var buf bytes.Buffer
+    if err = png.Encode(&buf, img); err != nil {
+        return
+    }
+
+    data := base64.StdEncoding.EncodeToString(buf.Bytes())
+    var b strings.Builder
+
+    for first := true; first || len(data) > 0; first = false {
+        chunk := data
+        if len(chunk) > 4096 {
+            chunk = chunk[:4096]
+        }
+
+        data = data[len(chunk):]
+
+        more := 0
+        if len(data) > 0 {
+            more = 1
+        }
+
+        if first {
+            fmt.Fprintf(&b, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+        } else {
+            fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+        }
+    }
+
+    return b.String(), nil
+}
+
+// ToSixel converts an image to sixel graphics, which display it at full resolution from the cursor position.
+// It's reduced to 256 colours first, as most terminals don't allow more, transparent pixels are left undrawn.
+func ToSixel(img image.Image) string {
+    quantized := quantize(img, 256, rgbSpace, DitherNone, image.Point{})
+    bounds := quantized.Bounds()
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "\x1bP0;1q\"1;1;%d;%d", bounds.Dx(), bounds.Dy())
+
+    percent := func(v uint8) int {
+        return (int(v) * 100 + 127) / 0xff
+    }
+
+    for i, c := range quantized.Palette {
+        c8 := rgba8(c)
+        fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, percent(c8.R), percent(c8.G), percent(c8.B))
+    }
+
+    for top := bounds.Min.Y; top < bounds.Max.Y; top += 6 {
+        // Every band of 6 rows is drawn colour by colour, going back to its start in between
+        first := true
+
+        for i, c := range quantized.Palette {
+            if _, _, _, a := c.RGBA(); a == 0 {
+                continue
+            }
+
+            line := sixelLine(quantized, uint8(i), top)
+            if line == "" {
+                continue
+            }
+
+            if !first {
+                b.WriteString("$")
+            }
+
+            fmt.Fprintf(&b, "#%d%s", i, line)
+            first = false
+        }
+
+        b.WriteString("-")
+    }
+
+    b.WriteString("\x1b\\")
+    return b.String()
+}
+
+// sixelLine returns the sixels drawing the pixels of the given palette index in the band of 6 rows from top,
+// run-length encoded & without the empty sixels at the end. It's empty if there aren't any such pixels.
+func sixelLine(img *image.Paletted, index uint8, top int) string {
+    bounds := img.Bounds()
+    var b strings.Builder
+    var pending int
+    var run byte
+    var count int
+
+    flush := func() {
+        switch {
+            case count > 3:
+                fmt.Fprintf(&b, "!%d%c", count, run)
+
+            default:
+                b.WriteString(strings.Repeat(string(run), count))
+        }
+
+        count = 0
+    }
+
+    for x := bounds.Min.X; x < bounds.Max.X; x++ {
+        var bits byte
+        for k := 0; k < 6 && top + k < bounds.Max.Y; k++ {
+            if img.ColorIndexAt(x, top + k) == index {
+                bits |= 1 << k
+            }
+        }
+
+        if bits == 0 {
+            // Empty sixels are only written if something follows them
+            pending++
+            continue
+        }
+
+        if pending > 0 {
+            if count > 0 {
+                flush()
+            }
+
+            run, count = '?', pending
+            flush()
+            pending = 0
+        }
+
+        ch := 63 + bits
+        if count > 0 && ch != run {
+            flush()
+        }
+
+        run = ch
+        count++
+    }
+
+    if count > 0 {
+        flush()
+    }
+
+    return b.String()
+}
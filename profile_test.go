@@ -0,0 +1,32 @@
+package pxl
+
+import (
+    "regexp"
+    "testing"
+)
+
+func TestEncodeFor(t *testing.T) {
+    shapes := map[ColorProfile]string{
+        ProfileASCII:     `^[ .:\-=+*#%@]{2}\n$`,
+        ProfileANSI16:    `^(\x1b\[(3[0-7]|9[0-7]);(4[0-7]|10[0-7])m▀){2}\x1b\[0m\n$`,
+        ProfileANSI256:   `^(\x1b\[38;5;\d+;48;5;\d+m▀){2}\x1b\[0m\n$`,
+        ProfileTrueColor: `^(\x1b\[38;2;\d+;\d+;\d+;48;2;\d+;\d+;\d+m▀){2}\x1b\[0m\n$`,
+        ProfileKitty:     `^\x1b_Gf=100,a=T,m=0;[A-Za-z0-9+/=]+\x1b\\$`,
+        ProfileSixel:     `^\x1bP0;1q"1;1;2;2.*-\x1b\\$`,
+    }
+
+    for profile, shape := range shapes {
+        encoded, err := EncodeFor(TestPattern(2, 2), profile)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !regexp.MustCompile(shape).MatchString(encoded) {
+            t.Errorf("profile %d: EncodeFor() = %q, want it to match %q", profile, encoded, shape)
+        }
+    }
+
+    if _, err := EncodeFor(TestPattern(2, 2), ProfileSixel + 1); err == nil {
+        t.Error("EncodeFor() of an unknown profile succeeded")
+    }
+}
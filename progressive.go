@@ -0,0 +1,169 @@
+package pxl
+
+import (
+    "bytes"
+    "image"
+    "image/jpeg"
+    "io"
+)
+
+// JPEG markers the progressive decoder looks for
+const (
+    markerSOF2 = 0xc2
+    markerSOI  = 0xd8
+    markerEOI  = 0xd9
+    markerSOS  = 0xda
+)
+
+// DecodeProgressive decodes an image read from an io.Reader, like image.Decode() does,
+// but for progressive JPEGs it calls preview with the image decoded from the scans read so far
+// whenever another one arrives, so a blurry version can be shown while the rest is downloaded.
+// Interlaced PNGs are previewed the same way whenever another of their passes arrives, every pixel
+// which hasn't arrived yet taking the colour of the nearest one above & to the left of it.
+// Other images are decoded once they have been read in full, without calling preview.
+func DecodeProgressive(reader io.Reader, preview func(img image.Image)) (img image.Image, err error) {
+    var buf []byte
+    scanner := progressiveScanner{}
+    interlaced := interlaceScanner{}
+    chunk := make([]byte, 32 * 1024)
+
+    for {
+        n, rerr := reader.Read(chunk)
+        buf = append(buf, chunk[:n]...)
+
+        for _, end := range scanner.advance(buf) {
+            if partial, err := decodeScans(buf[:end]); err == nil && preview != nil {
+                preview(partial)
+            }
+        }
+
+        for _, partial := range interlaced.advance(buf) {
+            if preview != nil {
+                preview(partial)
+            }
+        }
+
+        if rerr == io.EOF {
+            break
+        }
+
+        if rerr != nil {
+            return nil, rerr
+        }
+    }
+
+    img, _, err = image.Decode(bytes.NewReader(buf))
+    return
+}
+
+// decodeScans decodes the first scans of a progressive JPEG, ending the data early
+// makes the decoder build the image from the coefficients it has so far.
+func decodeScans(data []byte) (image.Image, error) {
+    terminated := make([]byte, len(data), len(data) + 2)
+    copy(terminated, data)
+
+    return jpeg.Decode(bytes.NewReader(append(terminated, 0xff, markerEOI)))
+}
+
+// progressiveScanner walks the segments of a JPEG as its data arrives,
+// to find where each scan of a progressive one ends.
+type progressiveScanner struct {
+    pos   int
+    scans int
+    done  bool
+
+    // Where the search for the end of the scan at pos carries on from, when it's a scan
+    search int
+}
+
+// advance parses as much of buf as it can, returning the offsets at which a complete scan ended.
+// It gives up on anything which isn't a progressive JPEG.
+func (s *progressiveScanner) advance(buf []byte) (ends []int) {
+    if s.pos == 0 && !s.done {
+        if len(buf) < 2 {
+            return
+        }
+
+        if buf[0] != 0xff || buf[1] != markerSOI {
+            s.done = true
+            return
+        }
+
+        s.pos = 2
+    }
+
+    for !s.done {
+        // Markers may be preceded by any number of 0xff fill bytes
+        start := s.pos
+        for start + 1 < len(buf) && buf[start] == 0xff && buf[start + 1] == 0xff {
+            start++
+        }
+
+        if start + 1 >= len(buf) {
+            return
+        }
+
+        if buf[start] != 0xff {
+            s.done = true
+            return
+        }
+
+        marker := buf[start + 1]
+        switch {
+            case marker == markerEOI:
+                s.done = true
+                return
+
+            case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+                s.pos = start + 2
+                continue
+        }
+
+        if start + 3 >= len(buf) {
+            return
+        }
+
+        end := start + 2 + (int(buf[start + 2]) << 8) + int(buf[start + 3])
+        if end > len(buf) {
+            return
+        }
+
+        if marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc {
+            if marker != markerSOF2 {
+                s.done = true
+                return
+            }
+        }
+
+        if marker != markerSOS {
+            s.pos = end
+            continue
+        }
+
+        if s.search == 0 {
+            if s.scans > 0 {
+                ends = append(ends, start)
+            }
+
+            s.search = end
+        }
+
+        // The entropy coded data runs until the next marker, other than a restart marker
+        for ; s.search + 1 < len(buf); s.search++ {
+            if buf[s.search] == 0xff && buf[s.search + 1] != 0 && (buf[s.search + 1] < 0xd0 || buf[s.search + 1] > 0xd7) {
+                break
+            }
+        }
+
+        if s.search + 1 >= len(buf) {
+            // Wait for the rest of the scan
+            s.pos = start
+            return
+        }
+
+        s.scans++
+        s.pos, s.search = s.search, 0
+    }
+
+    return
+}
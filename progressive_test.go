@@ -0,0 +1,282 @@
+package pxl
+
+import (
+    "bytes"
+    "compress/zlib"
+    "encoding/binary"
+    "image"
+    "image/png"
+    "io"
+    "math/rand"
+    "testing"
+)
+
+// interlacedPNG writes an image as an Adam7 interlaced 8 bit RGBA PNG, which image/png can't write,
+// with the compressed data split into IDAT chunks of chunkSize bytes.
+func interlacedPNG(img *image.NRGBA, chunkSize int) []byte {
+    size := img.Rect.Size()
+
+    var raw []byte
+    for _, p := range adam7 {
+        for y := p.y; y < size.Y; y += p.dy {
+            if p.x >= size.X {
+                break
+            }
+
+            raw = append(raw, 0)
+            for x := p.x; x < size.X; x += p.dx {
+                raw = append(raw, img.Pix[img.PixOffset(x, y):img.PixOffset(x, y) + 4]...)
+            }
+        }
+    }
+
+    var data bytes.Buffer
+    w := zlib.NewWriter(&data)
+    w.Write(raw)
+    w.Close()
+
+    header := make([]byte, 13)
+    binary.BigEndian.PutUint32(header, uint32(size.X))
+    binary.BigEndian.PutUint32(header[4:], uint32(size.Y))
+    header[8], header[9], header[12] = 8, 6, 1
+
+    var file bytes.Buffer
+    file.Write(pngSignature)
+    writeChunk(&file, "IHDR", header)
+
+    compressed := data.Bytes()
+    for len(compressed) > 0 {
+        n := chunkSize
+        if n > len(compressed) {
+            n = len(compressed)
+        }
+
+        writeChunk(&file, "IDAT", compressed[:n])
+        compressed = compressed[n:]
+    }
+
+    writeChunk(&file, "IEND", nil)
+    return file.Bytes()
+}
+
+// progressiveJPEG writes a greyscale progressive JPEG with a flat 8 by 8 block for each level, side by side,
+// which image/jpeg can't write. The DC coefficients come in the first scan, the AC ones, which are all zero,
+// in two more scans of spectral bands.
+func progressiveJPEG(levels []uint8) []byte {
+    var file bytes.Buffer
+    segment := func(marker byte, data ...byte) {
+        file.Write([]byte{0xff, marker, byte((len(data) + 2) >> 8), byte(len(data) + 2)})
+        file.Write(data)
+    }
+
+    file.Write([]byte{0xff, markerSOI})
+
+    // Every coefficient is quantized by 1
+    segment(0xdb, append([]byte{0}, bytes.Repeat([]byte{1}, 64)...)...)
+
+    width := len(levels) * 8
+    segment(markerSOF2, 8, 0, 8, byte(width >> 8), byte(width), 1, 1, 0x11, 0)
+
+    // DC differences of every size take a 4 bit code, the AC scans only ever hold an end of band, a 0 bit
+    segment(0xc4, append([]byte{0x00, 0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11)...)
+    segment(0xc4, 0x10, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+    var acc, bits uint
+    write := func(v, n uint) {
+        for i := int(n) - 1; i >= 0; i-- {
+            acc, bits = acc << 1 | v >> uint(i) & 1, bits + 1
+            if bits == 8 {
+                file.WriteByte(byte(acc))
+                if acc == 0xff {
+                    file.WriteByte(0)
+                }
+
+                acc, bits = 0, 0
+            }
+        }
+    }
+
+    flush := func() {
+        for bits != 0 {
+            write(1, 1)
+        }
+    }
+
+    segment(markerSOS, 1, 1, 0x00, 0, 0, 0)
+    prev := 0
+    for _, level := range levels {
+        // A flat block's pixels are its DC coefficient divided by 8, plus 128
+        dc := (int(level) - 128) * 8
+        diff := dc - prev
+        prev = dc
+
+        size, magnitude := uint(0), diff
+        if magnitude < 0 {
+            magnitude = -magnitude
+        }
+
+        for magnitude >> size != 0 {
+            size++
+        }
+
+        extra := uint(diff)
+        if diff < 0 {
+            extra = uint(diff + 1 << size - 1)
+        }
+
+        write(size, 4)
+        write(extra, size)
+    }
+    flush()
+
+    for _, band := range [][2]byte{{1, 5}, {6, 63}} {
+        segment(markerSOS, 1, 1, 0x00, band[0], band[1], 0)
+        for range levels {
+            write(0, 1)
+        }
+        flush()
+    }
+
+    file.Write([]byte{0xff, markerEOI})
+    return file.Bytes()
+}
+
+// noise returns a w by h image of random opaque pixels, which don't compress.
+func noise(w, h int, seed int64) *image.NRGBA {
+    r := rand.New(rand.NewSource(seed))
+    img := image.NewNRGBA(image.Rect(0, 0, w, h))
+    for i := range img.Pix {
+        img.Pix[i] = uint8(r.Intn(256))
+        if i % 4 == 3 {
+            img.Pix[i] = 0xff
+        }
+    }
+
+    return img
+}
+
+// pieceReader hands out its data n bytes at a time, like a slow download.
+type pieceReader struct {
+    data []byte
+    n    int
+}
+
+func (r *pieceReader) Read(p []byte) (int, error) {
+    if len(r.data) == 0 {
+        return 0, io.EOF
+    }
+
+    if len(p) > r.n {
+        p = p[:r.n]
+    }
+
+    n := copy(p, r.data)
+    r.data = r.data[n:]
+    return n, nil
+}
+
+func TestDecodeProgressiveInterlacedPNG(t *testing.T) {
+    img := noise(32, 32, 1)
+    file := interlacedPNG(img, 256)
+
+    var previews []image.Image
+    decoded, err := DecodeProgressive(&pieceReader{file, 200}, func(preview image.Image) {
+        previews = append(previews, preview)
+    })
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for y := 0; y < 32; y++ {
+        for x := 0; x < 32; x++ {
+            if !SameCell(decoded.At(x, y), img.At(x, y)) {
+                t.Fatalf("pixel %d,%d decoded as %v, want %v", x, y, decoded.At(x, y), img.At(x, y))
+            }
+        }
+    }
+
+    if len(previews) == 0 || len(previews) >= len(adam7) {
+        t.Fatalf("got %d previews, want between 1 & %d", len(previews), len(adam7) - 1)
+    }
+
+    // Whatever the passes, the top-left pixel is always known & each block takes its colour
+    first := previews[0]
+    if first.Bounds() != img.Rect || !SameCell(first.At(0, 0), img.At(0, 0)) || !SameCell(first.At(1, 1), img.At(0, 0)) {
+        t.Errorf("first preview doesn't fill its blocks with the first pass")
+    }
+}
+
+func TestDecodeProgressiveJPEG(t *testing.T) {
+    levels := []uint8{0x10, 0x80, 0xf0, 0x40}
+    file := progressiveJPEG(levels)
+
+    var previews []image.Image
+    decoded, err := DecodeProgressive(&pieceReader{file, 16}, func(img image.Image) {
+        previews = append(previews, img)
+    })
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(previews) < 2 {
+        t.Fatalf("DecodeProgressive() previewed %d times, want at least twice", len(previews))
+    }
+
+    // The DC scan alone gives every block its level already
+    for _, img := range append(previews[:1], decoded) {
+        for i, level := range levels {
+            if y, _, _, _ := img.At(i * 8 + 3, 4).RGBA(); uint8(y >> 8) != level {
+                t.Errorf("block %d of %v is %#x, want %#x", i, img.Bounds(), y >> 8, level)
+            }
+        }
+    }
+}
+
+func TestDecodeProgressiveOtherImages(t *testing.T) {
+    var file bytes.Buffer
+    if err := png.Encode(&file, TestPattern(8, 8)); err != nil {
+        t.Fatal(err)
+    }
+
+    called := false
+    decoded, err := DecodeProgressive(&pieceReader{file.Bytes(), 16}, func(image.Image) {
+        called = true
+    })
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if decoded.Bounds() != image.Rect(0, 0, 8, 8) {
+        t.Errorf("decoded image is %v, want 8 by 8", decoded.Bounds())
+    }
+
+    if called {
+        t.Error("DecodeProgressive() previewed a PNG which isn't interlaced")
+    }
+}
+
+func TestInterlaceScannerInflatesRarely(t *testing.T) {
+    file := interlacedPNG(noise(64, 64, 2), 64)
+
+    var s interlaceScanner
+    var inflations, previews int
+    for end := 1; end <= len(file); end++ {
+        before := s.inflatedFrom
+        previews += len(s.advance(file[:end]))
+        if s.inflatedFrom != before {
+            inflations++
+        }
+    }
+
+    if previews == 0 {
+        t.Error("no previews of an interlaced PNG read a byte at a time")
+    }
+
+    // Decompressing whenever a chunk arrives would take hundreds of times for this one
+    if inflations > 100 {
+        t.Errorf("decompressed %d times for %d bytes", inflations, len(file))
+    }
+}
@@ -0,0 +1,246 @@
+// Package pxlico adds Windows icons (.ico), like the favicons of websites, to the formats
+// image.Decode() understands, so pxl.FromFile() & pxl.FromReader() can convert them too.
+// It only needs to be imported for its side effect:
+//
+//     import _ "github.com/abdfnx/pxl/pxlico"
+//
+// An icon holds the same picture at several sizes, the largest one is the one decoded.
+package pxlico
+
+import (
+    "bytes"
+    "encoding/binary"
+    "image"
+    "image/color"
+    "image/png"
+    "io"
+
+    "github.com/abdfnx/pxl"
+    "github.com/pkg/errors"
+)
+
+// The signature icons start with: a reserved zero & the resource type 1
+const signature = "\x00\x00\x01\x00"
+
+// Sizes of the parts of an icon
+const (
+    headerLen    = 6
+    entryLen     = 16
+    dibHeaderLen = 40
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func init() {
+    pxl.RegisterFormat("ico", signature, Decode, DecodeConfig)
+}
+
+// entry is an image in the directory of an icon.
+type entry struct {
+    width, height int
+    bpp           int
+    data          []byte
+}
+
+// Decode reads an icon & returns the largest image it holds.
+// Images may be stored as PNGs or as uncompressed bitmaps with 1, 4, 8, 24 or 32 bits per pixel.
+func Decode(r io.Reader) (image.Image, error) {
+    e, err := largest(r)
+    if err != nil {
+        return nil, err
+    }
+
+    if bytes.HasPrefix(e.data, pngSignature) {
+        return png.Decode(bytes.NewReader(e.data))
+    }
+
+    return decodeBitmap(e.data)
+}
+
+// DecodeConfig returns the size of the largest image of an icon, without decoding it.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+    e, err := largest(r)
+    if err != nil {
+        return image.Config{}, err
+    }
+
+    if bytes.HasPrefix(e.data, pngSignature) {
+        return png.DecodeConfig(bytes.NewReader(e.data))
+    }
+
+    width, height, _, err := bitmapHeader(e.data)
+    if err != nil {
+        return image.Config{}, err
+    }
+
+    return image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}, nil
+}
+
+// largest reads the directory of an icon & picks the image with the most pixels,
+// or the most bits per pixel among those of the same size.
+func largest(r io.Reader) (best entry, err error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return
+    }
+
+    if len(data) < headerLen || string(data[:4]) != signature {
+        return best, errors.New("pixelview: Can't decode icon without a valid header")
+    }
+
+    count := int(binary.LittleEndian.Uint16(data[4:6]))
+    if count == 0 || len(data) < headerLen + count * entryLen {
+        return best, errors.New("pixelview: Can't decode icon with a truncated directory")
+    }
+
+    for i := 0; i < count; i++ {
+        d := data[headerLen + i * entryLen:]
+
+        // A size of 0 stands for 256 pixels
+        e := entry{int(d[0]), int(d[1]), int(binary.LittleEndian.Uint16(d[6:8])), nil}
+        if e.width == 0 {
+            e.width = 256
+        }
+
+        if e.height == 0 {
+            e.height = 256
+        }
+
+        size, offset := binary.LittleEndian.Uint32(d[8:12]), binary.LittleEndian.Uint32(d[12:16])
+        if uint64(offset) + uint64(size) > uint64(len(data)) {
+            return best, errors.New("pixelview: Can't decode icon with an image past the end of the file")
+        }
+
+        e.data = data[offset:offset + size]
+
+        area, bestArea := e.width * e.height, best.width * best.height
+        if best.data == nil || area > bestArea || (area == bestArea && e.bpp > best.bpp) {
+            best = e
+        }
+    }
+
+    return
+}
+
+// bitmapHeader reads the size & bits per pixel of an image stored as a bitmap without its file header.
+// The height of the bitmap counts the rows of the transparency mask as well, which is left out.
+func bitmapHeader(data []byte) (width, height, bpp int, err error) {
+    if len(data) < dibHeaderLen || binary.LittleEndian.Uint32(data[0:4]) < dibHeaderLen {
+        return 0, 0, 0, errors.New("pixelview: Can't decode icon image without a valid bitmap header")
+    }
+
+    width = int(int32(binary.LittleEndian.Uint32(data[4:8])))
+    height = int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+    bpp = int(binary.LittleEndian.Uint16(data[14:16]))
+
+    if width <= 0 || height <= 0 {
+        return 0, 0, 0, errors.New("pixelview: Can't decode icon image with an invalid size")
+    }
+
+    switch bpp {
+        case 1, 4, 8, 24, 32:
+
+        default:
+            return 0, 0, 0, errors.Errorf("pixelview: Can't decode icon image with %d bits per pixel", bpp)
+    }
+
+    if compression := binary.LittleEndian.Uint32(data[16:20]); compression != 0 {
+        return 0, 0, 0, errors.New("pixelview: Can't decode compressed icon image")
+    }
+
+    return
+}
+
+// decodeBitmap decodes an image stored as a bitmap. Its rows run from the bottom up
+// & are followed by a 1 bit mask of the transparent pixels, which only 32 bit images may do without.
+func decodeBitmap(data []byte) (image.Image, error) {
+    width, height, bpp, err := bitmapHeader(data)
+    if err != nil {
+        return nil, err
+    }
+
+    pos := int(binary.LittleEndian.Uint32(data[0:4]))
+    if pos > len(data) {
+        return nil, errors.New("pixelview: Can't decode icon image without a valid bitmap header")
+    }
+
+    var palette color.Palette
+    if bpp <= 8 {
+        colors := int(binary.LittleEndian.Uint32(data[32:36]))
+        if colors == 0 || colors > 1 << bpp {
+            colors = 1 << bpp
+        }
+
+        if len(data) < pos + colors * 4 {
+            return nil, errors.New("pixelview: Can't decode icon image with a truncated palette")
+        }
+
+        for i := 0; i < colors; i++ {
+            c := data[pos + i * 4:]
+            palette = append(palette, color.NRGBA{c[2], c[1], c[0], 0xff})
+        }
+
+        pos += colors * 4
+    }
+
+    stride := (width * bpp + 31) / 32 * 4
+    maskStride := (width + 31) / 32 * 4
+
+    pixels := data[pos:]
+    if len(pixels) < stride || len(pixels) < height * stride {
+        return nil, errors.New("pixelview: Can't decode icon image with truncated pixels")
+    }
+
+    mask := pixels[height * stride:]
+    if len(mask) < height * maskStride {
+        mask = nil
+    }
+
+    img := image.NewNRGBA(image.Rect(0, 0, width, height))
+    hasAlpha := false
+
+    for y := 0; y < height; y++ {
+        row := pixels[(height - 1 - y) * stride:]
+
+        for x := 0; x < width; x++ {
+            var c color.NRGBA
+
+            switch bpp {
+                case 32:
+                    c = color.NRGBA{row[x * 4 + 2], row[x * 4 + 1], row[x * 4], row[x * 4 + 3]}
+                    hasAlpha = hasAlpha || c.A != 0
+
+                case 24:
+                    c = color.NRGBA{row[x * 3 + 2], row[x * 3 + 1], row[x * 3], 0xff}
+
+                default:
+                    bit := x * bpp
+                    index := int(row[bit / 8] >> (8 - bpp - bit % 8)) & (1 << bpp - 1)
+                    if index < len(palette) {
+                        c = palette[index].(color.NRGBA)
+                    }
+            }
+
+            img.SetNRGBA(x, y, c)
+        }
+    }
+
+    // 32 bit images with an alpha channel don't need the mask,
+    // older ones leave the alpha empty & rely on it like all the others
+    if bpp == 32 && hasAlpha {
+        return img, nil
+    }
+
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            i := img.PixOffset(x, y)
+            img.Pix[i + 3] = 0xff
+
+            if mask != nil && mask[(height - 1 - y) * maskStride + x / 8] & (0x80 >> (x % 8)) != 0 {
+                img.Pix[i], img.Pix[i + 1], img.Pix[i + 2], img.Pix[i + 3] = 0, 0, 0, 0
+            }
+        }
+    }
+
+    return img, nil
+}
@@ -0,0 +1,117 @@
+package pxlico
+
+import (
+    "bytes"
+    "encoding/binary"
+    "image"
+    "image/color"
+    "image/png"
+    "testing"
+
+    "github.com/abdfnx/pxl"
+)
+
+// icon builds an icon out of images already stored the way icons store them.
+func icon(sizes []image.Point, images ...[]byte) []byte {
+    var buf bytes.Buffer
+    buf.WriteString(signature)
+    binary.Write(&buf, binary.LittleEndian, uint16(len(images)))
+
+    offset := headerLen + len(images) * entryLen
+    for i, data := range images {
+        buf.Write([]byte{byte(sizes[i].X), byte(sizes[i].Y), 0, 0, 1, 0, 32, 0})
+        binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+        binary.Write(&buf, binary.LittleEndian, uint32(offset))
+        offset += len(data)
+    }
+
+    for _, data := range images {
+        buf.Write(data)
+    }
+
+    return buf.Bytes()
+}
+
+// bitmap stores a w by h image of a single colour as a 24 bit bitmap, with its top left pixel masked out.
+func bitmap(w, h int, c color.NRGBA) []byte {
+    var buf bytes.Buffer
+    binary.Write(&buf, binary.LittleEndian, []int32{dibHeaderLen, int32(w), int32(h * 2)})
+    binary.Write(&buf, binary.LittleEndian, []uint16{1, 24})
+    buf.Write(make([]byte, dibHeaderLen - 16))
+
+    stride, maskStride := (w * 3 + 3) / 4 * 4, (w + 31) / 32 * 4
+    for y := 0; y < h; y++ {
+        row := make([]byte, stride)
+        for x := 0; x < w; x++ {
+            row[x * 3], row[x * 3 + 1], row[x * 3 + 2] = c.B, c.G, c.R
+        }
+        buf.Write(row)
+    }
+
+    // The rows run from the bottom up, so the top one is last
+    mask := make([]byte, h * maskStride)
+    mask[(h - 1) * maskStride] = 0x80
+    buf.Write(mask)
+
+    return buf.Bytes()
+}
+
+func TestDecodeLargest(t *testing.T) {
+    red := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+    for i := 0; i < len(red.Pix); i += 4 {
+        copy(red.Pix[i:], []byte{0xff, 0, 0, 0xff})
+    }
+
+    var large bytes.Buffer
+    if err := png.Encode(&large, red); err != nil {
+        t.Fatal(err)
+    }
+
+    green := color.NRGBA{0, 0xff, 0, 0xff}
+    data := icon([]image.Point{{2, 2}, {4, 4}, {4, 2}}, bitmap(2, 2, green), large.Bytes(), bitmap(4, 2, green))
+
+    config, err := DecodeConfig(bytes.NewReader(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if config.Width != 4 || config.Height != 4 {
+        t.Errorf("DecodeConfig() = %d by %d, want the largest image, 4 by 4", config.Width, config.Height)
+    }
+
+    encoded, err := pxl.FromReader(bytes.NewReader(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := pxl.FromImage(red); encoded != want {
+        t.Errorf("FromReader() of an icon = %q, want the largest image %q", encoded, want)
+    }
+}
+
+func TestDecodeBitmap(t *testing.T) {
+    green := color.NRGBA{0, 0xff, 0, 0xff}
+    img, err := Decode(bytes.NewReader(icon([]image.Point{{3, 2}}, bitmap(3, 2, green))))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if img.Bounds() != image.Rect(0, 0, 3, 2) {
+        t.Fatalf("Decode() = %v image, want 3 by 2", img.Bounds())
+    }
+
+    for _, p := range []image.Point{{0, 0}, {1, 0}, {2, 1}} {
+        want := color.Color(green)
+        if p == (image.Point{}) {
+            want = color.NRGBA{}
+        }
+
+        if got := img.At(p.X, p.Y); got != want {
+            t.Errorf("pixel %v = %v, want %v", p, got, want)
+        }
+    }
+
+    if _, err = Decode(bytes.NewReader([]byte(signature + "\x01\x00"))); err == nil {
+        t.Error("Decode() of an icon with a truncated directory succeeded")
+    }
+}
@@ -0,0 +1,72 @@
+// Package pxltcell converts images into styled cells for programs that draw
+// onto a tcell.Screen directly, without going through tview's tag parser.
+package pxltcell
+
+import (
+    "image"
+    "image/color"
+
+    "github.com/abdfnx/pxl"
+    "github.com/gdamore/tcell/v2"
+)
+
+// Cell is a single rune along with the style it should be drawn with.
+// Draw is false when both of its pixels are fully transparent, so the cell can be left as is on the screen,
+// like when laying sprites over something already drawn.
+type Cell struct {
+    Rune  rune
+    Style tcell.Style
+    Draw  bool
+}
+
+// FromImageCells converts an image into cells, like pxl.FromImage() does,
+// but returns them row by row in a flat slice along with the width of a row,
+// so cell (x, y) is cells[y * width + x].
+func FromImageCells(img image.Image) (cells []Cell, width int, err error) {
+    pixels, err := pxl.DecodeToPixels(img)
+    if err != nil {
+        return
+    }
+
+    width = img.Bounds().Dx()
+    cells = make([]Cell, 0, width * len(pixels))
+
+    for _, row := range pixels {
+        for _, cell := range row {
+            cells = append(cells, Cell{'▀', Style(cell.Fg, cell.Bg), opaque(cell.Fg) || opaque(cell.Bg)})
+        }
+    }
+
+    return
+}
+
+// Style builds the tcell.Style used to draw a fg & bg colour pair.
+func Style(fg, bg color.Color) tcell.Style {
+    return tcell.StyleDefault.Foreground(Color(fg)).Background(Color(bg))
+}
+
+// Color converts a colour into a true-colour tcell.Color. Fully transparent colours are tcell.ColorDefault,
+// so the terminal's own colour shows through, like the - pxl.FromImage() writes for them.
+func Color(c color.Color) tcell.Color {
+    if c == nil || !opaque(c) {
+        return tcell.ColorDefault
+    }
+
+    r, g, b, _ := c.RGBA()
+    return tcell.NewRGBColor(channel(r), channel(g), channel(b))
+}
+
+// opaque reports whether a colour isn't fully transparent.
+func opaque(c color.Color) bool {
+    _, _, _, a := c.RGBA()
+    return a > 0
+}
+
+// channel converts a 16 bit channel to 8 bits, clamping values outside the documented range.
+func channel(v uint32) int32 {
+    if v > 0xffff {
+        return 0xff
+    }
+
+    return int32(v >> 8)
+}
@@ -0,0 +1,62 @@
+package pxltcell
+
+import (
+    "image"
+    "image/color"
+    "testing"
+
+    "github.com/gdamore/tcell/v2"
+)
+
+func TestFromImageCellsStyles(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 4))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(0, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    img.Set(1, 0, color.NRGBA{0x12, 0x34, 0x56, 0xff})
+    img.Set(1, 1, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+
+    cells, width, err := FromImageCells(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if width != 2 || len(cells) != 4 {
+        t.Fatalf("got %d cells %d wide, want 4 cells 2 wide", len(cells), width)
+    }
+
+    want := [][2]tcell.Color{
+        {tcell.NewRGBColor(0xff, 0, 0), tcell.NewRGBColor(0, 0, 0xff)},
+        {tcell.NewRGBColor(0x12, 0x34, 0x56), tcell.NewRGBColor(0xff, 0xff, 0xff)},
+    }
+
+    for i, colors := range want {
+        fg, bg, _ := cells[i].Style.Decompose()
+        if cells[i].Rune != '▀' || fg != colors[0] || bg != colors[1] {
+            t.Errorf("cell %d is %q in %v on %v, want ▀ in %v on %v", i, cells[i].Rune, fg, bg, colors[0], colors[1])
+        }
+    }
+
+    if _, _, err = FromImageCells(image.NewNRGBA(image.Rect(0, 0, 2, 3))); err == nil {
+        t.Error("FromImageCells() of an uneven image succeeded")
+    }
+}
+
+func TestFromImageCellsTransparency(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(1, 1, color.NRGBA{0x12, 0x34, 0x56, 0})
+
+    cells, _, err := FromImageCells(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Transparent halves take the terminal's default colour rather than black
+    if fg, bg, _ := cells[0].Style.Decompose(); fg != tcell.NewRGBColor(0xff, 0, 0) || bg != tcell.ColorDefault || !cells[0].Draw {
+        t.Errorf("cell over a transparent pixel is drawn %v in %v on %v, want true in red on the default", cells[0].Draw, fg, bg)
+    }
+
+    if fg, bg, _ := cells[1].Style.Decompose(); fg != tcell.ColorDefault || bg != tcell.ColorDefault || cells[1].Draw {
+        t.Errorf("cell of transparent pixels is drawn %v in %v on %v, want false in the default colours", cells[1].Draw, fg, bg)
+    }
+}
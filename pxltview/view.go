@@ -0,0 +1,151 @@
+// Package pxltview provides a tview widget which displays an image,
+// redrawing it whenever the space it's given changes.
+package pxltview
+
+import (
+    "image"
+    "image/color"
+    "sync"
+
+    "github.com/abdfnx/pxl"
+    "github.com/abdfnx/pxl/pxltcell"
+    "github.com/gdamore/tcell/v2"
+    "github.com/rivo/tview"
+)
+
+// ImageView is a tview.Primitive which displays an image.
+// Without fitting, the image is drawn at its own size from the top-left corner & cut off where it doesn't fit.
+type ImageView struct {
+    *tview.Box
+
+    mu       sync.Mutex
+    img      image.Image
+    fit      bool
+    sampling pxl.Sampling
+
+    // The cells last drawn, kept until the image or the size of the view changes
+    cells []pxltcell.Cell
+    width int
+    size  image.Point
+}
+
+// NewImageView returns a new view displaying img.
+func NewImageView(img image.Image) *ImageView {
+    return &ImageView{
+        Box: tview.NewBox(),
+        img: img,
+    }
+}
+
+// SetImage replaces the image displayed by the view.
+func (v *ImageView) SetImage(img image.Image) *ImageView {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    v.img = img
+    v.cells = nil
+    return v
+}
+
+// SetFit makes the view scale the image to fit the space it's given, keeping its aspect ratio.
+func (v *ImageView) SetFit(fit bool) *ImageView {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    v.fit = fit
+    v.cells = nil
+    return v
+}
+
+// SetSampling selects which pixel stands for each area of the image when fitting scales it down,
+// the default is pxl.SampleCenter.
+func (v *ImageView) SetSampling(sampling pxl.Sampling) *ImageView {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    v.sampling = sampling
+    v.cells = nil
+    return v
+}
+
+// Draw draws the image onto the screen, re-encoding it if the size of the view changed.
+// Cells whose pixels are both fully transparent are left as they are, showing what's behind the view.
+func (v *ImageView) Draw(screen tcell.Screen) {
+    v.Box.DrawForSubclass(screen, v)
+
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    x, y, w, h := v.GetInnerRect()
+    if v.img == nil || w <= 0 || h <= 0 {
+        return
+    }
+
+    if size := image.Pt(w, h); v.cells == nil || size != v.size {
+        cells, width, err := pxltcell.FromImageCells(v.resample(w, h))
+        if err != nil {
+            return
+        }
+
+        v.cells, v.width, v.size = cells, width, size
+    }
+
+    for i, cell := range v.cells {
+        if !cell.Draw {
+            continue
+        }
+
+        screen.SetContent(x + i % v.width, y + i / v.width, cell.Rune, nil, cell.Style)
+    }
+}
+
+// resample returns the part of the image to draw in a view of w by h cells,
+// scaled to fit it if fitting is on. Its height is always even, since a cell holds two rows of pixels.
+func (v *ImageView) resample(w, h int) image.Image {
+    bounds := v.img.Bounds()
+
+    scale := 1.0
+    if v.fit && bounds.Dx() > 0 && bounds.Dy() > 0 {
+        scale = float64(w) / float64(bounds.Dx())
+        if s := float64(h * 2) / float64(bounds.Dy()); s < scale {
+            scale = s
+        }
+    }
+
+    size := image.Pt(int(float64(bounds.Dx()) * scale), int(float64(bounds.Dy()) * scale))
+    if size.X > w {
+        size.X = w
+    }
+
+    if size.Y > h * 2 {
+        size.Y = h * 2
+    }
+
+    size.Y -= size.Y % 2
+
+    offset := 0.5
+    if v.sampling == pxl.SampleTruncate {
+        offset = 0
+    }
+
+    return &resampled{v.img, size, scale, offset}
+}
+
+// resampled is an image scaled by nearest neighbour sampling & cropped to size.
+type resampled struct {
+    image.Image
+    size  image.Point
+    scale float64
+
+    // Where in the area of each pixel it's sampled, from 0 for the top-left corner to 0.5 for the center
+    offset float64
+}
+
+func (r *resampled) Bounds() image.Rectangle {
+    return image.Rectangle{Max: r.size}
+}
+
+func (r *resampled) At(x, y int) color.Color {
+    min := r.Image.Bounds().Min
+    return r.Image.At(min.X + int((float64(x) + r.offset) / r.scale), min.Y + int((float64(y) + r.offset) / r.scale))
+}
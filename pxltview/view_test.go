@@ -0,0 +1,73 @@
+package pxltview
+
+import (
+    "image"
+    "image/color"
+    "testing"
+
+    "github.com/gdamore/tcell/v2"
+)
+
+// drawn draws a view onto a simulated screen of w by h cells & returns its cells.
+func drawn(t *testing.T, v *ImageView, w, h int) []tcell.SimCell {
+    screen := tcell.NewSimulationScreen("UTF-8")
+    if err := screen.Init(); err != nil {
+        t.Fatal(err)
+    }
+    defer screen.Fini()
+
+    screen.SetSize(w, h)
+    v.SetRect(0, 0, w, h)
+    v.Draw(screen)
+    screen.Show()
+
+    cells, _, _ := screen.GetContents()
+    return cells
+}
+
+// text returns the runes shown by cells.
+func text(cells []tcell.SimCell) string {
+    runes := make([]rune, len(cells))
+    for i, cell := range cells {
+        runes[i] = cell.Runes[0]
+    }
+
+    return string(runes)
+}
+
+func TestImageViewSkipsTransparentCells(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+    img.Set(1, 0, color.White)
+    img.Set(2, 1, color.Black)
+
+    got := text(drawn(t, NewImageView(img), 3, 1))
+    if want := " ▀▀"; got != want {
+        t.Errorf("view shows %q, want %q", got, want)
+    }
+}
+
+func TestImageViewFit(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+    for i := range img.Pix {
+        img.Pix[i] = 0xff
+    }
+
+    got := text(drawn(t, NewImageView(img).SetFit(true), 6, 2))
+    if want := "▀▀▀▀  ▀▀▀▀  "; got != want {
+        t.Errorf("fitted view shows %q, want %q", got, want)
+    }
+}
+
+func TestImageViewDraw(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(0, 1, color.NRGBA{0, 0, 0xff, 0xff})
+    img.Set(1, 0, color.White)
+    img.Set(1, 1, color.White)
+
+    cells := drawn(t, NewImageView(img), 2, 1)
+    fg, bg, _ := cells[0].Style.Decompose()
+    if cells[0].Runes[0] != '▀' || fg != tcell.NewRGBColor(0xff, 0, 0) || bg != tcell.NewRGBColor(0, 0, 0xff) {
+        t.Errorf("top-left cell shows %q in %v on %v, want ▀ in red on blue", cells[0].Runes[0], fg, bg)
+    }
+}
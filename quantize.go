@@ -0,0 +1,384 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "math"
+    "sort"
+
+    "github.com/pkg/errors"
+)
+
+// DominantColors returns the n most prominent colours of an image, most prominent first.
+// The colours are found by median cut quantization, fully transparent pixels are ignored.
+// Fewer than n colours are returned if the image doesn't have that many.
+func DominantColors(img image.Image, n int) (colors []color.Color, err error) {
+    if n < 1 {
+        err = errors.New("pixelview: Can't find less than one dominant colour")
+        return
+    }
+
+    hist := ColorHistogram(img)
+    delete(hist, color.RGBA{})
+
+    if len(hist) == 0 {
+        err = errors.New("pixelview: Can't find dominant colours of an empty image")
+        return
+    }
+
+    boxes := medianCut(hist, n, rgbSpace)
+    sort.SliceStable(boxes, func(i, j int) bool {
+        return boxes[i].count() > boxes[j].count()
+    })
+
+    for _, box := range boxes {
+        colors = append(colors, box.mean())
+    }
+
+    return
+}
+
+// Dither is a way of spreading out the error of reducing an image to fewer colours.
+type Dither int
+
+const (
+    // DitherNone maps every pixel to the colour its group was reduced to, which leaves visible bands.
+    DitherNone Dither = iota
+
+    // DitherFloydSteinberg diffuses the error of every pixel into its neighbours.
+    DitherFloydSteinberg
+
+    // DitherBayer4 offsets every pixel by its position in a 4x4 Bayer matrix before picking the nearest colour,
+    // for a regular crosshatch pattern.
+    DitherBayer4
+
+    // DitherBayer8 is like DitherBayer4 with an 8x8 matrix, which gives finer gradations.
+    DitherBayer8
+
+    // ditherLuma is DitherFloydSteinberg diffusing only the error in brightness, set by WithLumaDither().
+    ditherLuma
+)
+
+// quantize reduces an image to at most n colours with median cut, measuring the colours in the given space.
+// Fully transparent pixels get a palette entry of their own, so they aren't averaged with the rest.
+// Without dithering each pixel takes the colour of its box, otherwise the nearest colour of the palette.
+// Ordered dithers start their matrix phase pixels to the right & down of the top-left corner.
+func quantize(img image.Image, n int, space colorSpace, dither Dither, phase image.Point) *image.Paletted {
+    hist := ColorHistogram(img)
+    bounds := img.Bounds()
+
+    var palette color.Palette
+    index := make(map[color.RGBA]uint8, len(hist))
+
+    if _, ok := hist[color.RGBA{}]; ok && n > 1 {
+        delete(hist, color.RGBA{})
+        palette = append(palette, color.Transparent)
+        n--
+    }
+
+    if n > 256 - len(palette) {
+        n = 256 - len(palette)
+    }
+
+    if len(hist) > 0 && n > 0 {
+        for _, box := range medianCut(hist, n, space) {
+            for _, c := range box {
+                index[c.color] = uint8(len(palette))
+            }
+
+            palette = append(palette, box.mean())
+        }
+    }
+
+    quantized := image.NewPaletted(bounds, palette)
+    switch dither {
+        case DitherFloydSteinberg:
+            draw.FloydSteinberg.Draw(quantized, bounds, img, bounds.Min)
+            return quantized
+
+        case DitherBayer4:
+            orderedDither(quantized, img, bayerMatrix(4), phase)
+            return quantized
+
+        case DitherBayer8:
+            orderedDither(quantized, img, bayerMatrix(8), phase)
+            return quantized
+
+        case ditherLuma:
+            lumaDither(quantized, img)
+            return quantized
+    }
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            quantized.SetColorIndex(x, y, index[rgba8(img.At(x, y))])
+        }
+    }
+
+    return quantized
+}
+
+// orderedDither draws img onto dst, offsetting every pixel by the threshold matrix
+// at its position, shifted by phase, before picking the nearest colour of the palette.
+func orderedDither(dst *image.Paletted, img image.Image, matrix [][]float64, phase image.Point) {
+    bounds := img.Bounds()
+    size := len(matrix)
+
+    // The offsets span roughly the distance between neighbouring colours of the palette
+    spread := 0xff / math.Cbrt(float64(len(dst.Palette)))
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            c := rgba8(img.At(x, y))
+            if c.A == 0 {
+                dst.SetColorIndex(x, y, uint8(dst.Palette.Index(c)))
+                continue
+            }
+
+            offset := spread * matrix[(y - bounds.Min.Y + phase.Y) % size][(x - bounds.Min.X + phase.X) % size]
+            shift := func(v uint8) uint8 {
+                return uint8(math.Max(0, math.Min(float64(v) + offset, float64(c.A))))
+            }
+
+            dst.SetColorIndex(x, y, uint8(dst.Palette.Index(color.RGBA{shift(c.R), shift(c.G), shift(c.B), c.A})))
+        }
+    }
+}
+
+// lumaDither draws img onto dst like Floyd-Steinberg dithering does, but only diffuses the error in luma,
+// the Y of YCbCr. Adding the same amount to every channel only changes luma, so the error carried
+// into a pixel shifts its channels alike & its chroma stays the same.
+func lumaDither(dst *image.Paletted, img image.Image) {
+    bounds := img.Bounds()
+    width := bounds.Dx()
+    luma := func(c color.RGBA) float64 {
+        return 0.299 * float64(c.R) + 0.587 * float64(c.G) + 0.114 * float64(c.B)
+    }
+
+    // The errors carried into the current row & the next one
+    current, next := make([]float64, width + 2), make([]float64, width + 2)
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := 0; x < width; x++ {
+            c := rgba8(img.At(bounds.Min.X + x, y))
+            if c.A == 0 {
+                dst.SetColorIndex(bounds.Min.X + x, y, uint8(dst.Palette.Index(c)))
+                continue
+            }
+
+            offset := current[x + 1]
+            shift := func(v uint8) uint8 {
+                return uint8(math.Max(0, math.Min(math.Round(float64(v) + offset), float64(c.A))))
+            }
+
+            shifted := color.RGBA{shift(c.R), shift(c.G), shift(c.B), c.A}
+            i := dst.Palette.Index(shifted)
+            dst.SetColorIndex(bounds.Min.X + x, y, uint8(i))
+
+            // The error of what got left out by clamping is carried on too
+            diff := luma(c) + offset - luma(rgba8(dst.Palette[i]))
+            current[x + 2] += diff * 7 / 16
+            next[x] += diff * 3 / 16
+            next[x + 1] += diff * 5 / 16
+            next[x + 2] += diff * 1 / 16
+        }
+
+        current, next = next, current
+        for i := range next {
+            next[i] = 0
+        }
+    }
+}
+
+// bayerMatrix builds a size by size Bayer matrix, size being a power of 2, with thresholds
+// spread evenly between -0.5 & 0.5.
+func bayerMatrix(size int) [][]float64 {
+    index := [][]int{{0}}
+    for n := 1; n < size; n *= 2 {
+        next := make([][]int, n * 2)
+        for y := range next {
+            next[y] = make([]int, n * 2)
+            for x := range next[y] {
+                // Each quadrant repeats the smaller matrix scaled by 4, offset by 0, 2, 3 & 1
+                quadrant := [2][2]int{{0, 2}, {3, 1}}[y / n][x / n]
+                next[y][x] = index[y % n][x % n] * 4 + quadrant
+            }
+        }
+
+        index = next
+    }
+
+    matrix := make([][]float64, size)
+    for y := range matrix {
+        matrix[y] = make([]float64, size)
+        for x := range matrix[y] {
+            matrix[y][x] = (float64(index[y][x]) + 0.5) / float64(size * size) - 0.5
+        }
+    }
+
+    return matrix
+}
+
+// ColorHistogram counts the occurrences of every colour in an image, quantized to 8 bits per channel,
+// it's what DominantColors() & WithMaxColors() work from. The colours are premultiplied,
+// like those returned by RGBA(), so all fully transparent pixels are counted as color.RGBA{}.
+func ColorHistogram(img image.Image) map[color.RGBA]int {
+    hist := make(map[color.RGBA]int)
+    bounds := img.Bounds()
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            hist[rgba8(img.At(x, y))]++
+        }
+    }
+
+    return hist
+}
+
+// colorSpace gives the coordinates median cut measures a colour by.
+type colorSpace func(c color.RGBA) [3]float64
+
+// rgbSpace measures colours by their 8 bit channels as they are.
+func rgbSpace(c color.RGBA) [3]float64 {
+    return [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+}
+
+// labSpace measures colours in CIELAB, where equal distances look about equally different,
+// so dark shades aren't split apart more eagerly than light ones.
+func labSpace(c color.RGBA) [3]float64 {
+    linear := func(v uint8) float64 {
+        f := float64(v) / 0xff
+        if f <= 0.04045 {
+            return f / 12.92
+        }
+
+        return math.Pow((f + 0.055) / 1.055, 2.4)
+    }
+
+    r, g, b := linear(c.R), linear(c.G), linear(c.B)
+
+    // XYZ relative to the D65 white point
+    x := (0.4124 * r + 0.3576 * g + 0.1805 * b) / 0.95047
+    y := 0.2126 * r + 0.7152 * g + 0.0722 * b
+    z := (0.0193 * r + 0.1192 * g + 0.9505 * b) / 1.08883
+
+    f := func(t float64) float64 {
+        if t > 216.0 / 24389 {
+            return math.Cbrt(t)
+        }
+
+        return (24389.0 / 27 * t + 16) / 116
+    }
+
+    fx, fy, fz := f(x), f(y), f(z)
+    return [3]float64{116 * fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+type colorCount struct {
+    color color.RGBA
+    n     int
+    pos   [3]float64
+}
+
+// colorBox is a group of colours which median cut will either split further or average together.
+type colorBox []colorCount
+
+func (box colorBox) count() (n int) {
+    for _, c := range box {
+        n += c.n
+    }
+
+    return
+}
+
+// mean is the average colour of the box, weighted by how often each colour occurs.
+func (box colorBox) mean() color.Color {
+    var r, g, b, a, n int
+    for _, c := range box {
+        r += int(c.color.R) * c.n
+        g += int(c.color.G) * c.n
+        b += int(c.color.B) * c.n
+        a += int(c.color.A) * c.n
+        n += c.n
+    }
+
+    return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+}
+
+// widest returns the axis of the colour space with the largest range of values in the box, along with that range.
+func (box colorBox) widest() (axis int, size float64) {
+    for ax := 0; ax < 3; ax++ {
+        min, max := math.Inf(1), math.Inf(-1)
+        for _, c := range box {
+            min = math.Min(min, c.pos[ax])
+            max = math.Max(max, c.pos[ax])
+        }
+
+        if max - min > size {
+            axis, size = ax, max - min
+        }
+    }
+
+    return
+}
+
+// medianCut splits the colours of a histogram into at most n boxes,
+// by repeatedly halving the box with the widest range of colours in space at its median.
+func medianCut(hist map[color.RGBA]int, n int, space colorSpace) []colorBox {
+    box := make(colorBox, 0, len(hist))
+    for c, count := range hist {
+        box = append(box, colorCount{c, count, space(c)})
+    }
+
+    // Map iteration is random, sorting keeps the result deterministic
+    sort.Slice(box, func(i, j int) bool {
+        a, b := box[i].color, box[j].color
+        if a.R != b.R {
+            return a.R < b.R
+        }
+
+        if a.G != b.G {
+            return a.G < b.G
+        }
+
+        if a.B != b.B {
+            return a.B < b.B
+        }
+
+        return a.A < b.A
+    })
+
+    boxes := []colorBox{box}
+    for len(boxes) < n {
+        split, axis, size := -1, 0, 0.0
+        for i, box := range boxes {
+            if ax, s := box.widest(); len(box) > 1 && s > size {
+                split, axis, size = i, ax, s
+            }
+        }
+
+        if split < 0 {
+            break
+        }
+
+        box := boxes[split]
+        sort.SliceStable(box, func(i, j int) bool {
+            return box[i].pos[axis] < box[j].pos[axis]
+        })
+
+        half, seen, cut := box.count() / 2, 0, 1
+        for i := 0; i < len(box) - 1; i++ {
+            seen += box[i].n
+            cut = i + 1
+            if seen >= half {
+                break
+            }
+        }
+
+        boxes[split] = box[:cut:cut]
+        boxes = append(boxes, box[cut:])
+    }
+
+    return boxes
+}
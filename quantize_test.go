@@ -0,0 +1,210 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+    "regexp"
+    "testing"
+)
+
+func TestDominantColors(t *testing.T) {
+    red, blue := color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}
+
+    img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+    for y := 0; y < 10; y++ {
+        for x := 0; x < 10; x++ {
+            c := red
+            if x >= 7 {
+                c = blue
+            }
+
+            img.SetRGBA(x, y, c)
+        }
+    }
+
+    // A transparent corner is left out
+    img.SetRGBA(9, 9, color.RGBA{})
+
+    colors, err := DominantColors(img, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(colors) != 2 || !SameCell(colors[0], red) || !SameCell(colors[1], blue) {
+        t.Errorf("DominantColors() = %v, want red then blue", colors)
+    }
+
+    if colors, _ = DominantColors(img, 5); len(colors) != 2 {
+        t.Errorf("DominantColors() of a 2 colour image returned %d colours", len(colors))
+    }
+
+    if _, err = DominantColors(img, 0); err == nil {
+        t.Error("DominantColors() of no colours succeeded")
+    }
+
+    if _, err = DominantColors(image.NewRGBA(image.Rect(0, 0, 2, 2)), 1); err == nil {
+        t.Error("DominantColors() of a transparent image succeeded")
+    }
+}
+
+func TestWithMaxColors(t *testing.T) {
+    gradient := image.NewNRGBA(image.Rect(0, 0, 64, 2))
+    for x := 0; x < 64; x++ {
+        gradient.SetNRGBA(x, 0, color.NRGBA{uint8(x * 4), 0x40, uint8(0xff - x * 4), 0xff})
+        gradient.SetNRGBA(x, 1, color.NRGBA{uint8(x * 4), 0x40, uint8(0xff - x * 4), 0xff})
+    }
+
+    encoded, err := NewEncoder(WithMaxColors(8)).Encode(gradient)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    distinct := map[string]bool{}
+    for _, hex := range regexp.MustCompile("#[0-9a-f]{6}").FindAllString(encoded, -1) {
+        distinct[hex] = true
+    }
+
+    if len(distinct) == 0 || len(distinct) > 8 {
+        t.Errorf("output holds %d distinct colours, want at most 8: %q", len(distinct), encoded)
+    }
+}
+
+func TestWithPerceptualQuantization(t *testing.T) {
+    // A dark, slightly noisy night sky, with a bright light in a corner
+    sky := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+    for y := 0; y < 64; y++ {
+        for x := 0; x < 64; x++ {
+            v := uint8(y / 2 + (x * 7 + y * 13) % 3)
+            c := color.NRGBA{v / 2, v / 2, v, 0xff}
+            if x > 50 && y > 50 {
+                c = color.NRGBA{0xff, uint8(0xc0 + x), uint8(y * 2), 0xff}
+            }
+
+            sky.SetNRGBA(x, y, c)
+        }
+    }
+
+    tags := func(opts ...Option) int {
+        encoded, err := NewEncoder(opts...).Encode(sky)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return len(tagPattern.FindAllString(encoded, -1))
+    }
+
+    channels, perceptual := tags(WithMaxColors(16)), tags(WithMaxColors(16), WithPerceptualQuantization(true))
+    if perceptual >= channels {
+        t.Errorf("perceptual quantization wrote %d tags, quantizing by channel values %d", perceptual, channels)
+    }
+}
+
+func TestOrderedDither(t *testing.T) {
+    bw := color.Palette{color.Black, color.White}
+
+    // Mid grey comes out as a checkerboard, a quarter grey turns white where the matrix is at its highest
+    tests := []struct {
+        gray uint8
+        want []string
+    }{
+        {0x80, []string{".#.#", "#.#.", ".#.#", "#.#."}},
+        {0x40, []string{"....", "..#.", "....", "#.#."}},
+    }
+
+    for _, test := range tests {
+        dst := image.NewPaletted(image.Rect(0, 0, 4, 4), bw)
+        orderedDither(dst, solid(4, 4, color.Gray{test.gray}), bayerMatrix(4), image.Point{})
+
+        for y, row := range test.want {
+            for x, want := range row {
+                if got := ".#"[dst.ColorIndexAt(x, y)]; rune(got) != want {
+                    t.Errorf("grey %#x: pixel %d, %d is %c, want %c", test.gray, x, y, got, want)
+                }
+            }
+        }
+    }
+}
+
+func TestColorHistogram(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(-2, 3, 3, 7))
+    for y := 3; y < 7; y++ {
+        for x := -2; x < 3; x++ {
+            switch {
+                case x < 1:
+                    img.Set(x, y, color.NRGBA{0, 0x80, 0, 0xff})
+                case y == 3:
+                    img.Set(x, y, color.NRGBA{0xff, 0, 0, 0x80})
+                default:
+                    img.Set(x, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+            }
+        }
+    }
+
+    hist := ColorHistogram(img)
+
+    total, top, topCount := 0, color.RGBA{}, 0
+    for c, n := range hist {
+        total += n
+        if n > topCount {
+            top, topCount = c, n
+        }
+    }
+
+    if total != 20 || len(hist) != 3 {
+        t.Errorf("counted %d pixels in %d colours, want 20 in 3", total, len(hist))
+    }
+
+    // Colours are counted premultiplied
+    if top != (color.RGBA{0, 0x80, 0, 0xff}) || topCount != 12 || hist[color.RGBA{0x80, 0, 0, 0x80}] != 2 {
+        t.Errorf("ColorHistogram() = %v, want 12 green, 6 white & 2 half opaque red", hist)
+    }
+}
+
+func TestLumaDither(t *testing.T) {
+    ycc := func(y, cb, cr uint8) color.RGBA {
+        r, g, b := color.YCbCrToRGB(y, cb, cr)
+        return color.RGBA{r, g, b, 0xff}
+    }
+
+    // Three brightnesses of one colour, & another hue halfway between two of them
+    palette := color.Palette{ycc(60, 100, 150), ycc(120, 100, 150), ycc(180, 100, 150), ycc(90, 150, 100)}
+
+    // A gradient of that colour from the darkest to the brightest of the palette
+    img := image.NewNRGBA(image.Rect(0, 0, 32, 8))
+    for y := 0; y < 8; y++ {
+        for x := 0; x < 32; x++ {
+            img.Set(x, y, ycc(uint8(60 + x * 120 / 31), 100, 150))
+        }
+    }
+
+    dithered := image.NewPaletted(img.Rect, palette)
+    lumaDither(dithered, img)
+
+    // Between the colours of the palette the brightness is made up by mixing them, in every block of 8 columns
+    for block := 0; block < 32; block += 8 {
+        var sum, want float64
+        levels := map[uint8]bool{}
+
+        for y := 0; y < 8; y++ {
+            for x := block; x < block + 8; x++ {
+                i := dithered.ColorIndexAt(x, y)
+                if i == 3 {
+                    t.Fatalf("pixel %d, %d took the other hue", x, y)
+                }
+
+                levels[i] = true
+                sum += float64(60 + int(i) * 60)
+                want += float64(60 + x * 120 / 31)
+            }
+        }
+
+        if mean, want := sum / 64, want / 64; math.Abs(mean - want) > 8 {
+            t.Errorf("columns %d to %d average a luma of %.0f, want about %.0f", block, block + 7, mean, want)
+        }
+
+        if len(levels) < 2 {
+            t.Errorf("columns %d to %d are a single colour, want a dither pattern", block, block + 7)
+        }
+    }
+}
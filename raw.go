@@ -0,0 +1,88 @@
+package pxl
+
+import (
+    "image"
+
+    "github.com/pkg/errors"
+)
+
+// PixelFormat is the layout of the pixels of a raw buffer FromRawBytes() reads.
+type PixelFormat int
+
+const (
+    // PixelRGBA holds 4 bytes a pixel, red, green, blue & alpha, which isn't premultiplied.
+    PixelRGBA PixelFormat = iota
+
+    // PixelRGB holds 3 bytes a pixel, red, green & blue, every pixel is opaque.
+    PixelRGB
+
+    // PixelBGRA holds 4 bytes a pixel, blue, green, red & alpha, like the buffers of many capture APIs.
+    PixelBGRA
+
+    // PixelGray holds a byte of luminance a pixel.
+    PixelGray
+)
+
+// pixelBytes returns how many bytes a pixel takes in a format, or 0 for an unknown one.
+func pixelBytes(format PixelFormat) int {
+    switch format {
+        case PixelRGBA, PixelBGRA:
+            return 4
+
+        case PixelRGB:
+            return 3
+
+        case PixelGray:
+            return 1
+    }
+
+    return 0
+}
+
+// FromRawBytes converts a raw buffer of w by h pixels like FromImage() does, for the buffers handed over
+// by capture & rendering APIs. Rows start stride bytes apart, which may be more than a row of pixels takes,
+// & the last one doesn't need its padding. RGBA & gray buffers are read in place, the others are copied.
+func FromRawBytes(pix []byte, w, h, stride int, format PixelFormat) (encoded string, err error) {
+    size := pixelBytes(format)
+    if size == 0 {
+        return "", errors.New("pixelview: Can't read pixels of an unknown format")
+    }
+
+    if w < 1 || h < 1 {
+        return "", errors.New("pixelview: Can't read raw image smaller than one pixel")
+    }
+
+    if stride < w * size {
+        return "", errors.Errorf("pixelview: Can't read rows of %d pixels %d bytes apart", w, stride)
+    }
+
+    if length := stride * (h - 1) + w * size; len(pix) < length {
+        return "", errors.Errorf("pixelview: Can't read %d by %d pixels from %d bytes, they take %d", w, h, len(pix), length)
+    }
+
+    rect := image.Rect(0, 0, w, h)
+    switch format {
+        case PixelRGBA:
+            return FromImage(&image.NRGBA{Pix: pix, Stride: stride, Rect: rect})
+
+        case PixelGray:
+            return FromImage(&image.Gray{Pix: pix, Stride: stride, Rect: rect})
+    }
+
+    img := image.NewNRGBA(rect)
+    for y := 0; y < h; y++ {
+        src, dst := pix[y * stride:], img.Pix[y * img.Stride:]
+        for x := 0; x < w; x++ {
+            s, d := src[x * size:], dst[x * 4:x * 4 + 4]
+            switch format {
+                case PixelRGB:
+                    d[0], d[1], d[2], d[3] = s[0], s[1], s[2], 0xff
+
+                case PixelBGRA:
+                    d[0], d[1], d[2], d[3] = s[2], s[1], s[0], s[3]
+            }
+        }
+    }
+
+    return FromImage(img)
+}
@@ -0,0 +1,56 @@
+package pxl
+
+import (
+    "testing"
+)
+
+func TestFromRawBytes(t *testing.T) {
+    // 2 by 2 pixels in rows 12 bytes apart, padded with bytes which mustn't be read, except after the last row
+    rgba := []byte{
+        0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0xee, 0xee, 0xee, 0xee,
+        0x00, 0x00, 0xff, 0xff, 0x12, 0x34, 0x56, 0xff,
+    }
+
+    encoded, err := FromRawBytes(rgba, 2, 2, 12, PixelRGBA)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "[#ff0000:#0000ff]▀[#00ff00:#123456]▀\n"
+    if encoded != want {
+        t.Errorf("FromRawBytes() of RGBA = %q, want %q", encoded, want)
+    }
+
+    // The same pixels in the other layouts, their rows padded with a byte
+    others := map[PixelFormat][]byte{
+        PixelRGB:  {0xff, 0x00, 0x00, 0x00, 0xff, 0x00, 0xee, 0x00, 0x00, 0xff, 0x12, 0x34, 0x56},
+        PixelBGRA: {0x00, 0x00, 0xff, 0xff, 0x00, 0xff, 0x00, 0xff, 0xee, 0xff, 0x00, 0x00, 0xff, 0x56, 0x34, 0x12, 0xff},
+    }
+
+    for format, pix := range others {
+        stride := 2 * pixelBytes(format) + 1
+        if got, err := FromRawBytes(pix, 2, 2, stride, format); err != nil || got != want {
+            t.Errorf("FromRawBytes() of format %d = %q, %v, want %q", format, got, err, want)
+        }
+    }
+
+    gray, err := FromRawBytes([]byte{0x10, 0x20, 0xee, 0x30, 0x40}, 2, 2, 3, PixelGray)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#101010:#303030]▀[#202020:#404040]▀\n"; gray != want {
+        t.Errorf("FromRawBytes() of gray = %q, want %q", gray, want)
+    }
+
+    // Rows which overlap, a buffer too short for them & an unknown format
+    for _, bad := range []struct {
+        pix    []byte
+        stride int
+        format PixelFormat
+    }{{rgba, 4, PixelRGBA}, {rgba[:19], 12, PixelRGBA}, {rgba, 12, PixelFormat(-1)}} {
+        if _, err = FromRawBytes(bad.pix, 2, 2, bad.stride, bad.format); err == nil {
+            t.Errorf("FromRawBytes() of %d bytes, %d apart in format %d succeeded", len(bad.pix), bad.stride, bad.format)
+        }
+    }
+}
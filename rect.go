@@ -0,0 +1,66 @@
+package pxl
+
+import (
+    "image"
+
+    "github.com/pkg/errors"
+)
+
+// EncodeRect converts only the part of an image inside r, for redrawing a region which changed,
+// like a brush stroke, without re-rendering the whole image. Along with the encoded region it returns
+// the cell where the region should be drawn, relative to the top-left cell of the full rendering.
+// As cells hold two rows of pixels, r must start on an even row counted from the top of the image.
+func EncodeRect(img image.Image, r image.Rectangle) (encoded string, col, row int, err error) {
+    bounds := img.Bounds()
+    r = r.Intersect(bounds)
+
+    if r.Empty() {
+        err = errors.New("pixelview: Can't encode rectangle outside of the image")
+        return
+    }
+
+    if (r.Min.Y - bounds.Min.Y) % 2 != 0 {
+        err = errors.New("pixelview: Can't encode rectangle starting on an uneven row")
+        return
+    }
+
+    encoded, err = FromImage(subImage(img, r))
+    return encoded, r.Min.X - bounds.Min.X, (r.Min.Y - bounds.Min.Y) / 2, err
+}
+
+// ContentBounds returns the smallest rectangle holding every pixel with an alpha above alphaThreshold,
+// for trimming the transparent space around sprites before encoding them with EncodeRect().
+// It's empty when no pixel is that opaque.
+func ContentBounds(img image.Image, alphaThreshold uint8) image.Rectangle {
+    bounds := img.Bounds()
+    var content image.Rectangle
+
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            if _, _, _, a := img.At(x, y).RGBA(); uint8(a >> 8) > alphaThreshold {
+                content = content.Union(image.Rect(x, y, x + 1, y + 1))
+            }
+        }
+    }
+
+    return content
+}
+
+// subImage returns the part of img inside r, sharing its pixels when the image type allows it.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+    if v, ok := img.(interface{ SubImage(image.Rectangle) image.Image }); ok {
+        return v.SubImage(r)
+    }
+
+    return &croppedImage{img, r.Intersect(img.Bounds())}
+}
+
+// croppedImage shows part of an image which has no SubImage() method.
+type croppedImage struct {
+    image.Image
+    rect image.Rectangle
+}
+
+func (c *croppedImage) Bounds() image.Rectangle {
+    return c.rect
+}
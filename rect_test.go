@@ -0,0 +1,54 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "strings"
+    "testing"
+)
+
+func TestEncodeRect(t *testing.T) {
+    img := TestPattern(12, 10)
+
+    encoded, col, row, err := EncodeRect(img, image.Rect(5, 4, 9, 8))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if col != 5 || row != 2 {
+        t.Errorf("EncodeRect() to be drawn at cell %d, %d, want 5, 2", col, row)
+    }
+
+    // The region matches the same cells of the full rendering
+    full, _ := DecodeToPixels(img)
+    region := Pixels{full[2][5:9], full[3][5:9]}
+    if want := region.Encode(); encoded != want {
+        t.Errorf("EncodeRect() = %q, want %q", encoded, want)
+    }
+
+    if _, _, _, err = EncodeRect(img, image.Rect(0, 3, 4, 7)); err == nil || !strings.Contains(err.Error(), "uneven") {
+        t.Errorf("EncodeRect() starting on an uneven row returned %v", err)
+    }
+
+    if _, _, _, err = EncodeRect(img, image.Rect(20, 20, 24, 24)); err == nil {
+        t.Error("EncodeRect() outside of the image succeeded")
+    }
+}
+
+func TestContentBounds(t *testing.T) {
+    // An opaque square in the middle, with a faint fringe around it, of a transparent image at an offset
+    img := image.NewNRGBA(image.Rect(-4, 2, 8, 14))
+    draw.Draw(img, image.Rect(0, 5, 5, 11), image.NewUniform(color.NRGBA{0xff, 0, 0, 0x20}), image.Point{}, draw.Src)
+    draw.Draw(img, image.Rect(1, 6, 4, 10), image.NewUniform(color.NRGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+
+    for threshold, want := range map[uint8]image.Rectangle{0x80: image.Rect(1, 6, 4, 10), 0: image.Rect(0, 5, 5, 11)} {
+        if got := ContentBounds(img, threshold); got != want {
+            t.Errorf("ContentBounds(%#x) = %v, want %v", threshold, got, want)
+        }
+    }
+
+    if got := ContentBounds(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 0); !got.Empty() {
+        t.Errorf("ContentBounds() of a transparent image = %v, want an empty rectangle", got)
+    }
+}
@@ -0,0 +1,112 @@
+package pxl
+
+import (
+    "context"
+    "mime"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/pkg/errors"
+)
+
+// FetchOption configures how FromURL() fetches an image.
+type FetchOption func(*fetcher)
+
+type fetcher struct {
+    timeout time.Duration
+    retries int
+    backoff time.Duration
+}
+
+// WithTimeout bounds the total time FromURL() may take, retries included.
+// There's no timeout by default.
+func WithTimeout(timeout time.Duration) FetchOption {
+    return func(f *fetcher) {
+        f.timeout = timeout
+    }
+}
+
+// WithRetries makes FromURL() retry up to n times after a network error or a 5xx response,
+// waiting backoff before the first retry & twice as long before each one after that.
+// Other responses, like a 404, are never retried, & neither is anything when n isn't positive.
+func WithRetries(n int, backoff time.Duration) FetchOption {
+    return func(f *fetcher) {
+        f.retries = n
+        f.backoff = backoff
+    }
+}
+
+// FromURL is a convenience function that fetches an image over HTTP & converts it to a formatted string.
+// See FromImage() for more details.
+func FromURL(url string, opts ...FetchOption) (encoded string, err error) {
+    f := &fetcher{}
+    for _, opt := range opts {
+        opt(f)
+    }
+
+    ctx := context.Background()
+    if f.timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, f.timeout)
+        defer cancel()
+    }
+
+    backoff := f.backoff
+    for attempt := 0; ; attempt++ {
+        var retry bool
+        encoded, retry, err = fetch(ctx, url)
+        if err == nil || !retry || attempt >= f.retries {
+            return
+        }
+
+        select {
+            case <-ctx.Done():
+                return
+
+            case <-time.After(backoff):
+                backoff *= 2
+        }
+    }
+}
+
+// fetch makes a single attempt at fetching & converting an image,
+// reporting whether a failure is transient & worth retrying.
+func fetch(ctx context.Context, url string) (encoded string, retry bool, err error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        retry = ctx.Err() == nil
+        return
+    }
+
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        err = errors.Errorf("pixelview: Can't fetch %s: %s", url, resp.Status)
+        retry = resp.StatusCode >= 500
+        return
+    }
+
+    encoded, err = FromResponse(resp)
+    return
+}
+
+// FromResponse converts the image in the body of an HTTP response like FromReader() does, after checking
+// its Content-Type is that of an image, so an HTML error page served in its place, like some CDNs do,
+// is reported as such rather than as an image in an unknown format. Responses without a Content-Type are decoded
+// as they are. The body is read but not closed.
+func FromResponse(resp *http.Response) (encoded string, err error) {
+    if header := resp.Header.Get("Content-Type"); header != "" {
+        mediaType, _, err := mime.ParseMediaType(header)
+        if err != nil || !strings.HasPrefix(mediaType, "image/") {
+            return "", errors.Errorf("pixelview: Can't convert response with content type %q, which isn't an image", header)
+        }
+    }
+
+    return FromReader(resp.Body)
+}
@@ -0,0 +1,118 @@
+package pxl
+
+import (
+    "bytes"
+    "errors"
+    "image"
+    "image/png"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// flakyServer serves a PNG after failing the first failures requests with a 503, counting every request.
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, TestPattern(4, 4)); err != nil {
+        t.Fatal(err)
+    }
+
+    var requests int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.AddInt32(&requests, 1) <= failures {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+
+        w.Header().Set("Content-Type", "image/png")
+        w.Write(buf.Bytes())
+    }))
+
+    t.Cleanup(server.Close)
+    return server, &requests
+}
+
+func TestFromURLRetries(t *testing.T) {
+    server, requests := flakyServer(t, 2)
+
+    encoded, err := FromURL(server.URL, WithRetries(2, time.Millisecond))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want, _ := FromImage(TestPattern(4, 4)); encoded != want {
+        t.Errorf("FromURL() = %q, want %q", encoded, want)
+    }
+
+    if *requests != 3 {
+        t.Errorf("FromURL() made %d requests, want 3", *requests)
+    }
+}
+
+func TestFromURLGivesUp(t *testing.T) {
+    for _, retries := range []int{0, 1, -1} {
+        server, requests := flakyServer(t, 1 << 30)
+
+        if _, err := FromURL(server.URL, WithRetries(retries, time.Millisecond)); err == nil {
+            t.Errorf("FromURL() with %d retries of a failing server succeeded", retries)
+        }
+
+        want := int32(retries + 1)
+        if retries < 0 {
+            want = 1
+        }
+
+        if *requests != want {
+            t.Errorf("FromURL() with %d retries made %d requests, want %d", retries, *requests, want)
+        }
+    }
+}
+
+func TestFromURLTimeout(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+    }))
+    defer server.Close()
+
+    start := time.Now()
+    if _, err := FromURL(server.URL, WithTimeout(20 * time.Millisecond), WithRetries(5, time.Millisecond)); err == nil {
+        t.Error("FromURL() of a server that never answers succeeded")
+    }
+
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("FromURL() took %v with a timeout of 20ms", elapsed)
+    }
+}
+
+func TestFromResponse(t *testing.T) {
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, TestPattern(4, 4)); err != nil {
+        t.Fatal(err)
+    }
+
+    response := func(contentType string, body []byte) *http.Response {
+        resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(body))}
+        if contentType != "" {
+            resp.Header.Set("Content-Type", contentType)
+        }
+
+        return resp
+    }
+
+    want, _ := FromImage(TestPattern(4, 4))
+    for _, contentType := range []string{"image/png", "image/png; charset=binary", ""} {
+        if encoded, err := FromResponse(response(contentType, buf.Bytes())); err != nil || encoded != want {
+            t.Errorf("FromResponse() of %q returned %v, output %q, want %q", contentType, err, encoded, want)
+        }
+    }
+
+    // An error page is reported by its content type, rather than as an unknown format
+    _, err := FromResponse(response("text/html; charset=utf-8", []byte("<html>Not Found</html>")))
+    if err == nil || !strings.Contains(err.Error(), "text/html") || errors.Is(err, image.ErrFormat) {
+        t.Errorf("FromResponse() of an HTML page returned %v, want a content type error", err)
+    }
+}
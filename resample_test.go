@@ -0,0 +1,65 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+
+    xdraw "golang.org/x/image/draw"
+)
+
+func TestResizeLanczos(t *testing.T) {
+    // Bars of white 8 pixels wide on black, scaled down to under half
+    bars := image.NewNRGBA(image.Rect(0, 0, 48, 8))
+    for x := 8; x < 48; x += 16 {
+        for y := 0; y < 8; y++ {
+            for i := 0; i < 8; i++ {
+                bars.Set(x + i, y, color.White)
+            }
+        }
+    }
+
+    // The largest change in brightness from one pixel to the next along a row
+    sharpest := func(img image.Image) (largest uint8) {
+        for x := 1; x < img.Bounds().Dx(); x++ {
+            a, b := color.GrayModel.Convert(img.At(x - 1, 1)).(color.Gray).Y, color.GrayModel.Convert(img.At(x, 1)).(color.Gray).Y
+            if b < a {
+                a, b = b, a
+            }
+
+            if b - a > largest {
+                largest = b - a
+            }
+        }
+
+        return
+    }
+
+    bilinear := image.NewRGBA64(image.Rect(0, 0, 18, 3))
+    xdraw.BiLinear.Scale(bilinear, bilinear.Rect, bars, bars.Rect, xdraw.Src, nil)
+
+    for _, lobes := range []int{2, 3} {
+        if lanczos, linear := sharpest(ResizeLanczosLobes(bars, 18, 3, lobes)), sharpest(bilinear); lanczos <= linear {
+            t.Errorf("with %d lobes the sharpest edge steps by %d, bilinear by %d, want it sharper", lobes, lanczos, linear)
+        }
+    }
+
+    // The ringing around the edges of white bars over transparency doesn't make invalid colours
+    opaque := image.NewNRGBA(bars.Rect)
+    for i := 0; i < len(opaque.Pix); i += 4 {
+        opaque.Pix[i], opaque.Pix[i + 1], opaque.Pix[i + 2], opaque.Pix[i + 3] = 0xff, 0xff, 0xff, bars.Pix[i]
+    }
+
+    resized := ResizeLanczos(opaque, 18, 3).(*image.RGBA64)
+    for y := 0; y < 3; y++ {
+        for x := 0; x < 18; x++ {
+            if c := resized.RGBA64At(x, y); c.R > c.A || c.G > c.A || c.B > c.A {
+                t.Errorf("ResizeLanczos() returned %v at (%d, %d), which has channels past its alpha", c, x, y)
+            }
+        }
+    }
+
+    if size := ResizeLanczos(bars, 5, 7).Bounds(); size != image.Rect(0, 0, 5, 7) {
+        t.Errorf("ResizeLanczos() to 5 by 7 is %v", size)
+    }
+}
@@ -0,0 +1,101 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+
+    "github.com/pkg/errors"
+)
+
+// RotateArbitrary returns an image rotated clockwise by any number of degrees about its center,
+// sized to fit the whole of the rotated image. Every pixel is sampled bilinearly from the four
+// nearest to it, the corners which the image no longer covers are filled with bg.
+// A nil bg leaves them transparent.
+func RotateArbitrary(img image.Image, degrees float64, bg color.Color) image.Image {
+    if bg == nil {
+        bg = color.Transparent
+    }
+
+    bounds := img.Bounds()
+    w, h := float64(bounds.Dx()), float64(bounds.Dy())
+
+    sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+    // Rounding errors would otherwise add a column or row to sizes which are meant to be whole
+    width := int(math.Ceil(math.Abs(w * cos) + math.Abs(h * sin) - 1e-9))
+    height := int(math.Ceil(math.Abs(w * sin) + math.Abs(h * cos) - 1e-9))
+
+    rotated := image.NewRGBA64(image.Rect(0, 0, width, height))
+
+    r, g, b, a := bg.RGBA()
+    fill := [4]float64{float64(r), float64(g), float64(b), float64(a)}
+
+    sample := func(x, y int) [4]float64 {
+        if x < 0 || y < 0 || x >= bounds.Dx() || y >= bounds.Dy() {
+            return fill
+        }
+
+        r, g, b, a := img.At(bounds.Min.X + x, bounds.Min.Y + y).RGBA()
+        return [4]float64{float64(r), float64(g), float64(b), float64(a)}
+    }
+
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            dx, dy := float64(x) + 0.5 - float64(width) / 2, float64(y) + 0.5 - float64(height) / 2
+
+            // Rotating back the other way finds the point of the source the pixel comes from,
+            // counted between pixel centers
+            u := cos * dx + sin * dy + w / 2 - 0.5
+            v := -sin * dx + cos * dy + h / 2 - 0.5
+
+            x0, y0 := math.Floor(u), math.Floor(v)
+            fx, fy := u - x0, v - y0
+
+            tl, tr := sample(int(x0), int(y0)), sample(int(x0) + 1, int(y0))
+            bl, br := sample(int(x0), int(y0) + 1), sample(int(x0) + 1, int(y0) + 1)
+
+            var c [4]uint16
+            for i := range c {
+                top := tl[i] + (tr[i] - tl[i]) * fx
+                bottom := bl[i] + (br[i] - bl[i]) * fx
+                c[i] = uint16(math.Round(top + (bottom - top) * fy))
+            }
+
+            rotated.SetRGBA64(x, y, color.RGBA64{c[0], c[1], c[2], c[3]})
+        }
+    }
+
+    return rotated
+}
+
+// Symmetry returns an image of the same size made symmetric like a kaleidoscope, for generative art.
+// With 2 axes the left half is mirrored onto the right one, with 4 the top-left quarter is mirrored onto the other three.
+// The middle column or row of an image with an uneven size is kept as it is.
+func Symmetry(img image.Image, axes int) (image.Image, error) {
+    if axes != 2 && axes != 4 {
+        return nil, errors.Errorf("pixelview: Can't mirror image with %d axes of symmetry, only 2 or 4", axes)
+    }
+
+    bounds := img.Bounds()
+    w, h := bounds.Dx(), bounds.Dy()
+    mirrored := image.NewRGBA64(image.Rect(0, 0, w, h))
+
+    for y := 0; y < h; y++ {
+        sy := y
+        if axes == 4 && y >= (h + 1) / 2 {
+            sy = h - 1 - y
+        }
+
+        for x := 0; x < w; x++ {
+            sx := x
+            if x >= (w + 1) / 2 {
+                sx = w - 1 - x
+            }
+
+            mirrored.Set(x, y, img.At(bounds.Min.X + sx, bounds.Min.Y + sy))
+        }
+    }
+
+    return mirrored, nil
+}
@@ -0,0 +1,84 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+func TestRotateArbitrary(t *testing.T) {
+    red := color.RGBA{0xff, 0, 0, 0xff}
+    rotated := RotateArbitrary(solid(10, 10, color.White), 45, red)
+
+    // The diagonal of the square, 10√2 pixels
+    if rotated.Bounds() != image.Rect(0, 0, 15, 15) {
+        t.Fatalf("rotated image is %v, want 15 by 15", rotated.Bounds())
+    }
+
+    for p, want := range map[image.Point]string{{7, 7}: "#ffffff", {7, 1}: "#ffffff", {0, 0}: "#ff0000", {14, 14}: "#ff0000"} {
+        if got := ColorHex(rotated.At(p.X, p.Y)); got != want {
+            t.Errorf("pixel %v of the rotated image = %s, want %s", p, got, want)
+        }
+    }
+
+    // Where the edge crosses a pixel it's a blend of the two
+    if got := ColorHex(rotated.At(4, 3)); got == "#ffffff" || got == "#ff0000" {
+        t.Errorf("pixel on the edge = %s, want a blend", got)
+    }
+
+    // A quarter turn clockwise lands the bottom-left pixel in the top-left corner
+    img := image.NewGray(image.Rect(0, 0, 4, 2))
+    for i := range img.Pix {
+        img.Pix[i] = uint8(i * 30)
+    }
+
+    quarter := RotateArbitrary(img, 90, nil)
+    if quarter.Bounds() != image.Rect(0, 0, 2, 4) {
+        t.Fatalf("quarter turn is %v, want 2 by 4", quarter.Bounds())
+    }
+
+    if got, want := ColorHex(quarter.At(0, 0)), ColorHex(img.At(0, 1)); got != want {
+        t.Errorf("top-left pixel of the quarter turn = %s, want %s", got, want)
+    }
+}
+
+func TestSymmetry(t *testing.T) {
+    img := TestPattern(7, 6)
+
+    mirrored, err := Symmetry(img, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if mirrored.Bounds() != image.Rect(0, 0, 7, 6) {
+        t.Fatalf("mirrored image is %v, want 7 by 6", mirrored.Bounds())
+    }
+
+    // The quarters mirror each other, & the top-left one is the image's own
+    for y := 0; y < 6; y++ {
+        for x := 0; x < 7; x++ {
+            c := ColorHex(mirrored.At(x, y))
+            if c != ColorHex(mirrored.At(6 - x, y)) || c != ColorHex(mirrored.At(x, 5 - y)) {
+                t.Errorf("pixel %d, %d = %s doesn't mirror the other quarters", x, y, c)
+            }
+
+            if x <= 3 && y <= 2 && c != ColorHex(img.At(x, y)) {
+                t.Errorf("pixel %d, %d = %s, want %s from the image", x, y, c, ColorHex(img.At(x, y)))
+            }
+        }
+    }
+
+    // With 2 axes only the left half is mirrored
+    halves, err := Symmetry(img, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if a, b, c := ColorHex(halves.At(5, 4)), ColorHex(halves.At(1, 4)), ColorHex(img.At(1, 4)); a != b || b != c {
+        t.Errorf("pixel 5, 4 of 2 way symmetry = %s, want %s like pixel 1, 4 of the image, %s", a, b, c)
+    }
+
+    if _, err = Symmetry(img, 3); err == nil {
+        t.Error("Symmetry() with 3 axes succeeded")
+    }
+}
@@ -0,0 +1,42 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+)
+
+// shades go from the least to the most foreground showing through.
+var shades = []rune("░▒▓█")
+
+// FromImageShade converts an image to a string formatted for tview with a retro dithered look,
+// every pixel becomes one of the shade characters ░▒▓█ in the fg & bg colours,
+// the brighter the pixel, the more of fg shows.
+// Like FromImageSpace(), each row of pixels takes a whole row of output.
+func FromImageShade(img image.Image, fg, bg color.Color) (encoded string, err error) {
+    var b strings.Builder
+    tag := "[" + ColorHex(fg) + ":" + ColorHex(bg) + "]"
+
+    for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+        b.WriteString(tag)
+
+        for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+            b.WriteRune(shade(luminance(img.At(x, y))))
+        }
+
+        b.WriteString("\n")
+    }
+
+    return b.String(), nil
+}
+
+// shade picks the shade character for a luminance from 0 to 1,
+// splitting the range into equal quarters.
+func shade(l float64) rune {
+    i := int(l * float64(len(shades)))
+    if i >= len(shades) {
+        i = len(shades) - 1
+    }
+
+    return shades[i]
+}
@@ -0,0 +1,23 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+func TestFromImageShade(t *testing.T) {
+    // Just below & on each quarter of the luminance range
+    ramp := []uint8{0x00, 0x3f, 0x40, 0x7f, 0x80, 0xbf, 0xc0, 0xff}
+    img := image.NewGray(image.Rect(0, 0, len(ramp), 1))
+    copy(img.Pix, ramp)
+
+    encoded, err := FromImageShade(img, color.White, color.Black)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[#ffffff:#000000]░░▒▒▓▓██\n"; encoded != want {
+        t.Errorf("FromImageShade() = %q, want %q", encoded, want)
+    }
+}
@@ -0,0 +1,58 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+)
+
+// FromImageSpace converts an image to a string formatted for tview, where every pixel is
+// a space with its colour set as the background, some terminals draw these more reliably
+// than the half-block, which can show gaps in certain fonts.
+// Each row of pixels takes a whole row of output, so unlike FromImage()
+// it can process images with an uneven height, but the output is twice as tall.
+func FromImageSpace(img image.Image) (encoded string, err error) {
+    var b strings.Builder
+    line := make([]color.Color, img.Bounds().Dx())
+
+    for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+        for x := range line {
+            line[x] = img.At(img.Bounds().Min.X + x, y)
+        }
+
+        b.WriteString(spaceLine(line))
+        b.WriteString("\n")
+    }
+
+    return b.String(), nil
+}
+
+// spaceRow emits a row of cells as two lines of spaces in ModeTwoRowSpace, the top pixels above the bottom ones.
+// The suffix is written at the end of both lines.
+func (e *Encoder) spaceRow(cells []Cell, suffix string) string {
+    top := make([]color.Color, len(cells))
+    bottom := make([]color.Color, len(cells))
+
+    for x, cell := range cells {
+        top[x], bottom[x] = cell.Fg, cell.Bg
+    }
+
+    return e.bordered(spaceLine(top) + suffix, len(cells)) + e.separator + e.bordered(spaceLine(bottom) + suffix, len(cells))
+}
+
+// spaceLine emits a space for every pixel, only setting the background when it's written another way.
+func spaceLine(pixels []color.Color) string {
+    var b strings.Builder
+    var prev color.Color
+
+    for _, c := range pixels {
+        if !SameCell(c, prev) {
+            b.WriteString("[:" + tagColor(c) + "]")
+            prev = c
+        }
+
+        b.WriteString(" ")
+    }
+
+    return b.String()
+}
@@ -0,0 +1,44 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "strings"
+    "testing"
+)
+
+func TestFromImageSpace(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+    for i := range img.Pix {
+        img.Pix[i] = 0xff
+    }
+
+    img.Set(2, 0, color.NRGBA{0xff, 0, 0, 0xff})
+    img.Set(0, 2, color.Transparent)
+
+    encoded, err := FromImageSpace(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Every row of pixels is a line of spaces, even for an uneven height
+    want := "[:#ffffff]  [:#ff0000] \n[:#ffffff]   \n[:-] [:#ffffff]  \n"
+    if encoded != want {
+        t.Errorf("FromImageSpace() = %q, want %q", encoded, want)
+    }
+
+    if strings.ContainsAny(encoded, "▀") || strings.Contains(encoded, "[#") {
+        t.Errorf("FromImageSpace() set a foreground or wrote a half block: %q", encoded)
+    }
+}
+
+func TestFromImageSpaceMixedTypes(t *testing.T) {
+    encoded, err := FromImageSpace(mixedTypes())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if want := "[:#ff0000]   [:#ffffff] \n[:#000000]    \n"; encoded != want {
+        t.Errorf("FromImageSpace() = %q, want %q", encoded, want)
+    }
+}
@@ -0,0 +1,41 @@
+package pxl
+
+import (
+    "image"
+
+    "github.com/pkg/errors"
+)
+
+// FromSpriteSheet converts count frames of frameW by frameH pixels laid out on a sprite sheet, like the ones
+// of game sprites, each like FromImage() does. Frames are cut left to right, then top to bottom,
+// starting at the top-left corner of the sheet. Any space to the right or bottom of the last
+// whole column or row of frames is left out.
+func FromSpriteSheet(img image.Image, frameW, frameH, count int) (frames []string, err error) {
+    if frameW < 1 || frameH < 1 {
+        return nil, errors.New("pixelview: Can't cut sprite sheet into frames smaller than one pixel")
+    }
+
+    if count < 1 {
+        return nil, errors.New("pixelview: Can't cut less than one frame from sprite sheet")
+    }
+
+    bounds := img.Bounds()
+    cols, rows := bounds.Dx() / frameW, bounds.Dy() / frameH
+
+    if cols * rows < count {
+        return nil, errors.Errorf("pixelview: Can't cut %d frames from sprite sheet which holds %d", count, cols * rows)
+    }
+
+    for i := 0; i < count; i++ {
+        min := bounds.Min.Add(image.Pt(i % cols * frameW, i / cols * frameH))
+
+        var encoded string
+        if encoded, err = FromImage(subImage(img, image.Rectangle{min, min.Add(image.Pt(frameW, frameH))})); err != nil {
+            return nil, err
+        }
+
+        frames = append(frames, encoded)
+    }
+
+    return
+}
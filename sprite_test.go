@@ -0,0 +1,36 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "testing"
+)
+
+func TestFromSpriteSheet(t *testing.T) {
+    // Four 2 by 2 frames in a row, each a colour of its own, & a column left over at the end
+    colors := []color.Color{color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0xff, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}, color.White}
+    sheet := solid(9, 2, color.Black)
+    for i, c := range colors {
+        draw.Draw(sheet, image.Rect(i * 2, 0, i * 2 + 2, 2), image.NewUniform(c), image.Point{}, draw.Src)
+    }
+
+    frames, err := FromSpriteSheet(sheet, 2, 2, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(frames) != 4 {
+        t.Fatalf("FromSpriteSheet() cut %d frames, want 4", len(frames))
+    }
+
+    for i, c := range colors {
+        if want, _ := FromImage(solid(2, 2, c)); frames[i] != want {
+            t.Errorf("frame %d = %q, want %q", i, frames[i], want)
+        }
+    }
+
+    if _, err = FromSpriteSheet(sheet, 2, 2, 5); err == nil {
+        t.Error("FromSpriteSheet() cut more frames than the sheet holds")
+    }
+}
@@ -0,0 +1,117 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "math"
+)
+
+// Stats helps decide whether an image is worth quantizing, it reports how many distinct colours
+// an image has, at 8 bits per channel, & how many tags FromImage() emits for it,
+// since a tag is only emitted when the colours change from one cell to the next.
+// The tags are counted in the output of FromImage(), so they match whichever way it takes.
+func Stats(img image.Image) (distinctColors int, estimatedTags int, err error) {
+    encoded, err := FromImage(img)
+    if err != nil {
+        return
+    }
+
+    return len(ColorHistogram(img)), len(tagPattern.FindAllStringIndex(encoded, -1)), nil
+}
+
+// MeanColor returns the average colour of an image, to pick a matching background or border.
+// An empty image averages to color.Transparent. The sRGB values are averaged as they are,
+// see Encoder.MeanColor() to average them in linear light.
+func MeanColor(img image.Image) color.Color {
+    return meanColor(img, false)
+}
+
+// MeanColor returns the average colour of an image like the MeanColor() function does, but with WithGamma(true)
+// it averages the colours in linear light, like the encoder blends them, so half black & half white
+// averages to #bcbcbc rather than #7f7f7f.
+func (e *Encoder) MeanColor(img image.Image) color.Color {
+    return meanColor(img, e.gamma)
+}
+
+// meanColor averages the colours of an image, in linear light when linear is set.
+// The linear channels are weighted by the opacity of every pixel, so transparent pixels don't darken the mean.
+func meanColor(img image.Image, linear bool) color.Color {
+    var r, g, b, a, n uint64
+    var lr, lg, lb, weight float64
+
+    add := func(c color.Color, count uint64) {
+        cr, cg, cb, ca := c.RGBA()
+        a += uint64(ca) * count
+        n += count
+
+        if !linear {
+            r += uint64(cr) * count
+            g += uint64(cg) * count
+            b += uint64(cb) * count
+            return
+        }
+
+        if ca == 0 {
+            return
+        }
+
+        w := float64(ca) / 0xffff * float64(count)
+        lr += srgbToLinear(float64(cr) / float64(ca)) * w
+        lg += srgbToLinear(float64(cg) / float64(ca)) * w
+        lb += srgbToLinear(float64(cb) / float64(ca)) * w
+        weight += w
+    }
+
+    bounds := img.Bounds()
+    switch v := img.(type) {
+        default:
+            for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+                for x := bounds.Min.X; x < bounds.Max.X; x++ {
+                    add(img.At(x, y), 1)
+                }
+            }
+
+        case *image.Paletted:
+            var counts [256]uint64
+            for y := 0; y < bounds.Dy(); y++ {
+                for _, i := range v.Pix[y * v.Stride:y * v.Stride + bounds.Dx()] {
+                    counts[i]++
+                }
+            }
+
+            for i, c := range clearTransparent(v.Palette) {
+                add(c, counts[i])
+            }
+
+        case *image.NRGBA:
+            for y := 0; y < bounds.Dy(); y++ {
+                row := v.Pix[y * v.Stride:y * v.Stride + bounds.Dx() * 4]
+                for i := 0; i < len(row); i += 4 {
+                    add(color.NRGBA{row[i], row[i+1], row[i+2], row[i+3]}, 1)
+                }
+            }
+
+        case *image.RGBA:
+            for y := 0; y < bounds.Dy(); y++ {
+                row := v.Pix[y * v.Stride:y * v.Stride + bounds.Dx() * 4]
+                for i := 0; i < len(row); i += 4 {
+                    add(color.RGBA{row[i], row[i+1], row[i+2], row[i+3]}, 1)
+                }
+            }
+    }
+
+    if n == 0 {
+        return color.Transparent
+    }
+
+    if linear && weight > 0 {
+        alpha := float64(a / n)
+        channel := func(v float64) uint16 {
+            return uint16(math.Round(linearToSRGB(v / weight) * alpha))
+        }
+
+        return color.RGBA64{channel(lr), channel(lg), channel(lb), uint16(a / n)}
+    }
+
+    return color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)}
+}
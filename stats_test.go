@@ -0,0 +1,137 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "image/draw"
+    "testing"
+)
+
+func TestStatsMatchesFromImage(t *testing.T) {
+    white := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+    for i := range white.Pix {
+        white.Pix[i] = 0xff
+    }
+
+    bilevel := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+    for i := range bilevel.Pix {
+        bilevel.Pix[i] = uint8(i % 3 % 2)
+    }
+
+    // A colour of its own in every pixel, so every cell takes a tag
+    ramp := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+    for y := 0; y < 8; y++ {
+        for x := 0; x < 16; x++ {
+            ramp.Set(x, y, color.NRGBA{uint8(x * 16), uint8(y * 32), 0x80, 0xff})
+        }
+    }
+
+    tests := []struct {
+        name string
+        img  image.Image
+        tags int
+    }{
+        {"solid", white, 2},
+        {"bilevel", bilevel, 0},
+        {"ramp", ramp, 16 * 4},
+    }
+
+    for _, test := range tests {
+        encoded, err := FromImage(test.img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        _, tags, err := Stats(test.img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want := len(tagPattern.FindAllString(encoded, -1)); tags != want {
+            t.Errorf("%s: Stats() counted %d tags, FromImage() wrote %d", test.name, tags, want)
+        }
+
+        if test.tags > 0 && tags != test.tags {
+            t.Errorf("%s: Stats() counted %d tags, want %d", test.name, tags, test.tags)
+        }
+    }
+}
+
+func TestStatsDistinctColors(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+    for i := range img.Pix {
+        img.Pix[i] = 0xff
+    }
+
+    img.Set(1, 1, color.NRGBA{255, 0, 0, 255})
+
+    distinct, tags, err := Stats(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // White over white, then white over red
+    if distinct != 2 || tags != 2 {
+        t.Errorf("Stats() counted %d colours & %d tags, want 2 & 2", distinct, tags)
+    }
+
+    if _, _, err = Stats(solid(2, 3, color.White)); err != ErrOddHeight {
+        t.Errorf("Stats() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestMeanColor(t *testing.T) {
+    halves := solid(4, 2, color.Black)
+    for y := 0; y < 2; y++ {
+        for x := 2; x < 4; x++ {
+            halves.Set(x, y, color.White)
+        }
+    }
+
+    rgba := image.NewRGBA(halves.Rect)
+    paletted := image.NewPaletted(halves.Rect, color.Palette{color.Black, color.White})
+    gray := image.NewGray(halves.Rect)
+    for _, dst := range []draw.Image{rgba, paletted, gray} {
+        draw.Draw(dst, halves.Rect, halves, image.Point{}, draw.Src)
+    }
+
+    for _, img := range []image.Image{halves, rgba, paletted, gray, halves.SubImage(image.Rect(1, 0, 3, 2))} {
+        if mean := MeanColor(img); ColorHex(mean) != "#7f7f7f" {
+            t.Errorf("%T: MeanColor() = %s, want #7f7f7f", img, ColorHex(mean))
+        }
+    }
+
+    if mean := MeanColor(image.NewNRGBA(image.Rectangle{})); mean != color.Transparent {
+        t.Errorf("MeanColor() of an empty image = %v, want transparent", mean)
+    }
+}
+
+func TestEncoderMeanColor(t *testing.T) {
+    halves := solid(4, 2, color.Black)
+    for y := 0; y < 2; y++ {
+        for x := 2; x < 4; x++ {
+            halves.Set(x, y, color.White)
+        }
+    }
+
+    paletted := image.NewPaletted(halves.Rect, color.Palette{color.Black, color.White})
+    draw.Draw(paletted, halves.Rect, halves, image.Point{}, draw.Src)
+
+    for _, img := range []image.Image{halves, paletted} {
+        if mean := NewEncoder().MeanColor(img); ColorHex(mean) != "#7f7f7f" {
+            t.Errorf("%T: MeanColor() without gamma = %s, want #7f7f7f", img, ColorHex(mean))
+        }
+
+        // Half of the light of white is lighter than half of its sRGB value
+        if mean := NewEncoder(WithGamma(true)).MeanColor(img); ColorHex(mean) != "#bcbcbc" {
+            t.Errorf("%T: MeanColor() with gamma = %s, want #bcbcbc", img, ColorHex(mean))
+        }
+    }
+
+    // Transparent pixels count towards the opacity of the mean, but not its colour
+    faded := solid(2, 2, color.Transparent)
+    faded.Set(0, 0, color.White)
+    if r, g, b, a := NewEncoder(WithGamma(true)).MeanColor(faded).RGBA(); r != g || g != b || b != a || a != 0xffff / 4 {
+        t.Errorf("MeanColor() with gamma of white & transparent pixels = %v, want white at a quarter opacity", color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)})
+    }
+}
@@ -0,0 +1,69 @@
+package pxl
+
+import (
+    "image/color"
+    "math"
+)
+
+// CVD is a colour vision deficiency WithCVDSimulation() can show images as seen with.
+type CVD int
+
+const (
+    // CVDNone shows images as they are, it's the default.
+    CVDNone CVD = iota
+
+    // CVDProtanopia is the lack of the long wavelength cones, which makes reds look dark & alike to greens.
+    CVDProtanopia
+
+    // CVDDeuteranopia is the lack of the medium wavelength cones, the most common kind of red-green colour blindness.
+    CVDDeuteranopia
+
+    // CVDTritanopia is the lack of the short wavelength cones, which confuses blues with greens & yellows with violets.
+    CVDTritanopia
+)
+
+// cvdMatrices are the simulation matrices of Machado et al. for a full deficiency, applied to linear RGB.
+var cvdMatrices = map[CVD][3][3]float64{
+    CVDProtanopia: {
+        {0.152286, 1.052583, -0.204868},
+        {0.114503, 0.786281, 0.099216},
+        {-0.003882, -0.048116, 1.051998},
+    },
+    CVDDeuteranopia: {
+        {0.367322, 0.860646, -0.227968},
+        {0.280085, 0.672501, 0.047413},
+        {-0.011820, 0.042940, 0.968881},
+    },
+    CVDTritanopia: {
+        {1.255528, -0.076749, -0.178779},
+        {-0.078411, 0.930809, 0.147602},
+        {0.004733, 0.691367, 0.303900},
+    },
+}
+
+// WithCVDSimulation shows images the way they look to people with a colour vision deficiency,
+// for checking that the colours of an interface can still be told apart. The default is CVDNone.
+func WithCVDSimulation(deficiency CVD) Option {
+    return func(e *Encoder) {
+        e.cvd = deficiency
+    }
+}
+
+// simulateCVD returns a colour as it looks with a deficiency, keeping its opacity.
+func simulateCVD(c color.Color, deficiency CVD) color.Color {
+    matrix, ok := cvdMatrices[deficiency]
+    if !ok {
+        return c
+    }
+
+    n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+    linear := [3]float64{srgbToLinear(float64(n.R) / 0xffff), srgbToLinear(float64(n.G) / 0xffff), srgbToLinear(float64(n.B) / 0xffff)}
+
+    var channels [3]uint16
+    for i, row := range matrix {
+        v := row[0] * linear[0] + row[1] * linear[1] + row[2] * linear[2]
+        channels[i] = uint16(math.Round(linearToSRGB(math.Max(0, math.Min(v, 1))) * 0xffff))
+    }
+
+    return color.NRGBA64{channels[0], channels[1], channels[2], n.A}
+}
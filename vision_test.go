@@ -0,0 +1,54 @@
+package pxl
+
+import (
+    "image"
+    "image/color"
+    "testing"
+)
+
+func TestWithCVDSimulation(t *testing.T) {
+    // A brick red over an olive green of about the same lightness
+    img := image.NewNRGBA(image.Rect(0, 0, 1, 2))
+    img.Set(0, 0, color.NRGBA{0xc0, 0x60, 0x30, 0xff})
+    img.Set(0, 1, color.NRGBA{0x70, 0x90, 0x30, 0xff})
+
+    // The largest difference between the channels of the two colours as they're written
+    apart := func(deficiency CVD) int {
+        encoded, err := NewEncoder(WithCVDSimulation(deficiency)).Encode(img)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        decoded, err := ToImage(encoded)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        top, bottom := color.NRGBAModel.Convert(decoded.At(0, 0)).(color.NRGBA), color.NRGBAModel.Convert(decoded.At(0, 1)).(color.NRGBA)
+        largest := 0
+        for _, d := range []int{int(top.R) - int(bottom.R), int(top.G) - int(bottom.G), int(top.B) - int(bottom.B)} {
+            if d < 0 {
+                d = -d
+            }
+
+            if d > largest {
+                largest = d
+            }
+        }
+
+        return largest
+    }
+
+    if d := apart(CVDNone); d < 0x40 {
+        t.Fatalf("without a simulation the colours are %d apart, want them easily told apart", d)
+    }
+
+    // The pair converges without the medium wavelength cones, but not without the short ones
+    if d := apart(CVDDeuteranopia); d > 0x10 {
+        t.Errorf("with deuteranopia the colours are %d apart, want them nearly the same", d)
+    }
+
+    if d := apart(CVDTritanopia); d < 0x40 {
+        t.Errorf("with tritanopia the colours are %d apart, want them still told apart", d)
+    }
+}
@@ -0,0 +1,93 @@
+package pxl
+
+import (
+    "image"
+    "io"
+
+    "github.com/pkg/errors"
+)
+
+// ErrOutputLimit is returned once writing another row would exceed the output size limit.
+var ErrOutputLimit = errors.New("pixelview: Output exceeds the size limit")
+
+// FromImageTo converts an image like FromImage() does, but writes each row to w
+// as soon as it's encoded. It returns the number of bytes written.
+func FromImageTo(w io.Writer, img image.Image) (n int, err error) {
+    return NewEncoder().EncodeTo(w, img)
+}
+
+// FromImageToLimited is like FromImageTo(), but stops writing before the output exceeds maxBytes,
+// returning ErrOutputLimit along with the number of bytes written up to that point.
+// Only whole rows are written, so the output is cut at the end of the last row that fits.
+func FromImageToLimited(w io.Writer, img image.Image, maxBytes int) (n int, err error) {
+    if maxBytes < 0 {
+        maxBytes = 0
+    }
+
+    return NewEncoder().encodeTo(w, img, maxBytes)
+}
+
+// EncodeReader converts an image like FromImage() does, but lazily, encoding a row at a time
+// as the returned reader is read, so the output can be streamed without holding all of it.
+// Errors, like an uneven height, are returned by the first read.
+func EncodeReader(img image.Image) io.Reader {
+    next, err := NewEncoder().chunks(img)
+    return &chunkReader{next: next, err: err}
+}
+
+// RowIterator converts an image like FromImage() does, but lazily, returning a function which encodes
+// the next row each time it's called, without the line separator. It returns false once every row has been.
+// Only the row being encoded is held, so images of any size can be written out row by row.
+func RowIterator(img image.Image) (next func() (row string, ok bool), err error) {
+    return NewEncoder().RowIterator(img)
+}
+
+// RowIterator encodes an image a row at a time, see the RowIterator() function for more details.
+// The header, footer & border lines of the encoder's options aren't returned.
+func (e *Encoder) RowIterator(img image.Image) (next func() (row string, ok bool), err error) {
+    if img, err = e.prepare(img); err != nil {
+        return
+    }
+
+    decode := e.rowDecoder(img)
+    y, index := img.Bounds().Min.Y, 0
+
+    next = func() (row string, ok bool) {
+        if y >= img.Bounds().Max.Y {
+            return "", false
+        }
+
+        r := scanIndex(index, img.Bounds().Dy() / 2, e.scanY)
+        row = e.encodeRow(r, decode(img.Bounds().Min.Y + r * 2))
+        y, index = y + 2, index + 1
+        return row, true
+    }
+
+    return
+}
+
+// chunkReader reads the pieces of output an encoder produces.
+type chunkReader struct {
+    next func() (string, bool)
+    buf  string
+    err  error
+}
+
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+    for r.buf == "" {
+        if r.err != nil {
+            return 0, r.err
+        }
+
+        chunk, ok := r.next()
+        if !ok {
+            r.err = io.EOF
+        }
+
+        r.buf = chunk
+    }
+
+    n = copy(p, r.buf)
+    r.buf = r.buf[n:]
+    return
+}
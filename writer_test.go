@@ -0,0 +1,108 @@
+package pxl
+
+import (
+    "bytes"
+    "image/color"
+    "io"
+    "strings"
+    "testing"
+)
+
+func TestFromImageTo(t *testing.T) {
+    img := TestPattern(5, 6)
+    want, _ := FromImage(img)
+
+    var buf bytes.Buffer
+    n, err := FromImageTo(&buf, img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if buf.String() != want || n != len(want) {
+        t.Errorf("FromImageTo() wrote %d bytes, %q, want %d, %q", n, buf.String(), len(want), want)
+    }
+}
+
+func TestFromImageToLimited(t *testing.T) {
+    img := TestPattern(5, 6)
+    full, _ := FromImage(img)
+    rows := strings.SplitAfter(full, "\n")
+
+    // A cap a byte short of two rows only fits the first
+    limit := len(rows[0]) + len(rows[1]) - 1
+
+    var buf bytes.Buffer
+    n, err := FromImageToLimited(&buf, img, limit)
+    if err != ErrOutputLimit {
+        t.Fatalf("FromImageToLimited() returned %v, want ErrOutputLimit", err)
+    }
+
+    if buf.String() != rows[0] || n != len(rows[0]) {
+        t.Errorf("FromImageToLimited() wrote %d bytes, %q, want %d, %q", n, buf.String(), len(rows[0]), rows[0])
+    }
+
+    // A cap of exactly the output fits all of it
+    buf.Reset()
+    if n, err = FromImageToLimited(&buf, img, len(full)); err != nil || n != len(full) || buf.String() != full {
+        t.Errorf("FromImageToLimited() with room for the whole output wrote %d bytes & returned %v", n, err)
+    }
+
+    buf.Reset()
+    if n, err = FromImageToLimited(&buf, img, -1); err != ErrOutputLimit || n != 0 || buf.Len() != 0 {
+        t.Errorf("FromImageToLimited() with a negative cap wrote %d bytes & returned %v", n, err)
+    }
+}
+
+func TestEncodeReader(t *testing.T) {
+    img := TestPattern(7, 10)
+    want, _ := FromImage(img)
+
+    // Chunks of 3 bytes split the tags & the glyphs too
+    reader := EncodeReader(img)
+    var read []byte
+    chunk := make([]byte, 3)
+    for {
+        n, err := reader.Read(chunk)
+        read = append(read, chunk[:n]...)
+        if err == io.EOF {
+            break
+        }
+
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if string(read) != want {
+        t.Errorf("EncodeReader() read %q, want %q", read, want)
+    }
+
+    if _, err := EncodeReader(solid(2, 3, color.White)).Read(chunk); err != ErrOddHeight {
+        t.Errorf("first read of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}
+
+func TestRowIterator(t *testing.T) {
+    img := TestPattern(6, 10)
+    next, err := RowIterator(img)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var rows []string
+    for row, ok := next(); ok; row, ok = next() {
+        rows = append(rows, row)
+    }
+
+    if want, _ := FromImage(img); len(rows) != 5 || strings.Join(rows, "\n") + "\n" != want {
+        t.Errorf("RowIterator() returned %q, FromImage() %q", rows, want)
+    }
+
+    if _, ok := next(); ok {
+        t.Error("RowIterator() returned a row after the last one")
+    }
+
+    if _, err = RowIterator(solid(2, 3, color.White)); err != ErrOddHeight {
+        t.Errorf("RowIterator() of an uneven image returned %v, want ErrOddHeight", err)
+    }
+}